@@ -97,7 +97,7 @@ var paramMatch = regexp.MustCompile(`\$(\~)?(\*|[0-9]+)`)
 func AllNames() []completion.Element {
 	names := make([]completion.Element, 0, len(Table))
 	for name1 := range Table {
-		names = append(names, completion.Element1(name1))
+		names = append(names, completion.Candidate{Text: name1, Kind: completion.KindAlias})
 	}
 	return names
 }
@@ -6,8 +6,6 @@ import (
 	"path/filepath"
 	"sort"
 
-	"github.com/zetamatta/go-findfile"
-
 	"github.com/zetamatta/nyagos/dos"
 )
 
@@ -34,7 +32,7 @@ func bit2flg(bits uint32, flag uint32, r rune) rune {
 }
 
 func globfile(pattern string) (result []string) {
-	findfile.Walk(pattern, func(f *findfile.FileInfo) bool {
+	dos.Walk(pattern, func(f *dos.FileInfo) bool {
 		if !f.IsDir() {
 			one := filepath.Join(filepath.Dir(pattern), f.Name())
 			result = append(result, one)
@@ -7,7 +7,7 @@ import (
 	"io/ioutil"
 	"strings"
 
-	"github.com/zetamatta/go-box"
+	"github.com/zetamatta/nyagos/readline"
 )
 
 func cmdBox(ctx context.Context, cmd Param) (int, error) {
@@ -25,7 +25,7 @@ func cmdBox(ctx context.Context, cmd Param) (int, error) {
 	}
 
 	console := bufio.NewWriter(cmd.Term())
-	result := box.Choice(
+	result := readline.BoxChoice(
 		list,
 		console)
 	fmt.Fprintln(console)
@@ -9,9 +9,10 @@ import (
 	"strings"
 
 	"github.com/zetamatta/nyagos/dos"
+	"github.com/zetamatta/nyagos/readline"
 )
 
-var cdHistory = make([]string, 0, 100)
+var cdHistory = make([]string, 0, maxCdHistory)
 var cdUniq = map[string]int{}
 
 func pushCdHistory() {
@@ -19,6 +20,12 @@ func pushCdHistory() {
 	if err != nil {
 		return
 	}
+	addCdHistory(directory)
+}
+
+// addCdHistory appends directory to cdHistory. When it is already in
+// cdHistory, it is moved to the tail.
+func addCdHistory(directory string) {
 	if i, ok := cdUniq[directory]; ok {
 		for ; i < len(cdHistory)-1; i++ {
 			cdHistory[i] = cdHistory[i+1]
@@ -26,10 +33,17 @@ func pushCdHistory() {
 		}
 		cdHistory[i] = directory
 		cdUniq[directory] = i
-	} else {
-		cdUniq[directory] = len(cdHistory)
-		cdHistory = append(cdHistory, directory)
+		return
 	}
+	if len(cdHistory) >= maxCdHistory {
+		delete(cdUniq, cdHistory[0])
+		cdHistory = append(cdHistory[:0], cdHistory[1:]...)
+		for i, dir := range cdHistory {
+			cdUniq[dir] = i
+		}
+	}
+	cdUniq[directory] = len(cdHistory)
+	cdHistory = append(cdHistory, directory)
 }
 
 const (
@@ -55,11 +69,35 @@ func cmdCdSub(dir string) (int, error) {
 	}
 	err := dos.Chdir(dir)
 	if err == nil {
+		recordVisit()
 		return 0, nil
 	}
 	return errnoChdirFail, err
 }
 
+// cdFuzzy selects the directory to move from the history
+// with readline.FuzzyFinder.
+func cdFuzzy(cmd Param, query string) (int, error) {
+	if len(cdHistory) < 1 {
+		return errnoNoHistory, errors.New("cd --fuzzy: there is no history")
+	}
+	candidates := make([]string, 0, len(cdHistory))
+	for i := len(cdHistory) - 1; i >= 0; i-- {
+		candidates = append(candidates, cdHistory[i])
+	}
+	finder := &readline.FuzzyFinder{
+		Candidates: candidates,
+		Query:      query,
+	}
+	indexes, err := finder.Run(cmd.Term())
+	if err != nil {
+		// canceled
+		return errnoChdirFail, nil
+	}
+	pushCdHistory()
+	return cmdCdSub(candidates[indexes[0]])
+}
+
 func cmdCd(ctx context.Context, cmd Param) (int, error) {
 	args := cmd.Args()
 	if len(args) >= 2 {
@@ -82,6 +120,10 @@ func cmdCd(ctx context.Context, cmd Param) (int, error) {
 				fmt.Fprintln(cmd.Out(), cdHistory[i])
 			}
 			return 0, nil
+		} else if args[1] == "--jump" {
+			return cdJump(cmd, args[2:])
+		} else if args[1] == "--fuzzy" {
+			return cdFuzzy(cmd, strings.Join(args[2:], " "))
 		} else if args[1] == "-h" || args[1] == "?" {
 			i := len(cdHistory) - 10
 			if i < 0 {
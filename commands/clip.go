@@ -6,7 +6,8 @@ import (
 	"unicode/utf8"
 
 	"github.com/atotto/clipboard"
-	"github.com/zetamatta/go-mbcs"
+
+	"github.com/zetamatta/nyagos/dos"
 )
 
 func cmdClip(ctx context.Context, cmd Param) (int, error) {
@@ -17,7 +18,7 @@ func cmdClip(ctx context.Context, cmd Param) (int, error) {
 	if utf8.Valid(data) {
 		clipboard.WriteAll(string(data))
 	} else {
-		str, err := mbcs.AtoU(data)
+		str, err := dos.AtoU(data)
 		if err == nil {
 			clipboard.WriteAll(str)
 		} else {
@@ -6,8 +6,6 @@ import (
 	"regexp"
 	"strings"
 
-	"github.com/zetamatta/go-findfile"
-
 	"github.com/zetamatta/nyagos/completion"
 	"github.com/zetamatta/nyagos/dos"
 	"github.com/zetamatta/nyagos/shell"
@@ -33,6 +31,8 @@ var unscoNamePattern = regexp.MustCompile("^__(.*)__$")
 
 // Exec is the entry function to call built-in functions from Shell
 func Exec(ctx context.Context, cmd Param) (int, bool, error) {
+	// the command may create or remove the executables.
+	clearLookPathCache()
 	name := strings.ToLower(cmd.Arg(0))
 	if len(name) == 2 && strings.HasSuffix(name, ":") {
 		err := dos.Chdrive(name)
@@ -50,7 +50,7 @@ func Exec(ctx context.Context, cmd Param) (int, bool, error) {
 			return 0, false, nil
 		}
 	}
-	cmd.SetArgs(findfile.Globs(cmd.Args()))
+	cmd.SetArgs(dos.Globs(cmd.Args()))
 	next, err := function(ctx, cmd)
 	return next, true, err
 }
@@ -59,7 +59,7 @@ func Exec(ctx context.Context, cmd Param) (int, bool, error) {
 func AllNames() []completion.Element {
 	names := make([]completion.Element, 0, len(buildInCommand))
 	for name1 := range buildInCommand {
-		names = append(names, completion.Element1(name1))
+		names = append(names, completion.Candidate{Text: name1, Kind: completion.KindBuiltin})
 	}
 	return names
 }
@@ -69,12 +69,13 @@ func init() {
 		".":        cmdSource,
 		"alias":    cmdAlias,
 		"attrib":   cmdAttrib,
+		"bg":       cmdBg,
 		"bindkey":  cmdBindkey,
 		"box":      cmdBox,
 		"cd":       cmdCd,
 		"clip":     cmdClip,
-		"clone":    cmdClone,
 		"cls":      cmdCls,
+		"complete": cmdComplete,
 		"chmod":    cmdChmod,
 		"copy":     cmdCopy,
 		"del":      cmdDel,
@@ -85,11 +86,16 @@ func init() {
 		"env":      cmdEnv,
 		"erase":    cmdDel,
 		"exit":     cmdExit,
+		"fg":       cmdFg,
 		"foreach":  cmdForeach,
+		"function": cmdFunction,
 		"history":  cmdHistory,
 		"if":       cmdIf,
+		"j":        cmdJump,
+		"jobs":     cmdJobs,
+		"kill":     cmdKill,
 		"ln":       cmdLn,
-		"lnk":      cmdLnk,
+		"local":    cmdLocal,
 		"ls":       cmdLs,
 		"md":       cmdMkdir,
 		"mkdir":    cmdMkdir,
@@ -101,12 +107,16 @@ func init() {
 		"pwd":      cmdPwd,
 		"rd":       cmdRmdir,
 		"rem":      cmdRem,
+		"return":   cmdReturn,
 		"rmdir":    cmdRmdir,
 		"set":      cmdSet,
 		"source":   cmdSource,
-		"su":       cmdSu,
 		"touch":    cmdTouch,
 		"type":     cmdType,
+		"wait":     cmdWait,
 		"which":    cmdWhich,
 	}
+	for name, function := range platformCommands {
+		buildInCommand[name] = function
+	}
 }
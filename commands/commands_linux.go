@@ -0,0 +1,8 @@
+package commands
+
+import (
+	"context"
+)
+
+// platformCommands are the built-in commands available only on Linux.
+var platformCommands = map[string]func(context.Context, Param) (int, error){}
@@ -0,0 +1,12 @@
+package commands
+
+import (
+	"context"
+)
+
+// platformCommands are the built-in commands available only on Windows.
+var platformCommands = map[string]func(context.Context, Param) (int, error){
+	"clone": cmdClone,
+	"lnk":   cmdLnk,
+	"su":    cmdSu,
+}
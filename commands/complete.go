@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zetamatta/nyagos/completion"
+)
+
+// applyCompleteOptions adds the options of `complete` to spec.
+func applyCompleteOptions(spec *completion.Spec, opts []string) error {
+	for i := 0; i < len(opts); i += 2 {
+		if i+1 >= len(opts) {
+			return fmt.Errorf("complete: %s: value required", opts[i])
+		}
+		value := opts[i+1]
+		switch opts[i] {
+		case "-s":
+			for _, name := range strings.Split(value, ",") {
+				spec.Subcommand(name, true)
+			}
+		case "-f":
+			flag := &completion.Flag{}
+			names := value
+			if eqlPos := strings.IndexRune(value, '='); eqlPos > 0 {
+				names = value[:eqlPos]
+				flag.Value = completion.ParseArg(value[eqlPos+1:])
+			}
+			flag.Names = strings.Split(names, ",")
+			replaced := false
+			for j, f := range spec.Flags {
+				if f.Names[0] == flag.Names[0] {
+					spec.Flags[j] = flag
+					replaced = true
+				}
+			}
+			if !replaced {
+				spec.Flags = append(spec.Flags, flag)
+			}
+		case "-a":
+			spec.Args = append(spec.Args, completion.ParseArg(value))
+		case "-A":
+			spec.Rest = completion.ParseArg(value)
+		case "-d":
+			spec.Description = value
+		default:
+			return fmt.Errorf("complete: %s: unknown option", opts[i])
+		}
+	}
+	return nil
+}
+
+func cmdComplete(ctx context.Context, cmd Param) (int, error) {
+	args := cmd.Args()[1:]
+	if len(args) <= 0 || args[0] == "-p" {
+		names := completion.SpecNames()
+		if len(args) >= 2 {
+			names = args[1:]
+		}
+		for _, name := range names {
+			spec := completion.GetSpec(name)
+			if spec == nil {
+				return 1, fmt.Errorf("complete: %s: no specification", name)
+			}
+			completion.WriteSpec(cmd.Out(), name, spec)
+		}
+		return 0, nil
+	}
+	if args[0] == "-r" {
+		for _, name := range args[1:] {
+			completion.SetSpec(name, nil)
+		}
+		return 0, nil
+	}
+	i := 0
+	for i < len(args) && !strings.HasPrefix(args[i], "-") {
+		i++
+	}
+	if i <= 0 {
+		return 1, errors.New("complete: no command name")
+	}
+	spec := completion.GetSpec(args[0])
+	if spec == nil {
+		spec = &completion.Spec{}
+		completion.SetSpec(args[0], spec)
+	}
+	for _, sub := range args[1:i] {
+		spec = spec.Subcommand(sub, true)
+	}
+	if err := applyCompleteOptions(spec, args[i:]); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zetamatta/nyagos/completion"
+)
+
+func TestApplyCompleteOptions(t *testing.T) {
+	spec := &completion.Spec{}
+	err := applyCompleteOptions(spec, []string{
+		"-d", "the tool",
+		"-f", "-v,--verbose",
+		"-f", "-o,--output=file",
+		"-f", "--mode=fast,slow",
+		"-a", "directory",
+		"-A", "env",
+		"-s", "build,run",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := applyCompleteOptions(spec.Subcommand("run", false), []string{"-a", "executable"}); err != nil {
+		t.Fatal(err)
+	}
+	var buffer strings.Builder
+	completion.WriteSpec(&buffer, "tool", spec)
+	expect := `complete tool -d "the tool" -f -v,--verbose -f -o,--output=file -f --mode=fast,slow -a directory -A env -s build -s run
+complete tool run -a executable
+`
+	if buffer.String() != expect {
+		t.Errorf("WriteSpec()\n\t= %q\n\texpected %q", buffer.String(), expect)
+	}
+	if err := applyCompleteOptions(spec, []string{"-x", "y"}); err == nil {
+		t.Error("applyCompleteOptions(-x): error expected")
+	}
+	if err := applyCompleteOptions(spec, []string{"-a"}); err == nil {
+		t.Error("applyCompleteOptions(-a): error expected")
+	}
+}
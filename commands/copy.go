@@ -9,9 +9,8 @@ import (
 	"regexp"
 	"unicode"
 
-	"github.com/zetamatta/go-getch"
-
 	"github.com/zetamatta/nyagos/dos"
+	"github.com/zetamatta/nyagos/readline"
 )
 
 type copyMoveT struct {
@@ -91,7 +90,7 @@ func (cm copyMoveT) Run(ctx context.Context, args []string) (int, error) {
 	isDir := judgeDir(args[len(args)-1])
 	srcs := args[0 : len(args)-1]
 	for i, src := range srcs {
-		if getch.IsCtrlCPressed() {
+		if readline.IsCtrlCPressed() {
 			fmt.Fprintln(cm.Err(), "^C")
 			return 0, nil
 		}
@@ -114,7 +113,7 @@ func (cm copyMoveT) Run(ctx context.Context, args []string) (int, error) {
 				fmt.Fprintf(cm.Err(),
 					"%s: override? [Yes/No/All/Quit] ",
 					dst)
-				ch := getch.Rune()
+				ch := readline.GetRune()
 				if unicode.IsPrint(ch) {
 					fmt.Fprintf(cm.Err(), "%c\n", ch)
 				} else {
@@ -145,7 +144,7 @@ func (cm copyMoveT) Run(ctx context.Context, args []string) (int, error) {
 				return 1, err
 			}
 			fmt.Fprintf(cm.Err(), "%s\nContinue? [Yes/No] ", err.Error())
-			ch := getch.Rune()
+			ch := readline.GetRune()
 			if unicode.IsPrint(ch) {
 				fmt.Fprintf(cm.Err(), "%c\n", ch)
 			} else {
@@ -0,0 +1,6 @@
+package commands
+
+// CorrectCase returns `path` as it is because the filesystem is case-sensitive.
+func CorrectCase(path string) (string, error) {
+	return path, nil
+}
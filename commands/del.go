@@ -7,9 +7,8 @@ import (
 	"syscall"
 	"unicode"
 
-	"github.com/zetamatta/go-getch"
-
 	"github.com/zetamatta/nyagos/dos"
+	"github.com/zetamatta/nyagos/readline"
 )
 
 func setReadonly(path string) error {
@@ -73,7 +72,7 @@ func cmdDel(ctx context.Context, cmd Param) (int, error) {
 			fmt.Fprintf(cmd.Out(),
 				"(%d/%d) %s: Remove ? [Yes/No/All/Quit] ",
 				i, n-1, path)
-			ch := getch.Rune()
+			ch := readline.GetRune()
 			if unicode.IsPrint(ch) {
 				fmt.Fprintf(cmd.Out(), "%c ", ch)
 			}
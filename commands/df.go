@@ -23,33 +23,19 @@ func df(rootPathName string, w io.Writer) (err error) {
 			humanize.Comma(int64(totalFree)),
 			100*(total-free)/total)
 	}
-	t, err1 := dos.GetDriveType(rootPathName)
-	if err1 != nil {
+	if err1 := printDriveType(rootPathName, w); err1 != nil {
 		if err != nil {
 			err = fmt.Errorf("%s,%s", err, err1)
 		} else {
 			err = fmt.Errorf("%s: %s", rootPathName, err1)
 		}
-	} else {
-		switch t {
-		case dos.DRIVE_REMOVABLE:
-			io.WriteString(w, " [REMOVABLE]")
-		case dos.DRIVE_FIXED:
-			io.WriteString(w, " [FIXED]")
-		case dos.DRIVE_REMOTE:
-			io.WriteString(w, " [REMOTE]")
-		case dos.DRIVE_CDROM:
-			io.WriteString(w, " [CDROM]")
-		case dos.DRIVE_RAMDISK:
-			io.WriteString(w, " [RAMDISK]")
-		}
 	}
 	fmt.Fprintln(w)
 	return
 }
 
 func cmdDiskFree(_ context.Context, cmd Param) (int, error) {
-	bits, err := dos.GetLogicalDrives()
+	drives, err := listDrives()
 	if err != nil {
 		return 0, err
 	}
@@ -66,14 +52,10 @@ func cmdDiskFree(_ context.Context, cmd Param) (int, error) {
 		count++
 	}
 	if count <= 0 {
-		for d := 'A'; d <= 'Z'; d++ {
-			if (bits & 1) != 0 {
-				rootPathName := fmt.Sprintf("%c:", d)
-				if err := df(rootPathName, cmd.Out()); err != nil {
-					fmt.Fprintln(cmd.Err(), err)
-				}
+		for _, rootPathName := range drives {
+			if err := df(rootPathName, cmd.Out()); err != nil {
+				fmt.Fprintln(cmd.Err(), err)
 			}
-			bits >>= 1
 		}
 	}
 	return 0, nil
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type mountT struct {
+	device string
+	dir    string
+	fstype string
+}
+
+func readMounts() ([]mountT, error) {
+	fd, err := os.Open("/proc/mounts")
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	mounts := []mountT{}
+	scan := bufio.NewScanner(fd)
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) >= 3 {
+			mounts = append(mounts, mountT{device: fields[0], dir: fields[1], fstype: fields[2]})
+		}
+	}
+	return mounts, scan.Err()
+}
+
+func listDrives() ([]string, error) {
+	mounts, err := readMounts()
+	if err != nil {
+		return nil, err
+	}
+	drives := []string{}
+	for _, m := range mounts {
+		if strings.HasPrefix(m.device, "/dev/") {
+			drives = append(drives, m.dir)
+		}
+	}
+	return drives, nil
+}
+
+func printDriveType(rootPathName string, w io.Writer) error {
+	mounts, err := readMounts()
+	if err != nil {
+		return err
+	}
+	for _, m := range mounts {
+		if m.dir == rootPathName {
+			fmt.Fprintf(w, " [%s]", m.fstype)
+			break
+		}
+	}
+	return nil
+}
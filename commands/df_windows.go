@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/zetamatta/nyagos/dos"
+)
+
+func listDrives() ([]string, error) {
+	bits, err := dos.GetLogicalDrives()
+	if err != nil {
+		return nil, err
+	}
+	drives := []string{}
+	for d := 'A'; d <= 'Z'; d++ {
+		if (bits & 1) != 0 {
+			drives = append(drives, fmt.Sprintf("%c:", d))
+		}
+		bits >>= 1
+	}
+	return drives, nil
+}
+
+func printDriveType(rootPathName string, w io.Writer) error {
+	t, err := dos.GetDriveType(rootPathName)
+	if err != nil {
+		return err
+	}
+	switch t {
+	case dos.DRIVE_REMOVABLE:
+		io.WriteString(w, " [REMOVABLE]")
+	case dos.DRIVE_FIXED:
+		io.WriteString(w, " [FIXED]")
+	case dos.DRIVE_REMOTE:
+		io.WriteString(w, " [REMOTE]")
+	case dos.DRIVE_CDROM:
+		io.WriteString(w, " [CDROM]")
+	case dos.DRIVE_RAMDISK:
+		io.WriteString(w, " [RAMDISK]")
+	}
+	return nil
+}
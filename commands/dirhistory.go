@@ -0,0 +1,332 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zetamatta/nyagos/dos"
+	"github.com/zetamatta/nyagos/history"
+)
+
+// DirHistoryPath is the file to keep the directories visited with
+// their visit counts over sessions. Empty means not to keep them.
+var DirHistoryPath = ""
+
+// DirStackPath is the file to keep the directory stack of pushd and popd.
+// Empty means not to keep it.
+var DirStackPath = ""
+
+const (
+	maxCdHistory  = 100  // the directories for `cd -N` and `cd -h`
+	maxDirHistory = 1000 // the directories in DirHistoryPath
+	dirAgingLimit = 9000 // the sum of the counts to start aging
+)
+
+// dirRecord is a directory visited.
+type dirRecord struct {
+	path  string
+	count float64
+	last  time.Time
+}
+
+// frecency returns the score of the directory by the visit count
+// weighted by how recently it was visited.
+func (r *dirRecord) frecency(now time.Time) float64 {
+	switch age := now.Sub(r.last); {
+	case age < time.Hour:
+		return r.count * 4
+	case age < 24*time.Hour:
+		return r.count * 2
+	case age < 7*24*time.Hour:
+		return r.count / 2
+	}
+	return r.count / 4
+}
+
+func sameDir(a, b string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// readDirHistory reads the file whose lines are PATH COUNT STAMP
+// separated with tabs.
+func readDirHistory(path string) ([]*dirRecord, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer fd.Close()
+	records := []*dirRecord{}
+	sc := bufio.NewScanner(fd)
+	for sc.Scan() {
+		field := strings.Split(sc.Text(), "\t")
+		if len(field) < 3 {
+			continue
+		}
+		count, err := strconv.ParseFloat(field[1], 64)
+		if err != nil {
+			continue
+		}
+		stamp, err := strconv.ParseInt(field[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		records = append(records, &dirRecord{
+			path:  field[0],
+			count: count,
+			last:  time.Unix(stamp, 0),
+		})
+	}
+	return records, sc.Err()
+}
+
+// writeFileAtomically replaces the file path with the lines.
+func writeFileAtomically(path string, lines []string) error {
+	fd, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(fd)
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	if err := w.Flush(); err != nil {
+		fd.Close()
+		os.Remove(fd.Name())
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		os.Remove(fd.Name())
+		return err
+	}
+	if err := os.Rename(fd.Name(), path); err != nil {
+		os.Remove(fd.Name())
+		return err
+	}
+	return nil
+}
+
+func writeDirHistory(path string, records []*dirRecord) error {
+	lines := make([]string, len(records))
+	for i, r := range records {
+		lines[i] = fmt.Sprintf("%s\t%s\t%d",
+			r.path,
+			strconv.FormatFloat(r.count, 'f', -1, 64),
+			r.last.Unix())
+	}
+	return writeFileAtomically(path, lines)
+}
+
+// visitDir adds a visit of dir to records. When the sum of the counts
+// is too large, all counts are reduced and the directories rarely
+// visited are forgotten.
+func visitDir(records []*dirRecord, dir string, now time.Time) []*dirRecord {
+	found := false
+	total := 0.0
+	for _, r := range records {
+		if sameDir(r.path, dir) {
+			r.path = dir
+			r.count++
+			r.last = now
+			found = true
+		}
+		total += r.count
+	}
+	if !found {
+		records = append(records, &dirRecord{path: dir, count: 1, last: now})
+		total++
+	}
+	if total > dirAgingLimit {
+		aged := records[:0]
+		for _, r := range records {
+			r.count *= 0.99
+			if r.count >= 1 {
+				aged = append(aged, r)
+			}
+		}
+		records = aged
+	}
+	if len(records) > maxDirHistory {
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].frecency(now) > records[j].frecency(now)
+		})
+		records = records[:maxDirHistory]
+	}
+	return records
+}
+
+// recordVisit adds the current directory to the file DirHistoryPath.
+func recordVisit() {
+	if DirHistoryPath == "" {
+		return
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	// other sessions must not rewrite the file between reading and writing.
+	unlock, err := history.Lock(DirHistoryPath)
+	if err == nil {
+		var records []*dirRecord
+		records, err = readDirHistory(DirHistoryPath)
+		if err == nil {
+			err = writeDirHistory(DirHistoryPath, visitDir(records, wd, time.Now()))
+		}
+		unlock()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+}
+
+// LoadDirHistory sets the directories in DirHistoryPath visited
+// recently into the history for `cd -N` and `cd -h`.
+func LoadDirHistory() error {
+	if DirHistoryPath == "" {
+		return nil
+	}
+	records, err := readDirHistory(DirHistoryPath)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].last.Before(records[j].last)
+	})
+	if len(records) > maxCdHistory {
+		records = records[len(records)-maxCdHistory:]
+	}
+	for _, r := range records {
+		addCdHistory(r.path)
+	}
+	return nil
+}
+
+// matchJump returns true when dir has all patterns in the order and
+// the last pattern is in the last element of dir. The case is ignored
+// unless the pattern has upper case letters.
+func matchJump(dir string, patterns []string) bool {
+	pos := 0
+	for i, pattern := range patterns {
+		target := dir
+		if strings.ToLower(pattern) == pattern {
+			target = strings.ToLower(dir)
+		}
+		if i == len(patterns)-1 {
+			base := strings.LastIndexAny(strings.TrimRight(target, `/\`), `/\`)
+			last := strings.LastIndex(target, pattern)
+			return last >= pos && last > base
+		}
+		found := strings.Index(target[pos:], pattern)
+		if found < 0 {
+			return false
+		}
+		pos += found + len(pattern)
+	}
+	return true
+}
+
+// jumpCandidates returns the existing directories matching patterns
+// except the current one in the order of frecency.
+func jumpCandidates(records []*dirRecord, patterns []string, wd string, now time.Time) []*dirRecord {
+	result := []*dirRecord{}
+	for _, r := range records {
+		if sameDir(r.path, wd) || !matchJump(r.path, patterns) {
+			continue
+		}
+		if stat, err := os.Stat(r.path); err != nil || !stat.IsDir() {
+			continue
+		}
+		result = append(result, r)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].frecency(now) > result[j].frecency(now)
+	})
+	return result
+}
+
+// cdJump moves to the directory which matches patterns best
+// by frecency. `-l` lists the candidates instead.
+func cdJump(cmd Param, patterns []string) (int, error) {
+	list := len(patterns) <= 0
+	if len(patterns) > 0 && patterns[0] == "-l" {
+		list = true
+		patterns = patterns[1:]
+	}
+	records, err := readDirHistory(DirHistoryPath)
+	if err != nil {
+		return errnoNoHistory, err
+	}
+	wd, _ := os.Getwd()
+	now := time.Now()
+	candidates := jumpCandidates(records, patterns, wd, now)
+	if list {
+		for i := len(candidates) - 1; i >= 0; i-- {
+			fmt.Fprintf(cmd.Out(), "%-10.1f %s\n", candidates[i].frecency(now), candidates[i].path)
+		}
+		return 0, nil
+	}
+	if len(candidates) <= 0 {
+		return errnoNoHistory, fmt.Errorf("%s: no directory matches", strings.Join(patterns, " "))
+	}
+	pushCdHistory()
+	return cmdCdSub(candidates[0].path)
+}
+
+func cmdJump(ctx context.Context, cmd Param) (int, error) {
+	return cdJump(cmd, cmd.Args()[1:])
+}
+
+// saveDirStack writes the current directory and the directory stack
+// into DirStackPath.
+func saveDirStack() {
+	if DirStackPath == "" {
+		return
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	lines := append([]string{wd}, dirstack...)
+	if err := writeFileAtomically(DirStackPath, lines); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+}
+
+// restoreDirStack restores the current directory and the directory stack
+// saved by the last pushd or popd of any session.
+func restoreDirStack() (int, error) {
+	if DirStackPath == "" {
+		return noDirStack, errors.New("dirs: the directory stack is not saved")
+	}
+	data, err := ioutil.ReadFile(DirStackPath)
+	if err != nil {
+		return noDirStack, err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\r\n"), "\n")
+	if len(lines) <= 0 || lines[0] == "" {
+		return noDirStack, errors.New("dirs: the directory stack saved is empty")
+	}
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+	if err := dos.Chdir(lines[0]); err != nil {
+		return errnoChdirFail, err
+	}
+	dirstack = append(dirstack[:0], lines[1:]...)
+	return 0, nil
+}
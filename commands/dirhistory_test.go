@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMatchJump(t *testing.T) {
+	cases := []struct {
+		dir      string
+		patterns []string
+		expect   bool
+	}{
+		{"/home/user/src/nyagos", []string{"nya"}, true},
+		{"/home/user/src/nyagos", []string{"src", "nya"}, true},
+		{"/home/user/src/nyagos", []string{"nya", "src"}, false},
+		{"/home/user/src/nyagos", []string{"src"}, false},
+		{"/home/user/src/nyagos", []string{"NYA"}, false},
+		{"/home/user/src/Nyagos", []string{"nya"}, true},
+		{"/home/user/src/Nyagos", []string{"Nya"}, true},
+		{`C:\Users\user\Documents`, []string{"users", "doc"}, true},
+	}
+	for _, c := range cases {
+		if result := matchJump(c.dir, c.patterns); result != c.expect {
+			t.Errorf("matchJump(%q,%q)=%v (expect %v)", c.dir, c.patterns, result, c.expect)
+		}
+	}
+}
+
+func TestVisitDir(t *testing.T) {
+	now := time.Now()
+	var records []*dirRecord
+	records = visitDir(records, "/a", now.Add(-30*24*time.Hour))
+	records = visitDir(records, "/a", now.Add(-30*24*time.Hour))
+	records = visitDir(records, "/a", now.Add(-30*24*time.Hour))
+	records = visitDir(records, "/b", now)
+	if len(records) != 2 {
+		t.Fatalf("len(records)=%d (expect 2)", len(records))
+	}
+	if records[0].count != 3 || records[1].count != 1 {
+		t.Fatalf("counts=%v,%v (expect 3,1)", records[0].count, records[1].count)
+	}
+	// /b is visited fewer times but more recently than /a.
+	if records[1].frecency(now) <= records[0].frecency(now) {
+		t.Errorf("frecency(/b)=%v <= frecency(/a)=%v",
+			records[1].frecency(now), records[0].frecency(now))
+	}
+}
+
+func TestDirHistoryFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dirhistory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "nyagos.dirhistory")
+
+	records, err := readDirHistory(path)
+	if err != nil || len(records) != 0 {
+		t.Fatalf("readDirHistory(not exist)=%v,%v", records, err)
+	}
+	stamp := time.Unix(1500000000, 0)
+	records = visitDir(nil, "/x y/z", stamp)
+	records = visitDir(records, "/x y/z", stamp)
+	if err := writeDirHistory(path, records); err != nil {
+		t.Fatal(err)
+	}
+	records, err = readDirHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 ||
+		records[0].path != "/x y/z" ||
+		records[0].count != 2 ||
+		!records[0].last.Equal(stamp) {
+		t.Errorf("readDirHistory()=%+v", records[0])
+	}
+}
+
+func TestRecordVisitConcurrently(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dirhistory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	save := DirHistoryPath
+	defer func() { DirHistoryPath = save }()
+	DirHistoryPath = filepath.Join(dir, "nyagos.dirhistory")
+
+	// no visits are lost while the sessions update the file at once.
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recordVisit()
+		}()
+	}
+	wg.Wait()
+	records, err := readDirHistory(DirHistoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].count != n {
+		t.Fatalf("readDirHistory()=%+v", records)
+	}
+}
@@ -12,8 +12,47 @@ import (
 )
 
 var startList = map[string]bool{
-	"foreach": true,
-	"if":      true,
+	"foreach":  true,
+	"function": true,
+	"if":       true,
+}
+
+// IsIncomplete returns true when text has the block `if ... then`,
+// `foreach` or `function NAME` whose `end` is not found yet.
+func IsIncomplete(text string) bool {
+	nest := 0
+	for _, line := range strings.Split(text, "\n") {
+		args := texts.SplitLikeShellString(line)
+		if len(args) <= 0 {
+			continue
+		}
+		name := strings.ToLower(args[0])
+		if nest == 0 {
+			// the commands which do not read the following lines
+			if name == "if" && !hasThen(args) {
+				continue
+			}
+			if name == "function" && len(args) < 2 {
+				continue
+			}
+		}
+		if startList[name] {
+			nest++
+		} else if (name == "end" || name == "endif") && nest > 0 {
+			nest--
+		}
+	}
+	return nest > 0
+}
+
+// hasThen returns true when the arguments of `if` has `then`.
+func hasThen(args []string) bool {
+	for _, arg := range args[1:] {
+		if arg == "then" {
+			return true
+		}
+	}
+	return false
 }
 
 func cmdForeach(ctx context.Context, cmd Param) (int, error) {
@@ -55,7 +94,11 @@ func cmdForeach(ctx context.Context, cmd Param) (int, error) {
 	save := os.Getenv(name)
 	for _, value := range cmd.Args()[2:] {
 		os.Setenv(name, value)
-		cmd.Loop(ctx, &bufstream)
+		rc, err := cmd.Loop(ctx, &bufstream)
+		if stopsBlock(ctx, rc, err) {
+			os.Setenv(name, save)
+			return rc, io.EOF
+		}
 		bufstream.SetPos(0)
 	}
 	os.Setenv(name, save)
@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"testing"
+)
+
+func TestIsIncomplete(t *testing.T) {
+	for text, expect := range map[string]bool{
+		"echo foo":                                    false,
+		"if exist foo then":                           true,
+		"if exist foo type foo":                       false,
+		"if exist foo then\n  type foo\nend":          false,
+		"if exist foo then\n  type foo\nelse":         true,
+		"foreach i a b c\n  echo %i%":                 true,
+		"foreach i a b c\n  echo %i%\nend":            false,
+		"function":                                    false,
+		"function foo\n  if %1 == x then\n  end":      true,
+		"function foo\n  if %1 == x then\n  end\nend": false,
+		"echo foo\nforeach i a":                       true,
+	} {
+		if result := IsIncomplete(text); result != expect {
+			t.Errorf("IsIncomplete(%q)=%v (expect %v)", text, result, expect)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zetamatta/nyagos/completion"
+	"github.com/zetamatta/nyagos/shell"
+	"github.com/zetamatta/nyagos/texts"
+)
+
+// maxFunctionDepth is the limit of the nested function calls.
+const maxFunctionDepth = 256
+
+type shellFunction struct {
+	name string
+	body []string
+}
+
+// functionTable has the functions defined by `function NAME ... end`.
+// The keys are the lowercase names.
+var functionTable = map[string]*shellFunction{}
+
+type functionFrameIDT struct{}
+
+var functionFrameID functionFrameIDT
+
+// functionFrame is the state of the running function.
+type functionFrame struct {
+	depth      int
+	locals     map[string]string // the values before `local`
+	returned   bool
+	errorlevel int
+}
+
+func (frame *functionFrame) restore() {
+	for name, value := range frame.locals {
+		if value == "" {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, value)
+		}
+	}
+}
+
+// expandPositional replaces %0..%9 and %* in the body with args.
+// Quoted with single quotations and the bodies of the nested functions
+// are not replaced.
+func expandPositional(body []string, args []string) []string {
+	result := make([]string, 0, len(body))
+	var blocks []string
+	for _, line := range body {
+		name := strings.ToLower(texts.FirstWord(line))
+		inner := false
+		for _, b := range blocks {
+			if b == "function" {
+				inner = true
+			}
+		}
+		if startList[name] {
+			blocks = append(blocks, name)
+		} else if (name == "end" || name == "endif") && len(blocks) > 0 {
+			blocks = blocks[:len(blocks)-1]
+		}
+		if inner {
+			result = append(result, line)
+			continue
+		}
+		var buffer strings.Builder
+		quoted := false
+		for i := 0; i < len(line); i++ {
+			ch := line[i]
+			if ch == '\'' {
+				quoted = !quoted
+			} else if ch == '%' && !quoted && i+1 < len(line) {
+				next := line[i+1]
+				if next == '*' {
+					if len(args) >= 2 {
+						buffer.WriteString(strings.Join(args[1:], " "))
+					}
+					i++
+					continue
+				} else if next >= '0' && next <= '9' {
+					if n := int(next - '0'); n < len(args) {
+						buffer.WriteString(args[n])
+					}
+					i++
+					continue
+				} else if end := strings.IndexByte(line[i+1:], '%'); end >= 0 &&
+					!strings.ContainsAny(line[i+1:i+1+end], " \t") {
+					// %NAME% is left to be expanded on execution.
+					buffer.WriteString(line[i : i+end+2])
+					i += end + 1
+					continue
+				}
+			}
+			buffer.WriteByte(ch)
+		}
+		result = append(result, buffer.String())
+	}
+	return result
+}
+
+func (f *shellFunction) call(ctx context.Context, cmd *shell.Cmd) (int, error) {
+	frame := &functionFrame{locals: map[string]string{}}
+	if parent, ok := ctx.Value(functionFrameID).(*functionFrame); ok {
+		frame.depth = parent.depth + 1
+	}
+	if frame.depth >= maxFunctionDepth {
+		return 1, fmt.Errorf("%s: function nesting too deep", f.name)
+	}
+	defer frame.restore()
+
+	var stream shell.BufStream
+	for _, line := range expandPositional(f.body, cmd.RawArgs()) {
+		stream.Add(line)
+	}
+	ctx = context.WithValue(ctx, functionFrameID, frame)
+	rc, err := cmd.NewSession().Loop(ctx, &stream)
+	if err != io.EOF {
+		return rc, err
+	}
+	if frame.returned {
+		return frame.errorlevel, nil
+	}
+	if rc != 0 && shell.ErrExit {
+		// stopped by errexit in the function
+		return rc, io.EOF
+	}
+	return shell.LastErrorLevel, nil
+}
+
+var nextFunctionHook shell.HookT
+
+func functionHook(ctx context.Context, cmd *shell.Cmd) (int, bool, error) {
+	f, ok := functionTable[strings.ToLower(cmd.Arg(0))]
+	if !ok {
+		return nextFunctionHook(ctx, cmd)
+	}
+	rc, err := f.call(ctx, cmd)
+	return rc, true, err
+}
+
+// InitFunctions inserts the hook to call functions into shell package.
+func InitFunctions() {
+	nextFunctionHook = shell.SetHook(functionHook)
+}
+
+// FunctionNames returns all function-names for completion package.
+func FunctionNames() []completion.Element {
+	names := make([]completion.Element, 0, len(functionTable))
+	for _, f := range functionTable {
+		names = append(names, completion.Candidate{Text: f.name, Kind: completion.KindFunction})
+	}
+	return names
+}
+
+// stopsBlock returns true when the commands in the block were stopped
+// by `return` or errexit, which also stop the commands around the block.
+func stopsBlock(ctx context.Context, rc int, err error) bool {
+	if err != io.EOF {
+		return false
+	}
+	if frame, ok := ctx.Value(functionFrameID).(*functionFrame); ok && frame.returned {
+		return true
+	}
+	return rc != 0 && shell.ErrExit
+}
+
+func printFunction(w io.Writer, f *shellFunction) {
+	fmt.Fprintf(w, "function %s\n", f.name)
+	for _, line := range f.body {
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintln(w, "end")
+}
+
+func cmdFunction(ctx context.Context, cmd Param) (int, error) {
+	if len(cmd.Args()) <= 1 {
+		names := make([]string, 0, len(functionTable))
+		for key := range functionTable {
+			names = append(names, key)
+		}
+		sort.Strings(names)
+		for _, key := range names {
+			printFunction(cmd.Out(), functionTable[key])
+		}
+		return 0, nil
+	}
+	name := strings.TrimSuffix(cmd.Arg(1), "()")
+	if name == "" {
+		return 1, errors.New("function: no name")
+	}
+
+	stream, ok := ctx.Value(shell.StreamID).(shell.Stream)
+	if !ok {
+		return 1, errors.New("Not found stream")
+	}
+
+	var body []string
+	savePrompt := os.Getenv("PROMPT")
+	os.Setenv("PROMPT", "function>")
+	defer os.Setenv("PROMPT", savePrompt)
+	nest := 1
+	for {
+		_, line, err := cmd.ReadCommand(ctx, stream)
+		if err != nil {
+			if err != io.EOF {
+				return -1, err
+			}
+			return 1, fmt.Errorf("function %s: `end` not found", name)
+		}
+		first := strings.ToLower(texts.FirstWord(line))
+		if _, ok := startList[first]; ok {
+			nest++
+		} else if first == "end" || first == "endif" {
+			nest--
+			if nest == 0 {
+				break
+			}
+		}
+		body = append(body, line)
+	}
+	functionTable[strings.ToLower(name)] = &shellFunction{name: name, body: body}
+	return 0, nil
+}
+
+func cmdLocal(ctx context.Context, cmd Param) (int, error) {
+	frame, ok := ctx.Value(functionFrameID).(*functionFrame)
+	if !ok {
+		return 1, errors.New("local: not in a function")
+	}
+	for _, arg := range cmd.Args()[1:] {
+		name, value, hasValue := arg, "", false
+		if eqlPos := strings.IndexRune(arg, '='); eqlPos >= 0 {
+			name, value, hasValue = arg[:eqlPos], arg[eqlPos+1:], true
+		}
+		if name == "" {
+			return 1, fmt.Errorf("local: %s: invalid name", arg)
+		}
+		if _, ok := frame.locals[name]; !ok {
+			frame.locals[name] = os.Getenv(name)
+		}
+		if !hasValue {
+			continue
+		}
+		if value == "" {
+			os.Unsetenv(name)
+		} else {
+			os.Setenv(name, value)
+		}
+	}
+	return 0, nil
+}
+
+func cmdReturn(ctx context.Context, cmd Param) (int, error) {
+	frame, ok := ctx.Value(functionFrameID).(*functionFrame)
+	if !ok {
+		return 1, errors.New("return: not in a function")
+	}
+	errorlevel := shell.LastErrorLevel
+	if len(cmd.Args()) >= 2 {
+		n, err := strconv.Atoi(cmd.Arg(1))
+		if err != nil {
+			return 1, fmt.Errorf("return: %s: numeric argument required", cmd.Arg(1))
+		}
+		errorlevel = n
+	}
+	frame.returned = true
+	frame.errorlevel = errorlevel
+	return errorlevel, io.EOF
+}
@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandPositional(t *testing.T) {
+	body := []string{
+		`echo %0 %1 %2 %3`,
+		`echo [%*] '%1' %PATH% %1%2`,
+		`function inner`,
+		`  echo %1`,
+		`end`,
+		`echo 100%`,
+	}
+	expect := []string{
+		`echo f a "b c" `,
+		`echo [a "b c"] '%1' %PATH% a"b c"`,
+		`function inner`,
+		`  echo %1`,
+		`end`,
+		`echo 100%`,
+	}
+	result := expandPositional(body, []string{"f", "a", `"b c"`})
+	if !reflect.DeepEqual(result, expect) {
+		t.Fatalf("expandPositional()\n\t= %q\n\texpected %q", result, expect)
+	}
+}
@@ -0,0 +1,248 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/zetamatta/nyagos/alias"
+	"github.com/zetamatta/nyagos/dos"
+	"github.com/zetamatta/nyagos/readline"
+	"github.com/zetamatta/nyagos/shell"
+)
+
+// HighlightColors are the escape sequences to color the command line
+// with `set -o highlight`. The empty string leaves the text as it is.
+var HighlightColors = map[string]string{
+	"command":  "\x1B[32m",   // the command name found
+	"unknown":  "\x1B[31m",   // the command name not found
+	"string":   "\x1B[33m",   // '...' and "..."
+	"variable": "\x1B[36m",   // %VAR%
+	"operator": "\x1B[35m",   // | && || & ; ( )
+	"redirect": "\x1B[35m",   // > >> < 2> 2>&1
+	"nofile":   "\x1B[4;31m", // the path which does not exist
+}
+
+// keywords are the words which are not commands but can be at the top
+// of the command line in the blocks.
+var keywords = map[string]struct{}{
+	"then":  {},
+	"else":  {},
+	"end":   {},
+	"endif": {},
+}
+
+// lookPathCache has the results of dos.LookPath for the highlight not
+// to search the directories on every key. It is cleared when %PATH% or
+// %NYAGOSPATH% is changed and when a command is executed.
+var lookPathCache = struct {
+	sync.Mutex
+	env   string
+	found map[string]bool
+}{}
+
+func clearLookPathCache() {
+	lookPathCache.Lock()
+	lookPathCache.found = nil
+	lookPathCache.Unlock()
+}
+
+// lookPathCached returns true when name is found by dos.LookPath.
+func lookPathCached(name string) bool {
+	lookPathCache.Lock()
+	defer lookPathCache.Unlock()
+	env := os.Getenv("PATH") + "\x00" + os.Getenv("NYAGOSPATH")
+	if lookPathCache.found == nil || lookPathCache.env != env {
+		lookPathCache.found = map[string]bool{}
+		lookPathCache.env = env
+	}
+	found, ok := lookPathCache.found[name]
+	if !ok {
+		found = dos.LookPath(shell.LookCurdirOrder, name, "NYAGOSPATH") != ""
+		lookPathCache.found[name] = found
+	}
+	return found
+}
+
+// commandExists returns true when name is an alias (including the Lua
+// functions), a built-in command, a function or an executable.
+func commandExists(name string) bool {
+	if name == "" {
+		return true
+	}
+	name = strings.ToLower(name)
+	if _, ok := alias.Table[name]; ok {
+		return true
+	}
+	if _, ok := buildInCommand[name]; ok {
+		return true
+	}
+	if _, ok := functionTable[name]; ok {
+		return true
+	}
+	if _, ok := keywords[name]; ok {
+		return true
+	}
+	if len(name) == 2 && name[1] == ':' {
+		// drive letter
+		return true
+	}
+	return lookPathCached(name)
+}
+
+// looksLikePath returns true when word seems to be a path to check if it
+// exists. The options such as `/s` and URLs are not paths.
+func looksLikePath(word string) bool {
+	if strings.Contains(word, "://") || strings.ContainsAny(word, "*?") {
+		return false
+	}
+	return strings.ContainsRune(word, os.PathSeparator) ||
+		strings.HasPrefix(word, "./") || strings.HasPrefix(word, "../") ||
+		strings.HasPrefix(word, "~/")
+}
+
+// pathExists returns true when path exists. When dirOnly is true, only
+// its directory has to exist for the path being typed or to be created.
+func pathExists(path string, dirOnly bool) bool {
+	if strings.HasPrefix(path, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + path[1:]
+		}
+	}
+	if dirOnly {
+		path = filepath.Dir(path)
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// highlighter collects the colors of the line in the byte offsets.
+type highlighter struct {
+	text   string
+	result []readline.Highlight
+}
+
+func (h *highlighter) add(start, end int, name string) {
+	color := HighlightColors[name]
+	if color == "" || start >= end {
+		return
+	}
+	h.result = append(h.result, readline.Highlight{Start: start, End: end, Color: color})
+}
+
+// word colors the strings and %VAR% in the word as the lexer reads them.
+func (h *highlighter) word(w *shell.WordNode) {
+	source := w.Source
+	quote := byte(0)
+	quoteStart := 0
+	yenCount := 0
+	for i := 0; i < len(source); i++ {
+		ch := source[i]
+		switch {
+		case ch == '\\':
+			yenCount++
+			continue
+		case quote == 0 && (ch == '\'' || ch == '"') && yenCount%2 == 0:
+			quote = ch
+			quoteStart = i
+		case quote != 0 && ch == quote && yenCount%2 == 0:
+			h.add(w.Pos()+quoteStart, w.Pos()+i+1, "string")
+			quote = 0
+		case ch == '%' && quote != '\'':
+			end := strings.IndexByte(source[i+1:], '%')
+			if end < 0 {
+				break
+			}
+			name := source[i+1 : i+1+end]
+			if name == "" || strings.ContainsAny(name, " \t\"'") {
+				break
+			}
+			if quote != 0 {
+				// close the string before the variable and open again after it.
+				h.add(w.Pos()+quoteStart, w.Pos()+i, "string")
+				quoteStart = i + end + 2
+			}
+			h.add(w.Pos()+i, w.Pos()+i+end+2, "variable")
+			i += end + 1
+		}
+		yenCount = 0
+	}
+	if quote != 0 {
+		// the string which is not closed yet
+		h.add(w.Pos()+quoteStart, w.Pos()+len(source), "string")
+	}
+}
+
+// path flags the word when the path does not exist. The word at the end
+// of the line is being typed and only its directory is checked.
+func (h *highlighter) path(w *shell.WordNode, dirOnly bool) {
+	if !pathExists(w.Text, dirOnly || w.End() >= len(h.text)) {
+		h.add(w.Pos(), w.End(), "nofile")
+	}
+}
+
+func (h *highlighter) walk(node shell.Node) {
+	shell.Walk(node, func(n shell.Node) bool {
+		switch v := n.(type) {
+		case *shell.CommandNode:
+			for i, w := range v.Words {
+				if i == 0 {
+					if commandExists(w.Text) {
+						h.add(w.Pos(), w.End(), "command")
+					} else {
+						h.add(w.Pos(), w.End(), "unknown")
+					}
+				} else if looksLikePath(w.Text) {
+					h.path(w, false)
+				}
+				h.word(w)
+			}
+		case *shell.RedirectNode:
+			h.redirect(v)
+		case *shell.OperatorNode:
+			h.add(v.Pos(), v.End(), "operator")
+		}
+		return true
+	})
+}
+
+func (h *highlighter) redirect(r *shell.RedirectNode) {
+	if r.Target == nil {
+		h.add(r.Pos(), r.End(), "redirect")
+		return
+	}
+	h.add(r.Pos(), r.Target.Pos(), "redirect")
+	// the file to write may not exist yet
+	h.path(r.Target, !strings.HasPrefix(strings.TrimLeft(r.Op, "0123456789"), "<"))
+	h.word(r.Target)
+}
+
+// highlight returns the colors of the command line in the rune offsets.
+func highlight(ctx context.Context, text string) []readline.Highlight {
+	h := &highlighter{text: text}
+	node, _ := shell.Parse(text)
+	if node == nil {
+		return nil
+	}
+	h.walk(node)
+
+	// byte offsets to rune offsets
+	runeIndex := make([]int, len(text)+1)
+	n := 0
+	for i := range text {
+		runeIndex[i] = n
+		n++
+	}
+	runeIndex[len(text)] = n
+	for i := range h.result {
+		h.result[i].Start = runeIndex[h.result[i].Start]
+		h.result[i].End = runeIndex[h.result[i].End]
+	}
+	return h.result
+}
+
+func init() {
+	readline.SetHighlightHook(highlight)
+}
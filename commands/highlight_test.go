@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	backup := HighlightColors
+	defer func() { HighlightColors = backup }()
+	HighlightColors = map[string]string{
+		"command":  "C",
+		"unknown":  "U",
+		"string":   "S",
+		"variable": "V",
+		"operator": "O",
+		"redirect": "R",
+		"nofile":   "N",
+	}
+	tests := []struct{ text, expect string }{
+		{`echo "a %X%"|nosuch_cmd`, `CCCC SSSVVVSOUUUUUUUUUU`},
+		{`echo ＡＢ 'c' && x_x <./no/such`, `CCCC __ SSS OO UUU RNNNNNNNNN`},
+		{`echo a>./no/such/out`, `CCCC _RNNNNNNNNNNNNN`},
+		{`cd ./no/such/dir`, `CC NNNNNNNNNNNNN`},
+		{`type ./no_such`, `CCCC _________`},
+		{`type ./no_such x`, `CCCC NNNNNNNNN _`},
+	}
+	for _, test := range tests {
+		colors := []rune(test.text)
+		for i := range colors {
+			if colors[i] != ' ' {
+				colors[i] = '_'
+			}
+		}
+		for _, h := range highlight(context.Background(), test.text) {
+			for i := h.Start; i < h.End; i++ {
+				colors[i] = []rune(h.Color)[0]
+			}
+		}
+		if string(colors) != test.expect {
+			t.Errorf("highlight(%q)\n\t= %q\n\texpected %q", test.text, string(colors), test.expect)
+		}
+	}
+}
+
+func TestLookPathCached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "highlight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "nyagos_test_cmd")
+	if runtime.GOOS == "windows" {
+		path += ".exe"
+	}
+	if err := ioutil.WriteFile(path, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir)
+	if !lookPathCached("nyagos_test_cmd") {
+		t.Fatal("not found")
+	}
+
+	// the result is kept until a command is executed or %PATH% is changed.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if !lookPathCached("nyagos_test_cmd") {
+		t.Fatal("not cached")
+	}
+	clearLookPathCache()
+	if lookPathCached("nyagos_test_cmd") {
+		t.Fatal("found after cleared")
+	}
+	if err := ioutil.WriteFile(path, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("PATH", dir+string(os.PathListSeparator))
+	if !lookPathCached("nyagos_test_cmd") {
+		t.Fatal("not found after %PATH% is changed")
+	}
+}
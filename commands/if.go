@@ -133,10 +133,15 @@ func cmdIf(ctx context.Context, cmd Param) (int, error) {
 		}
 	}
 
+	var rc int
+	var err error
 	if status {
-		cmd.Loop(ctx, &thenBuffer)
+		rc, err = cmd.Loop(ctx, &thenBuffer)
 	} else {
-		cmd.Loop(ctx, &elseBuffer)
+		rc, err = cmd.Loop(ctx, &elseBuffer)
+	}
+	if stopsBlock(ctx, rc, err) {
+		return rc, io.EOF
 	}
 	return 0, nil
 }
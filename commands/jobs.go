@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/zetamatta/nyagos/shell"
+)
+
+const (
+	errnoNoSuchJob = 1
+)
+
+func jobSpec(cmd Param) string {
+	if len(cmd.Args()) >= 2 {
+		return cmd.Arg(1)
+	}
+	return ""
+}
+
+func cmdJobs(ctx context.Context, cmd Param) (int, error) {
+	showPid := len(cmd.Args()) >= 2 && cmd.Arg(1) == "-l"
+	for _, job := range shell.Jobs() {
+		if showPid {
+			fmt.Fprintf(cmd.Out(), "%s (pid %v)\n", job.String(), job.Pids())
+		} else {
+			fmt.Fprintln(cmd.Out(), job.String())
+		}
+		if job.Done() {
+			// finished jobs are listed only once.
+			job.Wait(ctx)
+		}
+	}
+	return 0, nil
+}
+
+func cmdFg(ctx context.Context, cmd Param) (int, error) {
+	job, err := shell.FindJob(jobSpec(cmd))
+	if err != nil {
+		return errnoNoSuchJob, err
+	}
+	fmt.Fprintln(cmd.Out(), job.CommandLine)
+	errorlevel, err := job.Wait(ctx)
+	if err != nil {
+		if !shell.Interrupted(ctx) {
+			return errorlevel, err
+		}
+		// Ctrl-C stops the job in the foreground.
+		job.Kill()
+		return job.Wait(context.Background())
+	}
+	return errorlevel, nil
+}
+
+func cmdBg(ctx context.Context, cmd Param) (int, error) {
+	job, err := shell.FindJob(jobSpec(cmd))
+	if err != nil {
+		return errnoNoSuchJob, err
+	}
+	if err := job.Continue(); err != nil {
+		return 1, err
+	}
+	fmt.Fprintln(cmd.Out(), job.String())
+	return 0, nil
+}
+
+func cmdWait(ctx context.Context, cmd Param) (int, error) {
+	var jobs []*shell.Job
+	if len(cmd.Args()) < 2 {
+		jobs = shell.Jobs()
+	} else {
+		for _, spec := range cmd.Args()[1:] {
+			job, err := shell.FindJob(spec)
+			if err != nil {
+				return errnoNoSuchJob, err
+			}
+			jobs = append(jobs, job)
+		}
+	}
+	errorlevel := 0
+	for _, job := range jobs {
+		var err error
+		errorlevel, err = job.Wait(ctx)
+		if err != nil {
+			return errorlevel, err
+		}
+	}
+	return errorlevel, nil
+}
+
+func cmdKill(ctx context.Context, cmd Param) (int, error) {
+	if len(cmd.Args()) < 2 {
+		fmt.Fprintln(cmd.Err(), "usage: kill %JOBID|PID ...")
+		return 1, nil
+	}
+	for _, arg := range cmd.Args()[1:] {
+		if strings.HasPrefix(arg, "%") {
+			job, err := shell.FindJob(arg)
+			if err != nil {
+				return errnoNoSuchJob, err
+			}
+			if err := job.Kill(); err != nil {
+				return 1, err
+			}
+			continue
+		}
+		pid, err := strconv.Atoi(arg)
+		if err != nil {
+			return 1, fmt.Errorf("%s: arguments must be process or job IDs", arg)
+		}
+		process, err := os.FindProcess(pid)
+		if err != nil {
+			return 1, err
+		}
+		if err := process.Kill(); err != nil {
+			return 1, fmt.Errorf("%d: %s", pid, err.Error())
+		}
+	}
+	return 0, nil
+}
@@ -13,10 +13,9 @@ import (
 	"time"
 
 	"github.com/dustin/go-humanize"
-	"github.com/zetamatta/go-box"
-	"github.com/zetamatta/go-findfile"
 
 	"github.com/zetamatta/nyagos/dos"
+	"github.com/zetamatta/nyagos/readline"
 )
 
 const (
@@ -95,7 +94,7 @@ func lsOneLong(folder string, status os.FileInfo, flag int, width int, out io.Wr
 	mode := status.Mode()
 	perm := mode.Perm()
 	name := status.Name()
-	attr := findfile.GetFileAttributes(status)
+	attr := dos.GetFileInfoAttributes(status)
 
 	putFlag(uint32(perm), 4, "r", out)
 	if (perm & 2) > 0 {
@@ -216,7 +215,7 @@ func lsBox(ctx context.Context, folder string, nodes []os.FileInfo, flag int, ou
 				indicator = "*"
 			}
 		}
-		attr := findfile.GetFileAttributes(val)
+		attr := dos.GetFileInfoAttributes(val)
 		if (attr&dos.FILE_ATTRIBUTE_HIDDEN) != 0 &&
 			(flag&O_COLOR) != 0 {
 			prefix = ANSI_HIDDEN
@@ -230,7 +229,7 @@ func lsBox(ctx context.Context, folder string, nodes []os.FileInfo, flag int, ou
 		}
 		nodes_[key] = prefix + val.Name() + postfix + indicator
 	}
-	if !box.Print(ctx, nodes_, out) {
+	if !readline.BoxPrint(ctx, nodes_, out) {
 		return ErrCtrlC
 	}
 	return nil
@@ -279,7 +278,7 @@ func lsSimple(ctx context.Context, folder string, nodes []os.FileInfo, flag int,
 	for _, f := range nodes {
 		io.WriteString(out, f.Name())
 		if (flag & O_INDICATOR) != 0 {
-			if attr := findfile.GetFileAttributes(f); (attr&dos.FILE_ATTRIBUTE_REPARSE_POINT) != 0 && hasLink(folder, f.Name()) {
+			if attr := dos.GetFileInfoAttributes(f); (attr&dos.FILE_ATTRIBUTE_REPARSE_POINT) != 0 && hasLink(folder, f.Name()) {
 				io.WriteString(out, "@")
 			} else if f.IsDir() {
 				io.WriteString(out, "/")
@@ -351,7 +350,7 @@ func lsFolder(ctx context.Context, folder string, flag int, out io.Writer) error
 		wildcard = dos.Join(folder, "*")
 	}
 	canceled := false
-	findfile.Walk(wildcard, func(f *findfile.FileInfo) bool {
+	dos.Walk(wildcard, func(f *dos.FileInfo) bool {
 		if isCancel(ctx) {
 			canceled = true
 			return false
@@ -360,7 +359,7 @@ func lsFolder(ctx context.Context, folder string, flag int, out io.Writer) error
 			if strings.HasPrefix(f.Name(), ".") {
 				return true
 			}
-			attr := findfile.GetFileAttributes(f)
+			attr := dos.GetFileInfoAttributes(f)
 			if (attr & dos.FILE_ATTRIBUTE_HIDDEN) != 0 {
 				return true
 			}
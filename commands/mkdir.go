@@ -6,9 +6,8 @@ import (
 	"os"
 	"syscall"
 
-	"github.com/zetamatta/go-getch"
-
 	"github.com/zetamatta/nyagos/dos"
+	"github.com/zetamatta/nyagos/readline"
 )
 
 func cmdMkdir(ctx context.Context, cmd Param) (int, error) {
@@ -64,7 +63,7 @@ func cmdRmdir(ctx context.Context, cmd Param) (int, error) {
 		}
 		if !quiet {
 			fmt.Fprintf(cmd.Err(), message, arg1)
-			ch := getch.Rune()
+			ch := readline.GetRune()
 			fmt.Fprintf(cmd.Err(), "%c ", ch)
 			switch ch {
 			case 'y', 'Y':
@@ -12,9 +12,9 @@ import (
 	"unicode/utf8"
 
 	"github.com/mattn/go-runewidth"
-	"github.com/zetamatta/go-box"
-	"github.com/zetamatta/go-getch"
-	"github.com/zetamatta/go-mbcs"
+
+	"github.com/zetamatta/nyagos/dos"
+	"github.com/zetamatta/nyagos/readline"
 )
 
 var ansiStrip = regexp.MustCompile("\x1B[^a-zA-Z]*[A-Za-z]")
@@ -33,7 +33,7 @@ func more(r io.Reader, cmd Param) bool {
 			text = string(line)
 		} else {
 			var err error
-			text, err = mbcs.AtoU(line)
+			text, err = dos.AtoU(line)
 			if err != nil {
 				text = err.Error()
 			}
@@ -43,7 +43,7 @@ func more(r io.Reader, cmd Param) bool {
 		lines := (width + screenWidth) / screenWidth
 		for count+lines >= screenHeight {
 			io.WriteString(cmd.Err(), "more>")
-			ch := getch.Rune()
+			ch := readline.GetRune()
 			io.WriteString(cmd.Err(), "\r     \b\b\b\b\b")
 			if ch == 'q' {
 				return false
@@ -64,7 +64,7 @@ func more(r io.Reader, cmd Param) bool {
 
 func cmdMore(ctx context.Context, cmd Param) (int, error) {
 	count := 0
-	screenWidth, screenHeight = box.GetScreenBufferInfo().ViewSize()
+	screenWidth, screenHeight = readline.GetViewSize()
 	for _, arg1 := range cmd.Args()[1:] {
 		if arg1 == "-b" {
 			bold = true
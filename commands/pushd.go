@@ -18,6 +18,11 @@ const (
 )
 
 func cmdDirs(ctx context.Context, cmd Param) (int, error) {
+	if len(cmd.Args()) >= 2 && cmd.Arg(1) == "--restore" {
+		if rc, err := restoreDirStack(); err != nil {
+			return rc, err
+		}
+	}
 	wd, err := os.Getwd()
 	if err != nil {
 		return getwdFail, err
@@ -39,6 +44,7 @@ func cmdPopd(ctx context.Context, cmd Param) (int, error) {
 		return errnoChdirFail, err
 	}
 	dirstack = dirstack[:len(dirstack)-1]
+	saveDirStack()
 	return cmdDirs(ctx, cmd)
 }
 
@@ -63,5 +69,6 @@ func cmdPushd(ctx context.Context, cmd Param) (int, error) {
 		}
 		dirstack[len(dirstack)-1] = wd
 	}
+	saveDirStack()
 	return cmdDirs(ctx, cmd)
 }
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/zetamatta/nyagos/completion"
+	"github.com/zetamatta/nyagos/history"
 	"github.com/zetamatta/nyagos/readline"
 	"github.com/zetamatta/nyagos/shell"
 	"github.com/zetamatta/nyagos/texts"
@@ -44,6 +45,16 @@ type optionT struct {
 
 // BoolOptions are the all global option list.
 var BoolOptions = map[string]*optionT{
+	"autosuggest": {
+		V:       &readline.AutoSuggest,
+		Usage:   "suggest the rest of the line from the history in grey",
+		NoUsage: "do not suggest the rest of the line",
+	},
+	"autosuggest_dir": {
+		V:       &readline.SuggestDirOnly,
+		Usage:   "suggest only the commands executed in the current directory",
+		NoUsage: "suggest the commands executed in any directory",
+	},
 	"cleanup_buffer": {
 		V:       &readline.FlushBeforeReadline,
 		Usage:   "Clean up key buffer at prompt",
@@ -54,26 +65,97 @@ var BoolOptions = map[string]*optionT{
 		Usage:   "Include hidden files on completion",
 		NoUsage: "Do not include hidden files on completion",
 	},
+	"completion_menu": {
+		V:       &completion.MenuComplete,
+		Usage:   "select the candidate with the menu on ambiguous completion",
+		NoUsage: "list the candidates on ambiguous completion",
+	},
 	"completion_slash": {
 		V:       &completion.UseSlash,
 		Usage:   "use forward slash on completion",
 		NoUsage: "Do not use slash on completion",
 	},
+	"dir_history": {
+		V:       &readline.DirHistoryFirst,
+		Usage:   "Up/Down recall the commands executed in the current directory first",
+		NoUsage: "Up/Down recall the commands in the order executed",
+	},
+	"errexit": {
+		V:       &shell.ErrExit,
+		Usage:   "stop the script when a command fails",
+		NoUsage: "continue the script even if a command fails",
+	},
 	"glob": {
 		V:       &shell.WildCardExpansionAlways,
 		Usage:   "Enable to expand wildcards",
 		NoUsage: "Disable to expand wildcards",
 	},
+	"highlight": {
+		V:       &readline.SyntaxHighlight,
+		Usage:   "color the command line while typing (see nyagos.highlight)",
+		NoUsage: "do not color the command line",
+	},
+	"ignoredups": {
+		V:       &history.IgnoreDups,
+		Usage:   "Do not record the line same as the previous one into the history",
+		NoUsage: "record the line same as the previous one into the history",
+	},
+	"ignorespace": {
+		V:       &history.IgnoreSpace,
+		Usage:   "Do not record the line starting with a space into the history",
+		NoUsage: "record the line starting with a space into the history",
+	},
+	"isearch_ignorecase": {
+		V:       &readline.IncSearchIgnoreCase,
+		Usage:   "Incremental search ignores the case",
+		NoUsage: "Incremental search distinguishes the case",
+	},
+	"isearch_regexp": {
+		V:       &readline.IncSearchRegexp,
+		Usage:   "Incremental search uses regular expressions",
+		NoUsage: "Incremental search uses plain strings",
+	},
+	"multiline": {
+		V:       &readline.MultiLine,
+		Usage:   "Edit the command over multiple rows",
+		NoUsage: "Edit the command on one row",
+	},
+	"nounset": {
+		V:       &shell.NoUnset,
+		Usage:   "make the unknown %NAME% an error",
+		NoUsage: "leave the unknown %NAME% as it is",
+	},
 	"noclobber": {
 		V:       &shell.NoClobber,
 		Usage:   "forbide to overwrite files on redirect",
 		NoUsage: "Do not forbide to overwrite files no redirect",
 	},
+	"pipefail": {
+		V:       &shell.PipeFail,
+		Usage:   "the errorlevel of the pipeline is the last non-zero one of its commands",
+		NoUsage: "the errorlevel of the pipeline is the one of the last command",
+	},
+	"share_history": {
+		V:       &history.ShareHistory,
+		Usage:   "read the history of other sessions before every prompt",
+		NoUsage: "read the history of other sessions only by `history -r`",
+	},
 	"usesource": {
 		V:       &shell.UseSourceRunBatch,
 		Usage:   "allow batchfile to change environment variables of nyagos",
 		NoUsage: "forbide batchfile to change environment variables of nyagos",
 	},
+	"xtrace": {
+		V:       &shell.XTrace,
+		Usage:   "print commands and their arguments before execution",
+		NoUsage: "Do not print commands before execution",
+	},
+}
+
+func init() {
+	completion.OptionNames = func() []string {
+		return texts.SortedKeys(BoolOptions)
+	}
 }
 
 func dumpBoolOptions(out io.Writer) {
@@ -9,7 +9,7 @@ import (
 	"strings"
 	"unicode/utf8"
 
-	"github.com/zetamatta/go-mbcs"
+	"github.com/zetamatta/nyagos/dos"
 )
 
 func cat(ctx context.Context, r io.Reader, w io.Writer) bool {
@@ -29,7 +29,7 @@ func cat(ctx context.Context, r io.Reader, w io.Writer) bool {
 			text = string(line)
 		} else {
 			var err error
-			text, err = mbcs.AtoU(line)
+			text, err = dos.AtoU(line)
 			if err != nil {
 				text = err.Error()
 			}
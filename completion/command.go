@@ -7,14 +7,8 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
-
-	"github.com/zetamatta/nyagos/dos"
 )
 
-func isExecutable(path string) bool {
-	return dos.IsExecutableSuffix(filepath.Ext(path))
-}
-
 func listUpAllExecutableOnEnv(envName string) []Element {
 	list := make([]Element, 0, 100)
 	pathEnv := os.Getenv(envName)
@@ -29,10 +23,9 @@ func listUpAllExecutableOnEnv(envName string) []Element {
 				continue
 			}
 			name := file1.Name()
-			if isExecutable(name) {
+			if isExecutable(filepath.Join(dir1, name)) {
 				name_ := path.Base(name)
-				element := Element1(name_)
-				list = append(list, element)
+				list = append(list, Candidate{Text: name_, Kind: KindExecutable})
 			}
 		}
 	}
@@ -46,21 +39,29 @@ func listUpCurrentAllExecutable(ctx context.Context, str string) ([]Element, err
 	}
 	list := make([]Element, 0, len(listTmp))
 	for _, p := range listTmp {
-		if endWithRoot(p.String()) || isExecutable(p.String()) {
+		if endWithRoot(p.String()) {
 			list = append(list, p)
+		} else if isExecutable(p.String()) {
+			c := toCandidate(p)
+			c.Kind = KindExecutable
+			list = append(list, c)
 		}
 	}
 	return list, nil
 }
 
+// removeDup removes the elements which have the same string. The one
+// whose Kind is prior (an alias rather than an executable) is kept.
 func removeDup(list []Element) []Element {
-	found := map[string]struct{}{}
+	found := map[string]int{}
 	result := make([]Element, 0, len(list))
 
 	for _, value := range list {
-		if _, ok := found[value.String()]; !ok {
+		if i, ok := found[value.String()]; !ok {
+			found[value.String()] = len(result)
 			result = append(result, value)
-			found[value.String()] = struct{}{}
+		} else if toCandidate(value).Kind < toCandidate(result[i]).Kind {
+			result[i] = value
 		}
 	}
 	return result
@@ -0,0 +1,10 @@
+package completion
+
+import (
+	"os"
+)
+
+func isExecutable(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && !stat.IsDir() && (stat.Mode().Perm()&0111) != 0
+}
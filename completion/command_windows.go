@@ -0,0 +1,11 @@
+package completion
+
+import (
+	"path/filepath"
+
+	"github.com/zetamatta/nyagos/dos"
+)
+
+func isExecutable(path string) bool {
+	return dos.IsExecutableSuffix(filepath.Ext(path))
+}
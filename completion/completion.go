@@ -5,11 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"unicode"
 
-	"github.com/zetamatta/go-box"
-
 	"github.com/zetamatta/nyagos/readline"
 	"github.com/zetamatta/nyagos/texts"
 )
@@ -29,6 +28,58 @@ type Element1 string
 func (s Element1) String() string  { return string(s) }
 func (s Element1) Display() string { return string(s) }
 
+// Kind is the kind of the candidate, by which the menu groups them.
+type Kind int
+
+const (
+	KindOther Kind = iota // subcommands, flags and values by specifications
+	KindAlias
+	KindFunction
+	KindBuiltin
+	KindExecutable
+	KindDirectory
+	KindFile
+)
+
+var kindNames = map[Kind]string{
+	KindAlias:      "aliases",
+	KindFunction:   "functions",
+	KindBuiltin:    "built-in commands",
+	KindExecutable: "executables",
+	KindDirectory:  "directories",
+	KindFile:       "files",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Candidate is the Element with its kind and description.
+type Candidate struct {
+	Text        string
+	Shown       string // Display() returns Text when it is empty
+	Description string
+	Kind        Kind
+}
+
+func (c Candidate) String() string { return c.Text }
+
+func (c Candidate) Display() string {
+	if c.Shown == "" {
+		return c.Text
+	}
+	return c.Shown
+}
+
+// toCandidate returns e as Candidate. The elements other than Candidate
+// are KindOther.
+func toCandidate(e Element) Candidate {
+	if c, ok := e.(Candidate); ok {
+		return c
+	}
+	return Candidate{Text: e.String(), Shown: e.Display()}
+}
+
 type List struct {
 	AllLine string
 	List    []Element
@@ -41,17 +92,6 @@ type List struct {
 
 var UseSlash = false
 
-func isTop(s string, indexes [][]int) bool {
-	if len(indexes) < 1 {
-		return true
-	}
-	if len(indexes) == 1 {
-		return indexes[0][1] == len(s)
-	}
-	prev := s[indexes[len(indexes)-2][0]:indexes[len(indexes)-2][1]]
-	return prev == ";" || prev == "|" || prev == "&"
-}
-
 func listUpComplete(ctx context.Context, this *readline.Buffer) (*List, rune, error) {
 	var err error
 	rv := &List{
@@ -90,17 +130,31 @@ func listUpComplete(ctx context.Context, this *readline.Buffer) (*List, rune, er
 
 	start := strings.LastIndexAny(rv.Word, ";=") + 1
 
-	if isTop(rv.Left, indexes) {
+	pos := parsePosition(rv.Left)
+	if pos.redirect >= 0 {
+		// The operator such as `>` or `2>` may be followed by the target
+		// without spaces.
+		if n := pos.redirect - len(string([]rune(rv.Left)[:rv.Pos])); n > start && n <= len(rv.Word) {
+			start = n
+		}
+		rv.List, err = listUpFiles(ctx, rv.Word[start:])
+	} else if pos.top {
 		rv.List, err = listUpCommands(ctx, rv.Word[start:])
+	} else if list, ok, err1 := listUpSpec(ctx, pos, rv.Word[:start], rv.Word[start:]); ok {
+		rv.List, err = list, err1
 	} else {
 		rv.List, err = listUpFiles(ctx, rv.Word[start:])
 	}
 
 	for i := 0; i < len(rv.List); i++ {
-		rv.List[i] = Element2{
-			rv.Word[:start] + rv.List[i].String(),
-			rv.List[i].Display(),
-		}
+		c := toCandidate(rv.List[i])
+		c.Shown = c.Display()
+		c.Text = rv.Word[:start] + c.Text
+		rv.List[i] = c
+	}
+	if isSuggesting(ctx) {
+		// the hooks by Lua are too slow to call on every key.
+		return rv, default_delimiter, err
 	}
 	for _, f := range HookToList {
 		rv, err = f(ctx, this, rv)
@@ -136,7 +190,7 @@ func KeyFuncCompletionList(ctx context.Context, this *readline.Buffer) readline.
 	if err != nil {
 		fmt.Fprintf(this.Writer, "(warning) %s\n", err.Error())
 	}
-	box.Print(ctx, toDisplay(comp.List), this.Writer)
+	readline.BoxPrint(ctx, toDisplay(comp.List), this.Writer)
 	this.RepaintAll()
 	return readline.CONTINUE
 }
@@ -181,26 +235,53 @@ func endWithRoot(path string) bool {
 	return len(path) >= 1 && os.IsPathSeparator(path[len(path)-1])
 }
 
-func KeyFuncCompletion(ctx context.Context, this *readline.Buffer) readline.Result {
-	comp, default_delimiter, err := listUpComplete(ctx, this)
-	if comp.List == nil || len(comp.List) <= 0 {
-		return readline.CONTINUE
-	}
+// MenuComplete makes Tab select the candidate with the menu when they
+// have no common prefix longer than the word.
+var MenuComplete = false
 
-	slashToBackSlash := true
+// useBackSlash returns true when the path separators of the candidates
+// should be backslashes.
+func (comp *List) useBackSlash() bool {
 	firstFoundSlashPos := strings.IndexRune(comp.Word, '/')
 	firstFoundBackSlashPos := strings.IndexRune(comp.Word, os.PathSeparator)
 	if UseSlash {
-		slashToBackSlash = false
-		if firstFoundBackSlashPos >= 0 && (firstFoundSlashPos == -1 || firstFoundBackSlashPos < firstFoundSlashPos) {
-			slashToBackSlash = true
+		return firstFoundBackSlashPos >= 0 && (firstFoundSlashPos == -1 || firstFoundBackSlashPos < firstFoundSlashPos)
+	}
+	return !(firstFoundSlashPos >= 0 && (firstFoundBackSlashPos == -1 || firstFoundSlashPos < firstFoundBackSlashPos))
+}
+
+// completionText returns the text to replace the word with. str is quoted
+// with quotechar when it is not zero. When single is true, str is the only
+// candidate and the quotation is closed.
+func completionText(str string, quotechar byte, single, slashToBackSlash bool) string {
+	if quotechar != 0 {
+		var buffer strings.Builder
+		buffer.Grow(len(str) + 3)
+		if len(str) >= 2 && str[0] == '~' && os.IsPathSeparator(str[1]) {
+			buffer.WriteString(str[:1])
+			buffer.WriteByte(quotechar)
+			buffer.WriteString(str[1:])
+		} else {
+			buffer.WriteByte(quotechar)
+			buffer.WriteString(str)
 		}
-	} else {
-		if firstFoundSlashPos >= 0 && (firstFoundBackSlashPos == -1 || firstFoundSlashPos < firstFoundBackSlashPos) {
-			slashToBackSlash = false
+		if single && !endWithRoot(str) {
+			buffer.WriteByte(quotechar)
 		}
+		str = buffer.String()
+	}
+	if single && !endWithRoot(str) && !strings.HasSuffix(str, `%`) && !strings.HasSuffix(str, "=") {
+		str += " "
+	}
+	if slashToBackSlash {
+		str = filepath.FromSlash(str)
 	}
+	return str
+}
 
+// commonText returns the text to replace the word with the common prefix
+// of the candidates.
+func (comp *List) commonText(default_delimiter rune) string {
 	complete_list := toComplete(comp.List)
 	commonStr := CommonPrefix(complete_list)
 	quotechar := byte(0)
@@ -214,37 +295,75 @@ func KeyFuncCompletion(ctx context.Context, this *readline.Buffer) readline.Resu
 			}
 		}
 	}
-	if quotechar != 0 {
-		var buffer strings.Builder
-		buffer.Grow(len(commonStr) + 3)
-		if len(commonStr) >= 2 && commonStr[0] == '~' && os.IsPathSeparator(commonStr[1]) {
-			buffer.WriteString(commonStr[:1])
-			buffer.WriteByte(quotechar)
-			buffer.WriteString(commonStr[1:])
-		} else {
-			buffer.WriteByte(quotechar)
-			buffer.WriteString(commonStr)
+	return completionText(commonStr, quotechar, len(comp.List) == 1, comp.useBackSlash())
+}
+
+// selectMenu lets the user select one of the candidates with the menu
+// grouped by their kinds.
+func selectMenu(ctx context.Context, this *readline.Buffer, comp *List, default_delimiter rune) readline.Result {
+	candidates := make([]Candidate, len(comp.List))
+	for i, e := range comp.List {
+		candidates[i] = toCandidate(e)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Kind < candidates[j].Kind
+	})
+	slashToBackSlash := comp.useBackSlash()
+	wordQuote := byte(0)
+	if i := strings.IndexAny(comp.Word, readline.Delimiters); i >= 0 {
+		wordQuote = comp.Word[i]
+	}
+	items := make([]readline.MenuItem, len(candidates))
+	for i, c := range candidates {
+		quotechar := wordQuote
+		if quotechar == 0 && strings.ContainsAny(c.Text, " &!") {
+			quotechar = byte(default_delimiter)
 		}
-		if len(comp.List) == 1 && !endWithRoot(comp.List[0].String()) {
-			buffer.WriteByte(quotechar)
+		items[i] = readline.MenuItem{
+			Text:        completionText(c.Text, quotechar, true, slashToBackSlash),
+			Display:     c.Display(),
+			Description: c.Description,
+			Group:       c.Kind.String(),
 		}
-		commonStr = buffer.String()
 	}
-	if len(comp.List) == 1 && !endWithRoot(commonStr) && !strings.HasSuffix(commonStr, `%`) {
-		commonStr += " "
+	return this.SelectMenu(ctx, comp.Pos, items)
+}
+
+// complete replaces the word with the common prefix of the candidates.
+// When it is not longer than the word, the candidates are listed or
+// selected with the menu by MenuComplete. menu opens the menu always for
+// more than one candidate.
+func complete(ctx context.Context, this *readline.Buffer, menu bool) readline.Result {
+	comp, default_delimiter, err := listUpComplete(ctx, this)
+	if comp.List == nil || len(comp.List) <= 0 {
+		return readline.CONTINUE
 	}
-	if slashToBackSlash {
-		commonStr = filepath.FromSlash(commonStr)
+	if menu && len(comp.List) > 1 {
+		return selectMenu(ctx, this, comp, default_delimiter)
 	}
+
+	commonStr := comp.commonText(default_delimiter)
 	if comp.RawWord == commonStr {
+		if MenuComplete && len(comp.List) > 1 {
+			return selectMenu(ctx, this, comp, default_delimiter)
+		}
 		this.Writer.WriteByte('\n')
 		if err != nil {
 			fmt.Fprintf(this.Writer, "(warning) %s\n", err.Error())
 		}
-		box.Print(nil, toDisplay(comp.List), this.Writer)
+		readline.BoxPrint(nil, toDisplay(comp.List), this.Writer)
 		this.RepaintAll()
 		return readline.CONTINUE
 	}
 	this.ReplaceAndRepaint(comp.Pos, commonStr)
 	return readline.CONTINUE
 }
+
+func KeyFuncCompletion(ctx context.Context, this *readline.Buffer) readline.Result {
+	return complete(ctx, this, false)
+}
+
+// KeyFuncCompletionMenu selects the candidate with the menu.
+func KeyFuncCompletionMenu(ctx context.Context, this *readline.Buffer) readline.Result {
+	return complete(ctx, this, true)
+}
@@ -0,0 +1,129 @@
+package completion
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/zetamatta/nyagos/readline"
+	"github.com/zetamatta/nyagos/shell"
+)
+
+// OptionNames returns the names of the options for `set -o`.
+var OptionNames = func() []string { return nil }
+
+// position is where the word being completed is in the command line.
+type position struct {
+	top      bool     // the word is the command name
+	redirect int      // the offset where the target of the redirection starts, or -1
+	name     string   // the command name
+	args     []string // the words between the command name and the word
+}
+
+// parsePosition finds the position of the end of left by the syntax tree.
+func parsePosition(left string) *position {
+	pos := &position{redirect: -1}
+	node, _ := shell.Parse(left)
+	var lastCommand *shell.CommandNode
+	var lastRedirect *shell.RedirectNode
+	lastOperator := -1
+	shell.Walk(node, func(n shell.Node) bool {
+		switch v := n.(type) {
+		case *shell.CommandNode:
+			lastCommand = v
+		case *shell.RedirectNode:
+			lastRedirect = v
+		case *shell.OperatorNode:
+			lastOperator = v.Pos()
+		}
+		return true
+	})
+	if r := lastRedirect; r != nil && r.Pos() > lastOperator {
+		if r.Target != nil && r.Target.End() == len(left) {
+			pos.redirect = r.Target.Pos()
+			return pos
+		}
+		if r.Target == nil && !strings.Contains(r.Op, "&") &&
+			strings.TrimSpace(left[r.End():]) == "" {
+			pos.redirect = len(left)
+			return pos
+		}
+	}
+	if lastCommand == nil || lastOperator >= lastCommand.End() {
+		pos.top = true
+		return pos
+	}
+	words := lastCommand.Words
+	if len(words) > 0 && words[len(words)-1].End() == len(left) {
+		words = words[:len(words)-1]
+	}
+	if len(words) <= 0 {
+		pos.top = len(lastCommand.Words) > 0
+		return pos
+	}
+	pos.name = words[0].Text
+	pos.args = make([]string, len(words)-1)
+	for i, w := range words[1:] {
+		pos.args[i] = w.Text
+	}
+	return pos
+}
+
+func listUpKeyNames(ctx context.Context, word string, args []string) []string {
+	return readline.KeyNames()
+}
+
+func listUpFuncNames(ctx context.Context, word string, args []string) []string {
+	names := make([]string, 0, len(readline.NAME2FUNC))
+	for name := range readline.NAME2FUNC {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func listUpOptionNames(ctx context.Context, word string, args []string) []string {
+	return OptionNames()
+}
+
+// builtinSpecs are the specifications for the built-in commands, which
+// are used when the users do not define them with SetSpec.
+var builtinSpecs = map[string]*Spec{}
+
+func init() {
+	directories := &Spec{Rest: &Arg{Type: ValueDirectory}}
+	for _, name := range []string{"cd", "pushd", "rd", "rmdir"} {
+		builtinSpecs[name] = directories
+	}
+	builtinSpecs["which"] = &Spec{
+		Flags: []*Flag{{Names: []string{"-a"}}},
+		Rest:  &Arg{Type: ValueExecutable},
+	}
+	builtinSpecs["set"] = &Spec{
+		Flags: []*Flag{{
+			Names: []string{"-o", "+o"},
+			Value: &Arg{Type: ValueFunc, Func: listUpOptionNames},
+		}},
+		Rest: &Arg{Type: ValueEnv},
+	}
+	builtinSpecs["bindkey"] = &Spec{
+		Args: []*Arg{
+			{Type: ValueFunc, Func: listUpKeyNames},
+			{Type: ValueFunc, Func: listUpFuncNames},
+		},
+		Rest: &Arg{Type: ValueNone},
+	}
+}
+
+// listUpSpec returns the candidates by the specification for the command
+// at pos. It returns false when it is not found.
+func listUpSpec(ctx context.Context, pos *position, prefix, word string) ([]Element, bool, error) {
+	spec := GetSpec(pos.name)
+	if spec == nil {
+		spec = builtinSpecs[specKey(pos.name)]
+		if spec == nil {
+			return nil, false, nil
+		}
+	}
+	return spec.complete(ctx, pos.args, prefix, word)
+}
@@ -0,0 +1,119 @@
+package completion
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		left string
+		pos  position
+	}{
+		{"", position{top: true, redirect: -1}},
+		{"gi", position{top: true, redirect: -1}},
+		{"git ", position{redirect: -1, name: "git", args: []string{}}},
+		{"git commit -m", position{redirect: -1, name: "git", args: []string{"commit"}}},
+		{"git commit -m ", position{redirect: -1, name: "git", args: []string{"commit", "-m"}}},
+
+		// the target of the redirection
+		{"echo a > ", position{redirect: 9}},
+		{"echo a > fo", position{redirect: 9}},
+		{"echo a >fo", position{redirect: 8}},
+		{"echo a 2> ", position{redirect: 10}},
+		{"echo a 2> fo", position{redirect: 10}},
+		{"echo a >> fo", position{redirect: 10}},
+		{"echo a > fo ", position{redirect: -1, name: "echo", args: []string{"a"}}},
+
+		// the first word after the operators
+		{"ls | ", position{top: true, redirect: -1}},
+		{"ls | so", position{top: true, redirect: -1}},
+		{"ls > a | so", position{top: true, redirect: -1}},
+		{"make && ", position{top: true, redirect: -1}},
+		{"make && gi", position{top: true, redirect: -1}},
+		{"make && git a", position{redirect: -1, name: "git", args: []string{}}},
+		{"ls | sort -r ", position{redirect: -1, name: "sort", args: []string{"-r"}}},
+		{"a ; b", position{top: true, redirect: -1}},
+	}
+	for _, test := range tests {
+		pos := parsePosition(test.left)
+		if !reflect.DeepEqual(*pos, test.pos) {
+			t.Errorf("parsePosition(%q)=%+v but %+v", test.left, *pos, test.pos)
+		}
+	}
+}
+
+// specTexts returns the candidates of the built-in specifications for
+// the word at the end of left.
+func specTexts(t *testing.T, left, word string) ([]string, bool) {
+	t.Helper()
+	pos := parsePosition(left)
+	if pos.top || pos.redirect >= 0 {
+		t.Fatalf("parsePosition(%q)=%+v", left, *pos)
+	}
+	list, ok, err := listUpSpec(context.Background(), pos, "", word)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := make([]string, len(list))
+	for i, e := range list {
+		texts[i] = e.String()
+	}
+	return texts, ok
+}
+
+func TestBuiltinSpecs(t *testing.T) {
+	save := OptionNames
+	defer func() { OptionNames = save }()
+	OptionNames = func() []string { return []string{"noclobber", "pipefail"} }
+
+	if texts, ok := specTexts(t, "set -o ", ""); !ok || !reflect.DeepEqual(texts, []string{"noclobber", "pipefail"}) {
+		t.Errorf("set -o: %v,%v", texts, ok)
+	}
+	if texts, ok := specTexts(t, "set +o p", "p"); !ok || !reflect.DeepEqual(texts, []string{"pipefail"}) {
+		t.Errorf("set +o: %v,%v", texts, ok)
+	}
+	if texts, ok := specTexts(t, "which -", "-"); !ok || !reflect.DeepEqual(texts, []string{"-a"}) {
+		t.Errorf("which -: %v,%v", texts, ok)
+	}
+	if texts, ok := specTexts(t, "bindkey C_", "C_"); !ok || !contains(texts, "C_A") {
+		t.Errorf("bindkey C_: %v,%v", texts, ok)
+	}
+	if texts, ok := specTexts(t, "bindkey C_A BACKWARD_C", "BACKWARD_C"); !ok || !reflect.DeepEqual(texts, []string{"BACKWARD_CHAR"}) {
+		t.Errorf("bindkey C_A: %v,%v", texts, ok)
+	}
+	if texts, ok := specTexts(t, "bindkey C_A BACKWARD_CHAR ", ""); !ok || len(texts) != 0 {
+		t.Errorf("bindkey C_A BACKWARD_CHAR: %v,%v", texts, ok)
+	}
+
+	// only the directories for cd
+	dir, err := ioutil.TempDir("", "completion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	word := dir + string(os.PathSeparator)
+	texts, ok := specTexts(t, "cd "+word, word)
+	if !ok || len(texts) != 1 || filepath.Base(filepath.Clean(texts[0])) != "subdir" {
+		t.Errorf("cd: %v,%v", texts, ok)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, s1 := range list {
+		if s1 == s {
+			return true
+		}
+	}
+	return false
+}
@@ -4,10 +4,9 @@ import (
 	"context"
 	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 
-	"github.com/zetamatta/go-findfile"
-
 	"github.com/zetamatta/nyagos/dos"
 )
 
@@ -30,19 +29,21 @@ func listUpFiles(ctx context.Context, str string) ([]Element, error) {
 	}
 	str = strings.Replace(strings.Replace(str, OPT_SLASH, STD_SLASH, -1), `"`, "", -1)
 	directory := DirName(str)
-	wildcard := dos.Join(findfile.ExpandEnv(directory), "*")
+	wildcard := dos.Join(dos.ExpandEnv(directory), "*")
 
 	// Drive letter
 	cutprefix := 0
 	if strings.HasPrefix(directory, STD_SLASH) {
 		wd, _ := os.Getwd()
-		directory = wd[0:2] + directory
-		cutprefix = 2
+		if volume := filepath.VolumeName(wd); volume != "" {
+			directory = volume + directory
+			cutprefix = len(volume)
+		}
 	}
 	commons := make([]Element, 0)
 	STR := strings.ToUpper(str)
 	canceled := false
-	fdErr := findfile.Walk(wildcard, func(fd *findfile.FileInfo) bool {
+	fdErr := dos.Walk(wildcard, func(fd *dos.FileInfo) bool {
 		if ctx != nil {
 			select {
 			case <-ctx.Done():
@@ -64,15 +65,18 @@ func listUpFiles(ctx context.Context, str string) ([]Element, error) {
 			listname += OPT_SLASH
 		}
 		if cutprefix > 0 {
-			name = name[2:]
+			name = name[cutprefix:]
 		}
 		nameUpr := strings.ToUpper(name)
 		if strings.HasPrefix(nameUpr, STR) {
 			if orgSlash != STD_SLASH[0] {
 				name = strings.Replace(name, STD_SLASH, OPT_SLASH, -1)
 			}
-			element := Element2{name, listname}
-			commons = append(commons, element)
+			kind := KindFile
+			if fd.IsDir() {
+				kind = KindDirectory
+			}
+			commons = append(commons, Candidate{Text: name, Shown: listname, Kind: kind})
 		}
 		return true
 	})
@@ -0,0 +1,45 @@
+package completion
+
+import (
+	"context"
+	"strings"
+
+	"github.com/zetamatta/nyagos/readline"
+)
+
+// KeyFuncCompletionFuzzy selects the completion candidates with
+// readline.FuzzyFinder. The candidates selected with Tab are inserted
+// separated with spaces.
+func KeyFuncCompletionFuzzy(ctx context.Context, this *readline.Buffer) readline.Result {
+	comp, defaultDelimiter, _ := listUpComplete(ctx, this)
+	if comp == nil || len(comp.List) <= 0 {
+		return readline.CONTINUE
+	}
+	finder := &readline.FuzzyFinder{
+		Candidates: toDisplay(comp.List),
+		Multi:      true,
+	}
+	indexes, err := finder.Run(this.Writer)
+	if err != nil || len(indexes) <= 0 {
+		return readline.CONTINUE
+	}
+	var buffer strings.Builder
+	for i, index := range indexes {
+		if i > 0 {
+			buffer.WriteByte(' ')
+		}
+		word := comp.List[index].String()
+		if strings.ContainsAny(word, " &!") {
+			buffer.WriteRune(defaultDelimiter)
+			buffer.WriteString(word)
+			buffer.WriteRune(defaultDelimiter)
+		} else {
+			buffer.WriteString(word)
+		}
+	}
+	if len(indexes) > 1 || !endWithRoot(buffer.String()) {
+		buffer.WriteByte(' ')
+	}
+	this.ReplaceAndRepaint(comp.Pos, buffer.String())
+	return readline.CONTINUE
+}
@@ -24,4 +24,6 @@ func init() {
 	if err != nil {
 		panic(err.Error())
 	}
+	readline.NAME2FUNC["COMPLETE_FUZZY"] = KeyFuncCompletionFuzzy
+	readline.NAME2FUNC["COMPLETE_MENU"] = KeyFuncCompletionMenu
 }
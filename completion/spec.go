@@ -0,0 +1,343 @@
+package completion
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ValueType is the kind of the candidates for an argument or a flag value.
+type ValueType int
+
+const (
+	ValueNone       ValueType = iota // no candidates
+	ValueFile                        // file and directory names
+	ValueDirectory                   // directory names
+	ValueExecutable                  // command names
+	ValueEnv                         // environment variable names
+	ValueList                        // one of Arg.Values
+	ValueFunc                        // the result of Arg.Func
+)
+
+var valueTypeNames = map[ValueType]string{
+	ValueNone:       "none",
+	ValueFile:       "file",
+	ValueDirectory:  "directory",
+	ValueExecutable: "executable",
+	ValueEnv:        "env",
+}
+
+var valueTypeAliases = map[string]ValueType{
+	"dir":     ValueDirectory,
+	"command": ValueExecutable,
+}
+
+// Arg is the value expected for a positional argument or a flag.
+type Arg struct {
+	Type   ValueType
+	Values []string // for ValueList
+	// Func returns the candidates for ValueFunc. args are the words
+	// typed before word, without the command name.
+	Func func(ctx context.Context, word string, args []string) []string
+}
+
+// ParseArg returns Arg from the name of ValueType such as `file`,
+// `directory`, `executable`, `env` or `none`. The other strings are
+// the lists separated with commas.
+func ParseArg(s string) *Arg {
+	for t, name := range valueTypeNames {
+		if strings.EqualFold(s, name) {
+			return &Arg{Type: t}
+		}
+	}
+	if t, ok := valueTypeAliases[strings.ToLower(s)]; ok {
+		return &Arg{Type: t}
+	}
+	return &Arg{Type: ValueList, Values: strings.Split(s, ",")}
+}
+
+func (a *Arg) String() string {
+	switch a.Type {
+	case ValueList:
+		return strings.Join(a.Values, ",")
+	case ValueFunc:
+		return "(function)"
+	}
+	return valueTypeNames[a.Type]
+}
+
+// Flag is an option such as `-v` or `--output=FILE`.
+type Flag struct {
+	Names       []string // the aliases such as `-o` and `--output`
+	Value       *Arg     // nil when the flag takes no value
+	Description string
+}
+
+// Spec is the specification of the arguments of a command.
+type Spec struct {
+	Description string
+	Subcommands map[string]*Spec
+	Flags       []*Flag
+	Args        []*Arg // the positional arguments
+	Rest        *Arg   // the arguments after Args. nil means files.
+}
+
+// Flag returns the flag named name, or nil.
+func (spec *Spec) Flag(name string) *Flag {
+	for _, f := range spec.Flags {
+		for _, n := range f.Names {
+			if n == name {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// Subcommand returns the specification of the subcommand named name.
+// It is created when create is true and it does not exist.
+func (spec *Spec) Subcommand(name string, create bool) *Spec {
+	if sub, ok := spec.Subcommands[name]; ok || !create {
+		return sub
+	}
+	if spec.Subcommands == nil {
+		spec.Subcommands = map[string]*Spec{}
+	}
+	sub := &Spec{}
+	spec.Subcommands[name] = sub
+	return sub
+}
+
+var specs = map[string]*Spec{}
+
+func specKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// SetSpec registers the specification for the command name.
+// nil removes it.
+func SetSpec(name string, spec *Spec) {
+	if spec == nil {
+		delete(specs, specKey(name))
+	} else {
+		specs[specKey(name)] = spec
+	}
+}
+
+// GetSpec returns the specification for the command. The directory and
+// the extension of name are ignored when it is not registered as it is.
+func GetSpec(name string) *Spec {
+	if spec, ok := specs[specKey(name)]; ok {
+		return spec
+	}
+	name = filepath.Base(name)
+	if spec, ok := specs[specKey(name)]; ok {
+		return spec
+	}
+	spec, ok := specs[specKey(strings.TrimSuffix(name, filepath.Ext(name)))]
+	if !ok {
+		return nil
+	}
+	return spec
+}
+
+// SpecNames returns the names of the commands which have specifications.
+func SpecNames() []string {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteSpec writes the `complete` commands to make spec again.
+// path is the command name and its subcommands.
+func WriteSpec(w io.Writer, path string, spec *Spec) {
+	var line strings.Builder
+	line.WriteString("complete " + path)
+	if spec.Description != "" {
+		fmt.Fprintf(&line, " -d %s", quoteSpec(spec.Description))
+	}
+	for _, f := range spec.Flags {
+		flag := strings.Join(f.Names, ",")
+		if f.Value != nil {
+			flag += "=" + f.Value.String()
+		}
+		fmt.Fprintf(&line, " -f %s", quoteSpec(flag))
+	}
+	for _, a := range spec.Args {
+		fmt.Fprintf(&line, " -a %s", quoteSpec(a.String()))
+	}
+	if spec.Rest != nil {
+		fmt.Fprintf(&line, " -A %s", quoteSpec(spec.Rest.String()))
+	}
+	names := make([]string, 0, len(spec.Subcommands))
+	for name := range spec.Subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&line, " -s %s", quoteSpec(name))
+	}
+	fmt.Fprintln(w, line.String())
+	for _, name := range names {
+		sub := spec.Subcommands[name]
+		if sub.Description != "" || len(sub.Flags) > 0 || len(sub.Args) > 0 ||
+			sub.Rest != nil || len(sub.Subcommands) > 0 {
+			WriteSpec(w, path+" "+name, sub)
+		}
+	}
+}
+
+func quoteSpec(s string) string {
+	if strings.ContainsAny(s, " \t&|<>;()") {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+// candidates returns the candidates of arg for word.
+func (arg *Arg) candidates(ctx context.Context, word string, args []string) ([]Element, error) {
+	switch arg.Type {
+	case ValueNone:
+		return []Element{}, nil
+	case ValueFile:
+		return listUpFiles(ctx, word)
+	case ValueDirectory:
+		files, err := listUpFiles(ctx, word)
+		dirs := make([]Element, 0, len(files))
+		for _, f := range files {
+			if endWithRoot(f.String()) {
+				dirs = append(dirs, f)
+			}
+		}
+		return dirs, err
+	case ValueExecutable:
+		if isSuggesting(ctx) {
+			return []Element{}, nil
+		}
+		return listUpCommands(ctx, word)
+	case ValueEnv:
+		var names []string
+		for _, vars := range PercentVariables {
+			vars.EachKey(func(name string) {
+				names = append(names, name)
+			})
+		}
+		return filterByPrefix(names, word), nil
+	case ValueList:
+		return filterByPrefix(arg.Values, word), nil
+	case ValueFunc:
+		if arg.Func == nil || isSuggesting(ctx) {
+			return []Element{}, nil
+		}
+		return filterByPrefix(arg.Func(ctx, word, args), word), nil
+	}
+	return nil, nil
+}
+
+func filterByPrefix(list []string, word string) []Element {
+	wordUpr := strings.ToUpper(word)
+	result := make([]Element, 0, len(list))
+	for _, s := range list {
+		if strings.HasPrefix(strings.ToUpper(s), wordUpr) {
+			result = append(result, Element1(s))
+		}
+	}
+	return result
+}
+
+func filterCandidates(list []Candidate, word string) []Element {
+	wordUpr := strings.ToUpper(word)
+	result := make([]Element, 0, len(list))
+	for _, c := range list {
+		if strings.HasPrefix(strings.ToUpper(c.Text), wordUpr) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// complete returns the candidates for word typed after args. prefix is
+// the part of the word before `=` such as `--output=`. It returns false
+// when spec has nothing for the position.
+func (spec *Spec) complete(ctx context.Context, args []string, prefix, word string) ([]Element, bool, error) {
+	current := spec
+	positional := 0
+	var value *Arg
+	options := true
+	for _, a := range args {
+		if value != nil {
+			value = nil
+			continue
+		}
+		if options && a == "--" {
+			options = false
+			continue
+		}
+		if options && len(a) > 1 && (a[0] == '-' || current.Flag(a) != nil) {
+			if f := current.Flag(a); f != nil && f.Value != nil {
+				value = f.Value
+			}
+			continue
+		}
+		if positional == 0 {
+			if sub, ok := current.Subcommands[a]; ok {
+				current = sub
+				continue
+			}
+		}
+		positional++
+	}
+	if value != nil {
+		list, err := value.candidates(ctx, word, args)
+		return list, true, err
+	}
+	if prefix != "" {
+		if !options || !strings.HasPrefix(prefix, "-") || !strings.HasSuffix(prefix, "=") {
+			return nil, false, nil
+		}
+		if f := current.Flag(prefix[:len(prefix)-1]); f != nil && f.Value != nil {
+			list, err := f.Value.candidates(ctx, word, args)
+			return list, true, err
+		}
+		return nil, false, nil
+	}
+	if options && prefix == "" && strings.HasPrefix(word, "-") {
+		var flags []Candidate
+		for _, f := range current.Flags {
+			for _, name := range f.Names {
+				if f.Value != nil && strings.HasPrefix(name, "--") {
+					name += "="
+				}
+				flags = append(flags, Candidate{Text: name, Description: f.Description})
+			}
+		}
+		return filterCandidates(flags, word), true, nil
+	}
+	if positional == 0 && len(current.Subcommands) > 0 {
+		names := make([]string, 0, len(current.Subcommands))
+		for name := range current.Subcommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		subs := make([]Candidate, len(names))
+		for i, name := range names {
+			subs[i] = Candidate{Text: name, Description: current.Subcommands[name].Description}
+		}
+		return filterCandidates(subs, word), true, nil
+	}
+	arg := current.Rest
+	if positional < len(current.Args) {
+		arg = current.Args[positional]
+	}
+	if arg == nil {
+		return nil, false, nil
+	}
+	list, err := arg.candidates(ctx, word, args)
+	return list, true, err
+}
@@ -0,0 +1,54 @@
+package completion
+
+import (
+	"context"
+
+	"github.com/zetamatta/nyagos/readline"
+)
+
+var nextSuggestHook readline.SuggestHookT
+
+type suggestingT struct{}
+
+// suggesting is the key of the context to tell that the candidates are
+// listed for the suggestion. The slow sources, the executables, the
+// functions of the specifications and HookToList, are skipped then.
+var suggesting suggestingT
+
+func isSuggesting(ctx context.Context) bool {
+	return ctx != nil && ctx.Value(suggesting) != nil
+}
+
+// the last suggestion by the completion, which is reused while the line
+// is not changed, for example, when the cursor is moved.
+var (
+	lastSuggestBuffer *readline.Buffer
+	lastSuggestText   string
+	lastSuggestLine   string
+)
+
+// suggestByCompletion suggests the completion of the last word when the
+// previous hook (the history) suggests nothing. The command names are not
+// suggested since it takes time to find them on every key.
+func suggestByCompletion(ctx context.Context, this *readline.Buffer, text string) string {
+	if line := nextSuggestHook(ctx, this, text); line != "" {
+		return line
+	}
+	if text == "" || parsePosition(text).top {
+		return ""
+	}
+	if this == lastSuggestBuffer && text == lastSuggestText {
+		return lastSuggestLine
+	}
+	line := ""
+	comp, default_delimiter, err := listUpComplete(context.WithValue(ctx, suggesting, true), this)
+	if err == nil && comp != nil && len(comp.List) > 0 && comp.Word != "" {
+		line = string([]rune(text)[:comp.Pos]) + comp.commonText(default_delimiter)
+	}
+	lastSuggestBuffer, lastSuggestText, lastSuggestLine = this, text, line
+	return line
+}
+
+func init() {
+	nextSuggestHook = readline.SetSuggestHook(suggestByCompletion)
+}
@@ -0,0 +1,81 @@
+package completion
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zetamatta/nyagos/readline"
+)
+
+func newTestBuffer(text string) *readline.Buffer {
+	this := &readline.Buffer{
+		Editor: &readline.Editor{
+			History: new(readline.EmptyHistory),
+			Writer:  bufio.NewWriter(ioutil.Discard),
+		},
+		Buffer:    make([]rune, 20),
+		TermWidth: 80,
+	}
+	this.InsertString(0, text)
+	this.Cursor = this.Length
+	return this
+}
+
+func TestSuggestByCompletion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "suggest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "foobar.txt")
+	if err := ioutil.WriteFile(path, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// the hooks by Lua are not called for the suggestion.
+	called := 0
+	defer func(hooks []func(context.Context, *readline.Buffer, *List) (*List, error)) {
+		HookToList = hooks
+	}(HookToList)
+	HookToList = append(HookToList[:0:0], func(ctx context.Context, this *readline.Buffer, list *List) (*List, error) {
+		called++
+		return list, nil
+	})
+
+	ctx := context.Background()
+	text := "type " + filepath.Join(dir, "foo")
+	this := newTestBuffer(text)
+	if line := suggestByCompletion(ctx, this, text); line != "type "+path+" " {
+		t.Fatalf("suggestByCompletion(%q)=%q", text, line)
+	}
+	if called != 0 {
+		t.Fatalf("HookToList is called %d times", called)
+	}
+
+	// the suggestion for the same line is not listed again.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if line := suggestByCompletion(ctx, this, text); line != "type "+path+" " {
+		t.Fatalf("the second suggestByCompletion(%q)=%q", text, line)
+	}
+	if line := suggestByCompletion(ctx, newTestBuffer(text), text); line != "" {
+		t.Fatalf("suggestByCompletion(%q) after removed=%q", text, line)
+	}
+
+	// the command names are not searched.
+	for _, text := range []string{"l", "which l"} {
+		if line := suggestByCompletion(ctx, newTestBuffer(text), text); line != "" {
+			t.Errorf("suggestByCompletion(%q)=%q", text, line)
+		}
+	}
+
+	// the key completion lists them all as before.
+	if _, _, err := listUpComplete(ctx, newTestBuffer(text)); err != nil || called != 1 {
+		t.Fatalf("listUpComplete: %d,%v", called, err)
+	}
+}
@@ -0,0 +1,16 @@
+package dos
+
+import (
+	"errors"
+	"os"
+)
+
+// Chdrive is not supported because there are no drive letters on POSIX.
+func Chdrive(drive string) error {
+	return errors.New("Chdrive: drive letters are not supported")
+}
+
+// Chdir changes the current working directory.
+func Chdir(folder string) error {
+	return os.Chdir(folder)
+}
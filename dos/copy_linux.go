@@ -0,0 +1,50 @@
+package dos
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// Copy copies the file src to dst keeping its permission and timestamp.
+func Copy(src, dst string, isFailIfExists bool) error {
+	srcFd, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFd.Close()
+
+	stat, err := srcFd.Stat()
+	if err != nil {
+		return err
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if isFailIfExists {
+		flag |= os.O_EXCL
+	}
+	dstFd, err := os.OpenFile(dst, flag, stat.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dstFd, srcFd); err != nil {
+		dstFd.Close()
+		return err
+	}
+	if err := dstFd.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, stat.ModTime(), stat.ModTime())
+}
+
+// Move renames src to dst. When they are on the different filesystems,
+// it copies src to dst and removes src.
+func Move(src, dst string) error {
+	err := os.Rename(src, dst)
+	if linkErr, ok := err.(*os.LinkError); !ok || linkErr.Err != syscall.EXDEV {
+		return err
+	}
+	if err := Copy(src, dst, false); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
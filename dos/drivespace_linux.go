@@ -0,0 +1,19 @@
+package dos
+
+import (
+	"syscall"
+)
+
+// GetDiskFreeSpace retunrs disk information.
+//   rootPathName - the path on the filesystem like "/"
+func GetDiskFreeSpace(rootPathName string) (free uint64, total uint64, totalFree uint64, err error) {
+	var stat syscall.Statfs_t
+	if err = syscall.Statfs(rootPathName, &stat); err != nil {
+		return
+	}
+	bsize := uint64(stat.Bsize)
+	free = stat.Bavail * bsize
+	total = stat.Blocks * bsize
+	totalFree = stat.Bfree * bsize
+	return
+}
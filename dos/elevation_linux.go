@@ -0,0 +1,10 @@
+package dos
+
+import (
+	"os"
+)
+
+// IsElevated returns true if the current process runs as root
+func IsElevated() (bool, error) {
+	return os.Geteuid() == 0, nil
+}
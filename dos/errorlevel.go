@@ -12,6 +12,9 @@ func getErrorLevel(processState *os.ProcessState) (int, bool) {
 	} else if processState.Success() {
 		return 0, true
 	} else if t, ok := processState.Sys().(syscall.WaitStatus); ok {
+		if t.Signaled() {
+			return 128 + int(t.Signal()), true
+		}
 		return t.ExitStatus(), true
 	}
 	return 255, false
@@ -0,0 +1,50 @@
+package dos
+
+import (
+	"os"
+	"strings"
+)
+
+// GetFileInfoAttributes returns the attributes emulated from stat.
+// Dot-files are hidden and symbolic links are reparse points.
+func GetFileInfoAttributes(stat os.FileInfo) uint32 {
+	var attr uint32
+	if stat.Mode()&os.ModeSymlink != 0 {
+		attr |= FILE_ATTRIBUTE_REPARSE_POINT
+	}
+	if stat.Mode().Perm()&0222 == 0 {
+		attr |= FILE_ATTRIBUTE_READONLY
+	}
+	if base := stat.Name(); strings.HasPrefix(base, ".") && base != "." && base != ".." {
+		attr |= FILE_ATTRIBUTE_HIDDEN
+	}
+	if attr == 0 {
+		attr = FILE_ATTRIBUTE_NORMAL
+	}
+	return attr
+}
+
+// GetFileAttributes emulates Win32-API's GetFileAttributes.
+func GetFileAttributes(path string) (uint32, error) {
+	stat, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	return GetFileInfoAttributes(stat), nil
+}
+
+// SetFileAttributes emulates Win32-API's SetFileAttributes.
+// Only FILE_ATTRIBUTE_READONLY is reflected to the permission bits.
+func SetFileAttributes(path string, attr uint32) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	perm := stat.Mode().Perm()
+	if (attr & FILE_ATTRIBUTE_READONLY) != 0 {
+		perm &^= 0222
+	} else if perm&0200 == 0 {
+		perm |= 0200
+	}
+	return os.Chmod(path, perm)
+}
@@ -0,0 +1,78 @@
+package dos
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileInfo is the information of the file found by Walk.
+type FileInfo struct {
+	os.FileInfo
+}
+
+// IsHidden returns true if the file is a dot-file.
+func (f *FileInfo) IsHidden() bool {
+	return (GetFileInfoAttributes(f.FileInfo) & FILE_ATTRIBUTE_HIDDEN) != 0
+}
+
+// IsReparsePoint returns true if the file is a symbolic link.
+func (f *FileInfo) IsReparsePoint() bool {
+	return (f.Mode() & os.ModeSymlink) != 0
+}
+
+// Walk calls callback for each file matching pattern
+// until callback returns false.
+func Walk(pattern string, callback func(*FileInfo) bool) error {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	if len(matches) <= 0 {
+		return &os.PathError{Op: "Walk", Path: pattern, Err: os.ErrNotExist}
+	}
+	for _, path := range matches {
+		stat, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+		if !callback(&FileInfo{FileInfo: stat}) {
+			break
+		}
+	}
+	return nil
+}
+
+// Glob returns the filenames matching pattern.
+func Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+// Globs expands the wildcards in args.
+// Arguments not matching any file are kept as they are.
+func Globs(args []string) []string {
+	result := make([]string, 0, len(args))
+	for _, arg1 := range args {
+		if strings.ContainsAny(arg1, "*?") {
+			if matches, err := filepath.Glob(arg1); err == nil && len(matches) > 0 {
+				result = append(result, matches...)
+				continue
+			}
+		}
+		result = append(result, arg1)
+	}
+	return result
+}
+
+var rxEnv = regexp.MustCompile(`%[^%]+%`)
+
+// ExpandEnv expands %VAR% in s.
+func ExpandEnv(s string) string {
+	return rxEnv.ReplaceAllStringFunc(s, func(m string) string {
+		if value, ok := os.LookupEnv(m[1 : len(m)-1]); ok {
+			return value
+		}
+		return m
+	})
+}
@@ -0,0 +1,36 @@
+package dos
+
+import (
+	"os"
+
+	"github.com/zetamatta/go-findfile"
+)
+
+// FileInfo is the information of the file found by Walk.
+type FileInfo = findfile.FileInfo
+
+// Walk calls callback for each file matching pattern
+// until callback returns false.
+func Walk(pattern string, callback func(*FileInfo) bool) error {
+	return findfile.Walk(pattern, callback)
+}
+
+// Glob returns the filenames matching pattern.
+func Glob(pattern string) ([]string, error) {
+	return findfile.Glob(pattern)
+}
+
+// Globs expands the wildcards in args.
+func Globs(args []string) []string {
+	return findfile.Globs(args)
+}
+
+// ExpandEnv expands %VAR% in s.
+func ExpandEnv(s string) string {
+	return findfile.ExpandEnv(s)
+}
+
+// GetFileInfoAttributes returns the attributes of stat.
+func GetFileInfoAttributes(stat os.FileInfo) uint32 {
+	return findfile.GetFileAttributes(stat)
+}
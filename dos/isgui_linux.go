@@ -0,0 +1,7 @@
+package dos
+
+// IsGui always returns false because POSIX has no distinction
+// between GUI and console executables.
+func IsGui(fname string) bool {
+	return false
+}
@@ -0,0 +1,25 @@
+package dos
+
+import (
+	"strings"
+)
+
+func joinPath2(a, b string) string {
+	if len(a) <= 0 || strings.HasPrefix(b, "/") {
+		return b
+	}
+	if a[len(a)-1] == '/' {
+		return a + b
+	}
+	return a + "/" + b
+}
+
+// Join joins paths. When an element is absolute, the preceding ones are dropped.
+// Do not clean path (keep `./` on arguments)
+func Join(paths ...string) string {
+	result := paths[len(paths)-1]
+	for i := len(paths) - 2; i >= 0; i-- {
+		result = joinPath2(paths[i], result)
+	}
+	return result
+}
@@ -0,0 +1,20 @@
+package dos
+
+import (
+	"testing"
+)
+
+func TestJoinLinux(t *testing.T) {
+	cases := [][3]string{
+		{`foo`, `bar`, `foo/bar`},
+		{`foo/`, `bar`, `foo/bar`},
+		{`foo`, `/bar`, `/bar`},
+		{`./foo`, `bar`, `./foo/bar`},
+		{``, `bar`, `bar`},
+	}
+	for _, c := range cases {
+		if result := Join(c[0], c[1]); result != c[2] {
+			t.Errorf("Join(%q,%q)=%q (expected %q)", c[0], c[1], result, c[2])
+		}
+	}
+}
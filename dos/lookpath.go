@@ -4,50 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-
-	"github.com/zetamatta/go-findfile"
-
-	"github.com/zetamatta/nyagos/defined"
 )
 
-func lookPath(dir1, patternBase string) (foundpath string) {
-	pattern := patternBase + ".*"
-	pathExtList := filepath.SplitList(os.Getenv("PATHEXT"))
-	names := make([]string, len(pathExtList)+1)
-	basename := filepath.Base(patternBase)
-	names[0] = basename
-	for i, ext1 := range pathExtList {
-		names[i+1] = basename + ext1
-	}
-	findfile.Walk(pattern, func(f *findfile.FileInfo) bool {
-		if f.IsDir() {
-			return true
-		}
-		for _, name1 := range names {
-			if strings.EqualFold(f.Name(), name1) {
-				foundpath = filepath.Join(dir1, f.Name())
-				if !f.IsReparsePoint() {
-					return false
-				}
-				var err error
-				linkTo, err := os.Readlink(foundpath)
-				if err == nil && linkTo != "" {
-					foundpath = linkTo
-					if filepath.IsAbs(foundpath) {
-						return false
-					}
-					foundpath = filepath.Join(dir1, foundpath)
-					return false
-				} else if defined.DBG {
-					print(err.Error(), "\n")
-				}
-			}
-		}
-		return true
-	})
-	return
-}
-
 // LookCurdirT is the type for constant meaning the current directory should be looked.
 type LookCurdirT int
 
@@ -0,0 +1,13 @@
+package dos
+
+import (
+	"os"
+)
+
+func lookPath(dir1, patternBase string) (foundpath string) {
+	stat, err := os.Stat(patternBase)
+	if err != nil || stat.IsDir() || stat.Mode().Perm()&0111 == 0 {
+		return ""
+	}
+	return patternBase
+}
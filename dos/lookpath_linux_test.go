@@ -0,0 +1,52 @@
+package dos
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookPathLinux(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nyagos-lookpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	exe := filepath.Join(dir, "hello")
+	if err := ioutil.WriteFile(exe, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	text := filepath.Join(dir, "readme")
+	if err := ioutil.WriteFile(text, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	orgPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", orgPath)
+	os.Setenv("PATH", dir)
+
+	if result := LookPath(LookCurdirNever, "hello"); result != exe {
+		t.Errorf("LookPath(hello)=%q (expected %q)", result, exe)
+	}
+	if result := LookPath(LookCurdirNever, "readme"); result != "" {
+		t.Errorf("LookPath(readme)=%q (expected \"\")", result)
+	}
+	if result := LookPath(LookCurdirNever, "hello.exe"); result != "" {
+		t.Errorf("LookPath(hello.exe)=%q (expected \"\")", result)
+	}
+}
+
+func TestGetErrorLevelLinux(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "exit 3")
+	cmd.Run()
+	if errorlevel, ok := GetErrorLevel(cmd); !ok || errorlevel != 3 {
+		t.Errorf("GetErrorLevel()=%d,%v (expected 3,true)", errorlevel, ok)
+	}
+	cmd = exec.Command("/bin/sh", "-c", "kill -TERM $$")
+	cmd.Run()
+	if errorlevel, ok := GetErrorLevel(cmd); !ok || errorlevel != 128+15 {
+		t.Errorf("GetErrorLevel()=%d,%v (expected 143,true)", errorlevel, ok)
+	}
+}
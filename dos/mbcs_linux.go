@@ -0,0 +1,11 @@
+package dos
+
+// AtoU returns ansi as it is because the locale is expected to be UTF8.
+func AtoU(ansi []byte) (string, error) {
+	return string(ansi), nil
+}
+
+// UtoA returns utf8 as it is because the locale is expected to be UTF8.
+func UtoA(utf8 string) ([]byte, error) {
+	return []byte(utf8), nil
+}
@@ -0,0 +1,15 @@
+package dos
+
+import (
+	"github.com/zetamatta/go-mbcs"
+)
+
+// AtoU converts the string in the current codepage to UTF8.
+func AtoU(ansi []byte) (string, error) {
+	return mbcs.AtoU(ansi)
+}
+
+// UtoA converts the UTF8 string to the current codepage.
+func UtoA(utf8 string) ([]byte, error) {
+	return mbcs.UtoA(utf8)
+}
@@ -0,0 +1,22 @@
+package dos
+
+import (
+	"errors"
+)
+
+var errNetDriveNotSupported = errors.New("network drives are not supported")
+
+// WNetGetConnection is not supported on POSIX.
+func WNetGetConnection(localName string) (string, error) {
+	return "", errNetDriveNotSupported
+}
+
+// NetDriveToUNC returns path as it is.
+func NetDriveToUNC(path string) string {
+	return path
+}
+
+// WNetEnum is not supported on POSIX.
+func WNetEnum(handler func(localName string, remoteName string)) error {
+	return errNetDriveNotSupported
+}
@@ -0,0 +1,25 @@
+package dos
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ShellExecute starts path in background. Executables run directly
+// (RUNAS does not elevate) and other files are opened by xdg-open.
+func ShellExecute(action string, path string, param string, directory string) error {
+	var cmd *exec.Cmd
+	if stat, err := os.Stat(path); err == nil && !stat.IsDir() && stat.Mode().Perm()&0111 != 0 {
+		cmd = exec.Command(path, strings.Fields(param)...)
+	} else {
+		cmd = exec.Command("xdg-open", path)
+	}
+	cmd.Dir = directory
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
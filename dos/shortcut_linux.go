@@ -0,0 +1,17 @@
+package dos
+
+import (
+	"errors"
+)
+
+var errShortcutNotSupported = errors.New("shortcut files are not supported")
+
+// ReadShortcut is not supported on POSIX.
+func ReadShortcut(path string) (string, string, error) {
+	return "", "", errShortcutNotSupported
+}
+
+// MakeShortcut is not supported on POSIX.
+func MakeShortcut(from, to, dir string) error {
+	return errShortcutNotSupported
+}
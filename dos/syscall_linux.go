@@ -0,0 +1,7 @@
+package dos
+
+// CoInitializeEx does nothing because there is no COM on POSIX.
+func CoInitializeEx(res uintptr, opt uintptr) {}
+
+// CoUninitialize does nothing because there is no COM on POSIX.
+func CoUninitialize() {}
@@ -0,0 +1,13 @@
+package dos
+
+// EnableStdoutVirtualTerminalProcessing does nothing because terminals
+// on POSIX support ESCAPE SEQUENCE natively.
+func EnableStdoutVirtualTerminalProcessing() (func(), error) {
+	return func() {}, nil
+}
+
+// EnableStderrVirtualTerminalProcessing does nothing because terminals
+// on POSIX support ESCAPE SEQUENCE natively.
+func EnableStderrVirtualTerminalProcessing() (func(), error) {
+	return func() {}, nil
+}
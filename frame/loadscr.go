@@ -104,7 +104,16 @@ var appdatapath_ string
 
 func AppDataDir() string {
 	if appdatapath_ == "" {
-		appdatapath_ = filepath.Join(os.Getenv("APPDATA"), "NYAOS_ORG")
+		appdata := os.Getenv("APPDATA")
+		if appdata == "" {
+			// not Windows: follow the XDG Base Directory Specification
+			appdata = os.Getenv("XDG_CONFIG_HOME")
+			if appdata == "" {
+				appdata = filepath.Join(dos.GetHome(), ".config")
+			}
+			os.MkdirAll(appdata, 0777)
+		}
+		appdatapath_ = filepath.Join(appdata, "NYAOS_ORG")
 		os.Mkdir(appdatapath_, 0777)
 	}
 	return appdatapath_
@@ -7,14 +7,15 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime/debug"
 
-	"github.com/zetamatta/go-getch"
 	"github.com/zetamatta/nyagos/alias"
 	"github.com/zetamatta/nyagos/commands"
 	"github.com/zetamatta/nyagos/completion"
 	"github.com/zetamatta/nyagos/dos"
 	"github.com/zetamatta/nyagos/history"
+	"github.com/zetamatta/nyagos/readline"
 	"github.com/zetamatta/nyagos/shell"
 )
 
@@ -27,15 +28,23 @@ func Start(mainHandler func() error) error {
 		rc, done, err := commands.Exec(ctx, it)
 		return rc, done, err
 	})
+	commands.InitFunctions()
 	completion.AppendCommandLister(commands.AllNames)
+	completion.AppendCommandLister(commands.FunctionNames)
 	completion.AppendCommandLister(alias.AllNames)
 
 	dos.CoInitializeEx(0, dos.COINIT_MULTITHREADED)
 	defer dos.CoUninitialize()
 
-	getch.DisableCtrlC()
+	readline.DisableCtrlC()
 	alias.Init()
 
+	commands.DirHistoryPath = filepath.Join(AppDataDir(), "nyagos.dirhistory")
+	commands.DirStackPath = filepath.Join(AppDataDir(), "nyagos.dirstack")
+	if err := commands.LoadDirHistory(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+
 	return mainHandler()
 }
 
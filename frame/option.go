@@ -28,6 +28,10 @@ var OptionGoColorable = true
 // then Windows10's ENABLE_VIRTUAL_TERMINAL_PROCESSING is enabled.
 var OptionEnableVirtualTerminalProcessing = false
 
+// ExitCode is the exit code of the process, which is the errorlevel of
+// the command given with -c or -b.
+var ExitCode = 0
+
 type ScriptEngineForOption interface {
 	SetArg([]string)
 	RunFile(context.Context, string) ([]byte, error)
@@ -66,7 +70,7 @@ var optionMap = map[string]optionT{
 				return nil, errors.New("-c: requires parameters")
 			}
 			return func(ctx context.Context) error {
-				p.sh.Interpret(ctx, p.args[0])
+				ExitCode, _ = p.sh.Interpret(ctx, p.args[0])
 				return io.EOF
 			}, nil
 		},
@@ -83,7 +87,7 @@ var optionMap = map[string]optionT{
 			}
 			text := string(data)
 			return func(ctx context.Context) error {
-				p.sh.Interpret(ctx, text)
+				ExitCode, _ = p.sh.Interpret(ctx, text)
 				return io.EOF
 			}, nil
 		},
@@ -309,3 +313,37 @@ func OptionParse(sh *shell.Shell, e ScriptEngineForOption) (func(context.Context
 }
 
 var SilentMode = false
+
+// subshellCommand starts nyagos with the current options to run text
+// for `( ... )`.
+func subshellCommand(text string) ([]string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	args := []string{exe}
+	if OptionNorc {
+		args = append(args, "--norc")
+	}
+	for _, key := range texts.SortedKeys(commands.BoolOptions) {
+		name := strings.Replace(key, "_", "-", -1)
+		if *commands.BoolOptions[key].V {
+			args = append(args, "--"+name)
+		} else {
+			args = append(args, "--no-"+name)
+		}
+	}
+	switch shell.LookCurdirOrder {
+	case dos.LookCurdirFirst:
+		args = append(args, "--look-curdir-first")
+	case dos.LookCurdirLast:
+		args = append(args, "--look-curdir-last")
+	case dos.LookCurdirNever:
+		args = append(args, "--look-curdir-never")
+	}
+	return append(args, "-b", base64.StdEncoding.EncodeToString([]byte(text))), nil
+}
+
+func init() {
+	shell.SubshellCommand = subshellCommand
+}
@@ -7,9 +7,12 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/mattn/go-colorable"
 
+	"github.com/zetamatta/nyagos/commands"
 	"github.com/zetamatta/nyagos/dos"
 	"github.com/zetamatta/nyagos/history"
 	"github.com/zetamatta/nyagos/readline"
@@ -22,6 +25,7 @@ type CmdStreamConsole struct {
 	History  *history.Container
 	Editor   *readline.Editor
 	HistPath string
+	pending  *history.Line // the line executing, whose result is not recorded yet
 }
 
 var console io.Writer
@@ -45,20 +49,40 @@ func NewCmdStreamConsole(doPrompt func() (int, error)) *CmdStreamConsole {
 	this := &CmdStreamConsole{
 		History: history1,
 		Editor: &readline.Editor{
-			History: history1,
-			Prompt:  doPrompt,
-			Writer:  bufio.NewWriter(GetConsole())},
+			History:    history1,
+			Prompt:     doPrompt,
+			Writer:     bufio.NewWriter(GetConsole()),
+			Incomplete: commands.IsIncomplete},
 		HistPath: filepath.Join(AppDataDir(), "nyagos.history"),
 		CmdSeeker: shell.CmdSeeker{
 			PlainHistory: []string{},
 			Pointer:      -1,
 		},
 	}
-	history1.Load(this.HistPath)
-	history1.Save(this.HistPath)
+	if err := history1.Open(this.HistPath); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
 	return this
 }
 
+// Result records the errorlevel and the duration of the line read last
+// into the history.
+func (this *CmdStreamConsole) Result(errorlevel int) {
+	if this.pending == nil {
+		return
+	}
+	row := *this.pending
+	this.pending = nil
+	if row.Text == "" {
+		return
+	}
+	row.ErrorLevel = errorlevel
+	row.Duration = time.Since(row.Stamp)
+	if err := this.History.Finish(row); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+}
+
 func (this *CmdStreamConsole) ReadLine(ctx context.Context) (context.Context, string, error) {
 	if this.Pointer >= 0 {
 		if this.Pointer < len(this.PlainHistory) {
@@ -70,12 +94,28 @@ func (this *CmdStreamConsole) ReadLine(ctx context.Context) (context.Context, st
 	var line string
 	var err error
 	for {
+		shell.NotifyJobs(os.Stderr)
+		if history.ShareHistory {
+			if _, err := this.History.Sync(); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+			}
+		}
 		line, err = this.Editor.ReadLine(ctx)
 		if err != nil {
 			return ctx, line, err
 		}
 		var isReplaced bool
 		line, isReplaced, err = this.History.Replace(line)
+		if err == history.ErrPrintOnly {
+			// `:p` prints the line and adds it to the history only.
+			fmt.Fprintln(os.Stdout, line)
+			if text, ok := this.History.Filter(ctx, line); ok {
+				if err := this.History.Add(history.NewHistoryLine(text)); err != nil {
+					fmt.Fprintln(os.Stderr, err.Error())
+				}
+			}
+			continue
+		}
 		if err != nil {
 			return ctx, line, err
 		}
@@ -86,15 +126,31 @@ func (this *CmdStreamConsole) ReadLine(ctx context.Context) (context.Context, st
 			break
 		}
 	}
-	row := history.NewHistoryLine(line)
-	this.History.PushLine(row)
-	fd, err := os.OpenFile(this.HistPath, os.O_APPEND|os.O_CREATE, 0600)
-	if err == nil {
-		fmt.Fprintln(fd, row.String())
-		fd.Close()
+	if text, ok := this.History.Filter(ctx, line); !ok {
+		if this.pending == nil {
+			// an empty row to skip recording the result of the line
+			this.pending = &history.Line{}
+		}
 	} else {
+		row := history.NewHistoryLine(text)
+		if this.pending != nil {
+			// the line read by the command executing such as `if`
+			err = this.History.Add(row)
+		} else {
+			this.History.Start(row)
+			this.pending = &row
+		}
+	}
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 	}
+	if lines := strings.Split(line, "\n"); len(lines) > 1 {
+		// The lines edited at once are read one by one, as the block
+		// commands such as `if` read the following lines from the stream.
+		this.PlainHistory = append(this.PlainHistory, lines...)
+		this.Pointer = len(this.PlainHistory) - len(lines) + 1
+		return ctx, lines[0], err
+	}
 	this.PlainHistory = append(this.PlainHistory, line)
 	return ctx, line, err
 }
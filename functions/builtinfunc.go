@@ -10,12 +10,6 @@ import (
 	"sort"
 
 	"github.com/mattn/go-isatty"
-	"github.com/mattn/msgbox"
-
-	"github.com/zetamatta/go-box"
-	"github.com/zetamatta/go-findfile"
-	"github.com/zetamatta/go-getch"
-	"github.com/zetamatta/go-mbcs"
 
 	"github.com/zetamatta/nyagos/commands"
 	"github.com/zetamatta/nyagos/completion"
@@ -75,7 +69,55 @@ func CmdBox(this *Param) []any_t {
 			sources = append(sources, fmt.Sprint(val))
 		}
 	}
-	return []any_t{box.Choice(sources, this.Term)}
+	return []any_t{readline.BoxChoice(sources, this.Term)}
+}
+
+// CmdFuzzyFinder selects items of the table with readline.FuzzyFinder.
+// The second argument is the table of the options: prompt, query and
+// multi. It returns the item selected, or the table of them when multi
+// is true. It returns nil when canceled.
+func CmdFuzzyFinder(this *Param) []any_t {
+	args := this.Args
+	if len(args) < 1 {
+		return []any_t{nil, TooFewArguments}
+	}
+	t, ok := args[0].(map[any_t]any_t)
+	if !ok {
+		return []any_t{nil, "Not a table"}
+	}
+	finder := &readline.FuzzyFinder{
+		Candidates: make([]string, 0, len(t)),
+	}
+	for i, i_ := 1, len(t); i <= i_; i++ {
+		if val, ok := t[i]; ok {
+			finder.Candidates = append(finder.Candidates, fmt.Sprint(val))
+		}
+	}
+	if len(args) >= 2 {
+		if options, ok := args[1].(map[any_t]any_t); ok {
+			if prompt, ok := options["prompt"].(string); ok {
+				finder.Prompt = prompt
+			}
+			if query, ok := options["query"].(string); ok {
+				finder.Query = query
+			}
+			if multi, ok := options["multi"].(bool); ok {
+				finder.Multi = multi
+			}
+		}
+	}
+	indexes, err := finder.Run(this.Term)
+	if err != nil {
+		return []any_t{nil}
+	}
+	if !finder.Multi {
+		return []any_t{finder.Candidates[indexes[0]]}
+	}
+	result := make([]string, len(indexes))
+	for i, index := range indexes {
+		result[i] = finder.Candidates[index]
+	}
+	return []any_t{result}
 }
 
 func CmdResetCharWidth(args []any_t) []any_t {
@@ -118,12 +160,12 @@ func CmdGetwd(args []any_t) []any_t {
 }
 
 func CmdGetKey(args []any_t) []any_t {
-	keycode, scancode, shiftstatus := getch.Full()
+	keycode, scancode, shiftstatus := readline.GetKey()
 	return []any_t{keycode, scancode, shiftstatus}
 }
 
 func CmdGetViewWidth(args []any_t) []any_t {
-	width, height := box.GetScreenBufferInfo().ViewSize()
+	width, height := readline.GetViewSize()
 	return []any_t{width, height}
 }
 
@@ -185,7 +227,7 @@ func CmdStat(args []any_t) []any_t {
 	} else {
 		path_ = path
 	}
-	statErr := findfile.Walk(path_, func(f *findfile.FileInfo) bool {
+	statErr := dos.Walk(path_, func(f *dos.FileInfo) bool {
 		stat = f
 		return false
 	})
@@ -245,7 +287,7 @@ func CmdAtoU(args []any_t) []any_t {
 		return []any_t{nil, TooFewArguments}
 	}
 	if s, ok := args[0].(string); ok {
-		if val, err := mbcs.AtoU([]byte(s)); err == nil {
+		if val, err := dos.AtoU([]byte(s)); err == nil {
 			return []any_t{val}
 		} else {
 			return []any_t{nil, err}
@@ -260,7 +302,7 @@ func CmdUtoA(args []any_t) []any_t {
 		return []any_t{nil, TooFewArguments}
 	}
 	utf8 := fmt.Sprint(args[0])
-	bin, err := mbcs.UtoA(utf8)
+	bin, err := dos.UtoA(utf8)
 	if err != nil {
 		return []any_t{nil, err}
 	}
@@ -289,7 +331,7 @@ func CmdGlob(args []any_t) []any_t {
 	result := make([]string, 0)
 	for _, arg1 := range args {
 		wildcard := fmt.Sprint(arg1)
-		list, err := findfile.Glob(wildcard)
+		list, err := dos.Glob(wildcard)
 		if list == nil || err != nil {
 			result = append(result, wildcard)
 		} else {
@@ -328,7 +370,7 @@ func CmdMsgBox(args []any_t) []any_t {
 	if len(args) >= 2 {
 		title = fmt.Sprint(args[1])
 	}
-	msgbox.Show(0, message, title, msgbox.OK)
+	showMessage(message, title)
 	return []any_t{}
 }
 
@@ -481,6 +523,39 @@ func SetOption(args []any_t) []any_t {
 	return []any_t{true}
 }
 
+// GetHighlight returns the color of `set -o highlight` for the key.
+func GetHighlight(args []any_t) []any_t {
+	if len(args) < 2 {
+		return []any_t{nil, "too few arguments"}
+	}
+	key := fmt.Sprint(args[1])
+	color, ok := commands.HighlightColors[key]
+	if !ok {
+		return []any_t{nil, fmt.Sprintf("key: %s: not found", key)}
+	}
+	return []any_t{color}
+}
+
+// SetHighlight sets the color of `set -o highlight` for the key.
+// nil or false leaves the text as it is.
+func SetHighlight(args []any_t) []any_t {
+	if len(args) < 3 {
+		return []any_t{nil, "too few arguments"}
+	}
+	key := fmt.Sprint(args[1])
+	if _, ok := commands.HighlightColors[key]; !ok {
+		return []any_t{nil, fmt.Sprintf("key: %s: not found", key)}
+	}
+	if color, ok := args[2].(string); ok {
+		commands.HighlightColors[key] = color
+	} else if args[2] == nil || args[2] == false {
+		commands.HighlightColors[key] = ""
+	} else {
+		return []any_t{nil, fmt.Sprintf("%s: not a string", key)}
+	}
+	return []any_t{true}
+}
+
 func bitOperators(args []any_t, result int, f func(int, int) int) []any_t {
 	for _, arg1tmp := range args {
 		if arg1, ok := arg1tmp.(int); ok {
@@ -0,0 +1,11 @@
+package functions
+
+import (
+	"fmt"
+	"os"
+)
+
+// showMessage prints the message on STDERR because there is no message box.
+func showMessage(message, title string) {
+	fmt.Fprintf(os.Stderr, "[%s] %s\n", title, message)
+}
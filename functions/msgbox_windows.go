@@ -0,0 +1,9 @@
+package functions
+
+import (
+	"github.com/mattn/msgbox"
+)
+
+func showMessage(message, title string) {
+	msgbox.Show(0, message, title, msgbox.OK)
+}
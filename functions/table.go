@@ -28,6 +28,7 @@ var Table = map[string]func([]interface{}) []interface{}{
 
 var Table2 = map[string]func(*Param) []interface{}{
 	"box":            CmdBox,
+	"fuzzyfinder":    CmdFuzzyFinder,
 	"raweval":        CmdRawEval,
 	"rawexec":        CmdRawExec,
 	"write":          CmdWrite,
@@ -0,0 +1,260 @@
+package history
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/zetamatta/nyagos/texts"
+)
+
+// lastOld and lastNew are the strings of the last `:s/old/new/`
+// to be repeated by `:&`.
+var lastOld, lastNew string
+
+// peekRune returns the next character of reader without reading it.
+func peekRune(reader *strings.Reader) (rune, bool) {
+	ch, _, err := reader.ReadRune()
+	if err != nil {
+		return 0, false
+	}
+	reader.UnreadRune()
+	return ch, true
+}
+
+// readNumber reads the digits from reader.
+func readNumber(reader *strings.Reader) (int, bool) {
+	var digits strings.Builder
+	for {
+		ch, ok := peekRune(reader)
+		if !ok || ch < '0' || ch > '9' {
+			break
+		}
+		reader.ReadRune()
+		digits.WriteRune(ch)
+	}
+	n, err := strconv.Atoi(digits.String())
+	return n, err == nil
+}
+
+// readWordDesignator reads the word designator such as `^`, `$`, `*`,
+// `N`, `X-Y`, `X*`, `X-` or `-Y`, and returns the range of the words.
+// last is the index of the last word.
+func readWordDesignator(reader *strings.Reader, last int) (int, int) {
+	ch, _, _ := reader.ReadRune()
+	switch ch {
+	case '^':
+		return 1, 1
+	case '$':
+		return last, last
+	case '*':
+		return 1, last
+	case '-':
+		end, ok := readNumber(reader)
+		if !ok {
+			if next, ok := peekRune(reader); ok && next == '$' {
+				reader.ReadRune()
+				return 0, last
+			}
+			return 0, last - 1
+		}
+		return 0, end
+	}
+	reader.UnreadRune()
+	start, _ := readNumber(reader)
+	next, ok := peekRune(reader)
+	if !ok {
+		return start, start
+	}
+	switch next {
+	case '*':
+		reader.ReadRune()
+		return start, last
+	case '-':
+		reader.ReadRune()
+		if end, ok := readNumber(reader); ok {
+			return start, end
+		}
+		if next, ok := peekRune(reader); ok && next == '$' {
+			reader.ReadRune()
+			return start, last
+		}
+		return start, last - 1
+	}
+	return start, start
+}
+
+// isWordDesignator returns true when ch starts the word designator.
+func isWordDesignator(ch rune) bool {
+	return strings.ContainsRune("^$*-", ch) || (ch >= '0' && ch <= '9')
+}
+
+// lastSeparator returns the position of the last `/` or `\` in s or -1.
+func lastSeparator(s string) int {
+	return strings.LastIndexAny(s, `/\`)
+}
+
+// readSubstitution reads `/old/new/` of `:s/old/new/`. The delimiter is
+// the first character and the last one can be omitted at the end of line.
+func readSubstitution(reader *strings.Reader) (string, string, error) {
+	delimiter, _, err := reader.ReadRune()
+	if err != nil {
+		return "", "", fmt.Errorf(":s: no delimiter")
+	}
+	read := func() (string, bool) {
+		var buffer strings.Builder
+		for {
+			ch, _, err := reader.ReadRune()
+			if err != nil {
+				return buffer.String(), false
+			}
+			if ch == delimiter {
+				return buffer.String(), true
+			}
+			if ch == '\\' {
+				if next, ok := peekRune(reader); ok && next == delimiter {
+					reader.ReadRune()
+					ch = next
+				}
+			}
+			buffer.WriteRune(ch)
+		}
+	}
+	old, ok := read()
+	if !ok {
+		return "", "", fmt.Errorf(":s%c%s: no closing delimiter", delimiter, old)
+	}
+	new_, _ := read()
+	if old == "" {
+		old = lastOld
+	}
+	return old, new_, nil
+}
+
+// substitute replaces old in s with new. `&` in new means old.
+func substitute(s, old, new_ string, global bool) (string, error) {
+	if old == "" || !strings.Contains(s, old) {
+		return "", fmt.Errorf(":s%c%s%c: substitution failed", '/', old, '/')
+	}
+	var buffer strings.Builder
+	for i := 0; i < len(new_); i++ {
+		if new_[i] == '\\' && i+1 < len(new_) && new_[i+1] == '&' {
+			buffer.WriteByte('&')
+			i++
+		} else if new_[i] == '&' {
+			buffer.WriteString(old)
+		} else {
+			buffer.WriteByte(new_[i])
+		}
+	}
+	if global {
+		return strings.Replace(s, old, buffer.String(), -1), nil
+	}
+	return strings.Replace(s, old, buffer.String(), 1), nil
+}
+
+// applyModifier applies the modifier such as `h`, `t`, `r`, `e`,
+// `s/old/new/`, `gs/old/new/` or `&` read from reader to s.
+func applyModifier(reader *strings.Reader, s string) (string, bool, error) {
+	ch, _, _ := reader.ReadRune()
+	switch ch {
+	case 'h':
+		if i := lastSeparator(s); i >= 0 {
+			return s[:i], false, nil
+		}
+		return s, false, nil
+	case 't':
+		return s[lastSeparator(s)+1:], false, nil
+	case 'r':
+		if i := strings.LastIndexByte(s, '.'); i > lastSeparator(s) {
+			return s[:i], false, nil
+		}
+		return s, false, nil
+	case 'e':
+		if i := strings.LastIndexByte(s, '.'); i > lastSeparator(s) {
+			return s[i:], false, nil
+		}
+		return "", false, nil
+	case 'p':
+		return s, true, nil
+	}
+	global := false
+	if ch == 'g' || ch == 'a' {
+		global = true
+		ch, _, _ = reader.ReadRune()
+	}
+	switch ch {
+	case 's':
+		old, new_, err := readSubstitution(reader)
+		if err != nil {
+			return "", false, err
+		}
+		lastOld, lastNew = old, new_
+		result, err := substitute(s, old, new_, global)
+		return result, false, err
+	case '&':
+		result, err := substitute(s, lastOld, lastNew, global)
+		return result, false, err
+	}
+	return "", false, fmt.Errorf(":%c: unrecognized history modifier", ch)
+}
+
+// isModifier returns true when ch starts the modifier.
+func isModifier(ch rune) bool {
+	return strings.ContainsRune("htreps&ga", ch)
+}
+
+// expandMacro writes the words of line selected with the word designator
+// and the modifiers following in reader. It returns true when the
+// modifier `:p` is used.
+func expandMacro(buffer *strings.Builder, reader *strings.Reader, line string) (bool, error) {
+	result := line
+	if ch, ok := peekRune(reader); ok {
+		// `^`, `$`, `*` and `-` can be used without `:`
+		designated := strings.ContainsRune("^$*-", ch)
+		if ch == ':' {
+			reader.ReadRune()
+			if next, ok := peekRune(reader); ok && isWordDesignator(next) {
+				designated = true
+			} else {
+				reader.Seek(-1, io.SeekCurrent)
+			}
+		}
+		if designated {
+			words := texts.SplitLikeShellString(line)
+			start, end := readWordDesignator(reader, len(words)-1)
+			if start == 1 && end == 0 {
+				// `*` of the line without arguments
+				result = ""
+			} else if start < 0 || end >= len(words) || start > end {
+				return false, fmt.Errorf("%d-%d: bad word specifier", start, end)
+			} else {
+				result = strings.Join(words[start:end+1], " ")
+			}
+		}
+	}
+	printOnly := false
+	for {
+		ch, ok := peekRune(reader)
+		if !ok || ch != ':' {
+			break
+		}
+		reader.ReadRune()
+		next, ok := peekRune(reader)
+		if !ok || !isModifier(next) {
+			// `:` which is not a part of the history expansion
+			reader.Seek(-1, io.SeekCurrent)
+			break
+		}
+		var p bool
+		var err error
+		result, p, err = applyModifier(reader, result)
+		if err != nil {
+			return false, err
+		}
+		printOnly = printOnly || p
+	}
+	buffer.WriteString(result)
+	return printOnly, nil
+}
@@ -0,0 +1,120 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+// jsonLine is the format of Line for `history --json`.
+type jsonLine struct {
+	Text       string    `json:"text"`
+	Dir        string    `json:"dir"`
+	Stamp      time.Time `json:"stamp"`
+	Pid        int       `json:"pid"`
+	ErrorLevel int       `json:"errorlevel"`
+	Duration   int64     `json:"duration_ms"`
+	Session    string    `json:"session"`
+}
+
+// tsvHeader is the first line of `history --tsv`.
+const tsvHeader = "text\tdir\tstamp\tpid\terrorlevel\tduration_ms\tsession"
+
+func (c *Container) exportJSON(w io.Writer, indexes []int) error {
+	list := make([]jsonLine, 0, len(indexes))
+	for _, i := range indexes {
+		row := &c.rows[i]
+		list = append(list, jsonLine{
+			Text:       row.Text,
+			Dir:        row.Dir,
+			Stamp:      row.Stamp,
+			Pid:        row.Pid,
+			ErrorLevel: row.ErrorLevel,
+			Duration:   int64(row.Duration / time.Millisecond),
+			Session:    row.Session,
+		})
+	}
+	bin, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(bin))
+	return err
+}
+
+func (c *Container) exportTSV(w io.Writer, indexes []int) error {
+	fmt.Fprintln(w, tsvHeader)
+	for _, i := range indexes {
+		if _, err := fmt.Fprintln(w, c.rows[i].String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseExported converts the output of `history --json` or `--tsv`
+// into rows. When format is empty, it is guessed from the data.
+func parseExported(data string, format string) ([]Line, error) {
+	if format == "" {
+		if strings.HasPrefix(strings.TrimSpace(data), "[") {
+			format = "json"
+		} else {
+			format = "tsv"
+		}
+	}
+	if format == "json" {
+		var list []jsonLine
+		if err := json.Unmarshal([]byte(data), &list); err != nil {
+			return nil, err
+		}
+		rows := make([]Line, 0, len(list))
+		for _, j := range list {
+			rows = append(rows, Line{
+				Text:       j.Text,
+				Dir:        j.Dir,
+				Stamp:      j.Stamp.Local().Truncate(time.Second),
+				Pid:        j.Pid,
+				ErrorLevel: j.ErrorLevel,
+				Duration:   time.Duration(j.Duration) * time.Millisecond,
+				Session:    j.Session,
+			})
+		}
+		return rows, nil
+	}
+	data = strings.TrimPrefix(data, tsvHeader+"\n")
+	data = strings.TrimPrefix(data, tsvHeader+"\r\n")
+	rows, _, err := readLines(strings.NewReader(data), 2)
+	return rows, err
+}
+
+// importFile merges the rows exported by `history --json` or `--tsv`
+// into the history and the file. `-` is the standard input.
+func (c *Container) importFile(cmd Param, fname string, format string) (int, error) {
+	var data []byte
+	var err error
+	if fname == "-" {
+		data, err = ioutil.ReadAll(cmd.In())
+	} else {
+		data, err = ioutil.ReadFile(fname)
+	}
+	if err != nil {
+		return 1, err
+	}
+	rows, err := parseExported(string(data), format)
+	if err != nil {
+		return 1, fmt.Errorf("history: %s: %s", fname, err.Error())
+	}
+	n := c.Merge(rows)
+	sort.SliceStable(c.rows, func(i, j int) bool {
+		return c.rows[i].Stamp.Before(c.rows[j].Stamp)
+	})
+	if err := c.Rewrite(); err != nil {
+		return 1, err
+	}
+	fmt.Fprintf(cmd.Err(), "history: %d rows imported\n", n)
+	return 0, nil
+}
@@ -0,0 +1,132 @@
+package history
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// HistSize is the number of the rows kept in memory. 0 means unlimited.
+var HistSize = 0
+
+// HistFileSize is the number of the rows written into the file when
+// it is rewritten. 0 means unlimited.
+var HistFileSize = 1000
+
+// IgnoreDups is the switch not to record the line same as the previous one.
+var IgnoreDups = false
+
+// IgnoreSpace is the switch not to record the line starting with a space.
+var IgnoreSpace = false
+
+// HistIgnore is the list of the glob patterns separated with `:`.
+// The lines matching one of them are not recorded.
+// `*` matches any string, `?` matches any character and
+// `&` matches the previous line.
+var HistIgnore = ""
+
+// FilterHookT is the function to rewrite the line before it is recorded.
+// It returns false to veto the line.
+type FilterHookT func(ctx context.Context, line string) (string, bool)
+
+var filterHook FilterHookT = func(ctx context.Context, line string) (string, bool) {
+	return line, true
+}
+
+// SetFilterHook replaces the filter hook and returns the previous one.
+func SetFilterHook(hook FilterHookT) (rv FilterHookT) {
+	rv, filterHook = filterHook, hook
+	return
+}
+
+var histIgnoreCache struct {
+	source  string
+	pattern []*regexp.Regexp
+}
+
+// globToRegexp converts the glob pattern of HistIgnore to the regular
+// expression matching the whole line.
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var buffer strings.Builder
+	buffer.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch ch := glob[i]; ch {
+		case '*':
+			buffer.WriteString(".*")
+		case '?':
+			buffer.WriteString(".")
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				buffer.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			} else {
+				buffer.WriteString(`\\`)
+			}
+		default:
+			buffer.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	buffer.WriteString("$")
+	return regexp.Compile(buffer.String())
+}
+
+// histIgnorePatterns returns the patterns of HistIgnore except `&`.
+func histIgnorePatterns() []*regexp.Regexp {
+	if histIgnoreCache.source == HistIgnore {
+		return histIgnoreCache.pattern
+	}
+	var pattern []*regexp.Regexp
+	for _, glob := range strings.Split(HistIgnore, ":") {
+		if glob == "" || glob == "&" {
+			continue
+		}
+		if re, err := globToRegexp(glob); err == nil {
+			pattern = append(pattern, re)
+		}
+	}
+	histIgnoreCache.source = HistIgnore
+	histIgnoreCache.pattern = pattern
+	return pattern
+}
+
+// isIgnored returns true when line matches HistIgnore.
+func (c *Container) isIgnored(line string) bool {
+	for _, glob := range strings.Split(HistIgnore, ":") {
+		if glob == "&" && len(c.rows) > 0 && c.rows[len(c.rows)-1].Text == line {
+			return true
+		}
+	}
+	for _, re := range histIgnorePatterns() {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the line to be recorded into the history, or false when
+// it should not be recorded by IgnoreSpace, the filter hook, HistIgnore
+// or IgnoreDups.
+func (c *Container) Filter(ctx context.Context, line string) (string, bool) {
+	if IgnoreSpace && strings.HasPrefix(line, " ") {
+		return "", false
+	}
+	line, ok := filterHook(ctx, line)
+	if !ok || line == "" {
+		return "", false
+	}
+	if c.isIgnored(line) {
+		return "", false
+	}
+	if IgnoreDups && len(c.rows) > 0 && c.rows[len(c.rows)-1].Text == line {
+		return "", false
+	}
+	return line, true
+}
+
+// trim removes the oldest rows over HistSize.
+func (c *Container) trim() {
+	if HistSize > 0 && len(c.rows) > HistSize {
+		c.rows = append(c.rows[:0], c.rows[len(c.rows)-HistSize:]...)
+	}
+}
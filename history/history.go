@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,13 +16,16 @@ import (
 	"unicode"
 
 	"github.com/mattn/go-isatty"
-	"github.com/zetamatta/nyagos/texts"
 )
 
 var Mark = "!"
 
 var DisableMarks = "\"'"
 
+// ErrPrintOnly is returned by Replace with the line expanded when the
+// modifier `:p` is used. The line should be printed, but not executed.
+var ErrPrintOnly = errors.New("history: print only")
+
 func (hisObj *Container) Replace(line string) (string, bool, error) {
 	var mark rune
 	for _, c := range Mark {
@@ -35,6 +39,21 @@ func (hisObj *Container) Replace(line string) (string, bool, error) {
 	history_count := hisObj.Len()
 
 	quotedChar := '\000'
+	printOnly := false
+	expand := func(line string) error {
+		p, err := expandMacro(&buffer, reader, line)
+		printOnly = printOnly || p
+		isReplaced = true
+		return err
+	}
+
+	if mark != 0 && strings.HasPrefix(line, "^") && history_count >= 1 {
+		// ^old^new^ is same as !!:s^old^new^
+		reader = strings.NewReader(":s" + line)
+		if err := expand(hisObj.At(history_count - 1)); err != nil {
+			return "", false, err
+		}
+	}
 
 	for reader.Len() > 0 {
 		ch, _, _ := reader.ReadRune()
@@ -61,16 +80,24 @@ func (hisObj *Container) Replace(line string) (string, bool, error) {
 			reader.UnreadRune()
 			if history_count >= 1 {
 				line := hisObj.At(history_count - 1)
-				expandMacro(&buffer, reader, line)
-				isReplaced = true
+				if err := expand(line); err != nil {
+					return "", false, err
+				}
+			}
+			continue
+		}
+		if ch == '#' { // !# : the line typed so far
+			if err := expand(buffer.String()); err != nil {
+				return "", false, err
 			}
 			continue
 		}
 		if ch == mark { // !!
 			if history_count >= 1 {
 				line := hisObj.At(history_count - 1)
-				expandMacro(&buffer, reader, line)
-				isReplaced = true
+				if err := expand(line); err != nil {
+					return "", false, err
+				}
 				continue
 			} else {
 				return "", false, errors.New("!!: event not found")
@@ -82,8 +109,9 @@ func (hisObj *Container) Replace(line string) (string, bool, error) {
 			fmt.Fscan(reader, &backno)
 			if 0 <= backno && backno < history_count {
 				line := hisObj.At(backno)
-				expandMacro(&buffer, reader, line)
-				isReplaced = true
+				if err := expand(line); err != nil {
+					return "", false, err
+				}
 			} else {
 				return "", false, fmt.Errorf("!%d: event not found", backno)
 			}
@@ -95,8 +123,9 @@ func (hisObj *Container) Replace(line string) (string, bool, error) {
 				backno := history_count - number
 				if 0 <= backno && backno < history_count {
 					line := hisObj.At(backno)
-					expandMacro(&buffer, reader, line)
-					isReplaced = true
+					if err := expand(line); err != nil {
+						return "", false, err
+					}
 				} else {
 					return "", false, fmt.Errorf("!-%d: event not found", number)
 				}
@@ -121,8 +150,9 @@ func (hisObj *Container) Replace(line string) (string, bool, error) {
 			for i := history_count - 1; i >= 0; i-- {
 				his1 := hisObj.At(i)
 				if strings.Contains(his1, seekStr) {
-					expandMacro(&buffer, reader, his1)
-					isReplaced = true
+					if err := expand(his1); err != nil {
+						return "", false, err
+					}
 					found = true
 					break
 				}
@@ -152,8 +182,9 @@ func (hisObj *Container) Replace(line string) (string, bool, error) {
 		for i := history_count - 1; i >= 0; i-- {
 			his1 := hisObj.At(i)
 			if strings.HasPrefix(his1, seekStr) {
-				expandMacro(&buffer, reader, his1)
-				isReplaced = true
+				if err := expand(his1); err != nil {
+					return "", false, err
+				}
 				found = true
 				break
 			}
@@ -162,109 +193,240 @@ func (hisObj *Container) Replace(line string) (string, bool, error) {
 			return "", false, fmt.Errorf("%c%s: event not found", mark, seekStr)
 		}
 	}
-	return buffer.String(), isReplaced, nil
-}
-
-func expandMacro(buffer *strings.Builder, reader *strings.Reader, line string) {
-	ch, siz, _ := reader.ReadRune()
-	if siz > 0 && ch == ':' {
-		ch, siz, _ = reader.ReadRune()
-	}
-	if siz > 0 && ch == '^' {
-		if words := texts.SplitLikeShellString(line); len(words) >= 2 {
-			buffer.WriteString(words[1])
-		}
-	} else if siz > 0 && ch == '$' {
-		if words := texts.SplitLikeShellString(line); len(words) >= 2 {
-			buffer.WriteString(words[len(words)-1])
-		}
-	} else if siz > 0 && ch == '*' {
-		if words := texts.SplitLikeShellString(line); len(words) >= 2 {
-			buffer.WriteString(strings.Join(words[1:], " "))
-		}
-	} else if siz > 0 && unicode.IsDigit(ch) {
-		var n int
-		reader.UnreadRune()
-		fmt.Fscan(reader, &n)
-		words := texts.SplitLikeShellString(line)
-		if n < len(words) {
-			buffer.WriteString(words[n])
-		}
-	} else {
-		if siz > 0 {
-			reader.UnreadRune()
-		}
-		buffer.WriteString(line)
+	if printOnly {
+		return buffer.String(), isReplaced, ErrPrintOnly
 	}
+	return buffer.String(), isReplaced, nil
 }
 
 type Param interface {
 	Arg(int) string
 	Args() []string
+	In() io.Reader
 	Out() io.Writer
 	Err() io.Writer
 }
 
+// filter is the condition of the rows shown by `history`.
+type filter struct {
+	pattern *regexp.Regexp
+	dir     string
+	since   time.Time
+	until   time.Time
+}
+
+func (f *filter) match(row *Line) bool {
+	if f.pattern != nil && !f.pattern.MatchString(row.Text) {
+		return false
+	}
+	if f.dir != "" && !strings.EqualFold(filepath.Clean(row.Dir), f.dir) {
+		return false
+	}
+	if !f.since.IsZero() && row.Stamp.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && !row.Stamp.Before(f.until) {
+		return false
+	}
+	return true
+}
+
+// parseTime converts the date such as `2018-01-02 15:04` or the time
+// before now such as `2h` and `7d`.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if strings.HasSuffix(s, "d") {
+		if days, err := strconv.Atoi(s[:len(s)-1]); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+		"2006/01/02 15:04:05",
+		"2006/01/02 15:04",
+		"2006/01/02",
+	} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s: invalid time", s)
+}
+
 func CmdHistory(ctx context.Context, cmd Param) (int, error) {
 	if ctx == nil {
 		fmt.Fprintln(cmd.Err(), "history not found (case1)")
 		return 1, nil
 	}
-	var num int
-	if len(cmd.Args()) >= 2 {
-		num64, err := strconv.ParseInt(cmd.Arg(1), 0, 32)
-		if err != nil {
-			switch err.(type) {
-			case *strconv.NumError:
-				return 0, fmt.Errorf(
-					"history: %s not a number", cmd.Arg(1))
-			default:
-				return 0, err
+	historyObj, ok := ctx.Value(PackageId).(*Container)
+	if !ok {
+		return -1, errors.New("history: not available in startup script")
+	}
+	var f filter
+	var format, importFile string
+	filtered := false
+	num := -1
+	args := cmd.Args()[1:]
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+		// next returns the parameter of the option.
+		next := func() (string, error) {
+			if len(args) <= 0 {
+				return "", fmt.Errorf("history: %s: too few arguments", arg)
 			}
+			value := args[0]
+			args = args[1:]
+			return value, nil
 		}
-		num = int(num64)
-		if num < 0 {
-			num = -num
+		switch arg {
+		case "-r":
+			// read the history which other sessions wrote
+			if _, err := historyObj.Sync(); err != nil {
+				return 1, err
+			}
+			return 0, nil
+		case "--clear":
+			return 0, historyObj.Clear()
+		case "-d":
+			value, err := next()
+			if err != nil {
+				return 1, err
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || n >= historyObj.Len() {
+				return 1, fmt.Errorf("history: %s: out of range", value)
+			}
+			return 0, historyObj.Delete(n)
+		case "-g":
+			value, err := next()
+			if err != nil {
+				return 1, err
+			}
+			if f.pattern, err = regexp.Compile(value); err != nil {
+				return 1, fmt.Errorf("history: %s", err.Error())
+			}
+			filtered = true
+		case "--dir":
+			wd, err := os.Getwd()
+			if err != nil {
+				return 1, err
+			}
+			f.dir = filepath.Clean(wd)
+			filtered = true
+		case "--since", "--until":
+			value, err := next()
+			if err != nil {
+				return 1, err
+			}
+			t, err := parseTime(value, time.Now())
+			if err != nil {
+				return 1, fmt.Errorf("history: %s", err.Error())
+			}
+			if arg == "--since" {
+				f.since = t
+			} else {
+				f.until = t
+			}
+			filtered = true
+		case "--json", "--tsv":
+			format = arg[2:]
+		case "--import":
+			value, err := next()
+			if err != nil {
+				return 1, err
+			}
+			importFile = value
+		default:
+			num64, err := strconv.ParseInt(arg, 0, 32)
+			if err != nil {
+				switch err.(type) {
+				case *strconv.NumError:
+					return 0, fmt.Errorf(
+						"history: %s not a number", arg)
+				default:
+					return 0, err
+				}
+			}
+			num = int(num64)
+			if num < 0 {
+				num = -num
+			}
 		}
-	} else {
-		num = 10
 	}
-	start := 0
+	if importFile != "" {
+		return historyObj.importFile(cmd, importFile, format)
+	}
 
-	historyObj, ok := ctx.Value(PackageId).(*Container)
-	if !ok {
-		return -1, errors.New("history: not available in startup script")
+	indexes := make([]int, 0, historyObj.Len())
+	for i := range historyObj.rows {
+		if f.match(&historyObj.rows[i]) {
+			indexes = append(indexes, i)
+		}
+	}
+	if num < 0 && !filtered && format == "" {
+		if f, ok := cmd.Out().(*os.File); ok && isatty.IsTerminal(f.Fd()) {
+			num = 10
+		}
+	}
+	if num >= 0 && len(indexes) > num {
+		indexes = indexes[len(indexes)-num:]
 	}
-	if f, ok := cmd.Out().(*os.File); ok && isatty.IsTerminal(f.Fd()) && historyObj.Len() > num {
-		start = historyObj.Len() - num
+
+	switch format {
+	case "json":
+		return 0, historyObj.exportJSON(cmd.Out(), indexes)
+	case "tsv":
+		return 0, historyObj.exportTSV(cmd.Out(), indexes)
 	}
 	home := os.Getenv("USERPROFILE")
-	for i := start; i < historyObj.Len(); i++ {
+	for _, i := range indexes {
 		row := historyObj.rows[i]
 		dir := row.Dir
-		if strings.HasPrefix(strings.ToUpper(dir), strings.ToUpper(home)) {
+		if home != "" && strings.HasPrefix(strings.ToUpper(dir), strings.ToUpper(home)) {
 			dir = "~" + dir[len(home):]
 		}
 		dir = filepath.ToSlash(dir)
-		fmt.Fprintf(cmd.Out(), "%4d  %s [%d] %-s (%s)\n",
+		fmt.Fprintf(cmd.Out(), "%4d  %s [%d] %-s (%s)%s\n",
 			i,
 			row.Stamp.Format("Jan _2 15:04:05"),
 			row.Pid,
 			row.Text,
-			dir)
+			dir,
+			row.result())
 	}
 	return 0, nil
 }
 
-const max_histories = 1000
+// result returns the errorlevel when the command failed and the duration
+// when it was slow, for `history`.
+func (row *Line) result() string {
+	var buffer strings.Builder
+	if row.ErrorLevel != 0 {
+		fmt.Fprintf(&buffer, " =>%d", row.ErrorLevel)
+	}
+	if row.Duration >= time.Second {
+		fmt.Fprintf(&buffer, " %s", row.Duration.Round(100*time.Millisecond))
+	}
+	return buffer.String()
+}
 
 func (hisObj *Container) SaveViaWriter(w io.Writer) {
 	i := 0
-	if len(hisObj.rows) > max_histories {
-		i = len(hisObj.rows) - max_histories
+	if HistFileSize > 0 && len(hisObj.rows) > HistFileSize {
+		i = len(hisObj.rows) - HistFileSize
 	}
 	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, fileHeader)
 	for ; i < len(hisObj.rows); i++ {
+		if hisObj.isUnsaved(&hisObj.rows[i]) {
+			continue
+		}
 		fmt.Fprintln(bw, hisObj.rows[i].String())
 	}
 	bw.Flush()
@@ -279,48 +441,73 @@ func (hisObj *Container) Save(path string) error {
 	return fd.Close()
 }
 
-func (hisObj *Container) LoadViaReader(reader io.Reader) {
+// fileHeader is the first line of the history file. The number is
+// the version of the format.
+// version 1: TEXT DIR STAMP PID (without the header)
+// version 2: TEXT DIR STAMP PID ERRORLEVEL DURATION(ms) SESSION
+const fileHeader = "#nyagos-history\t2"
+
+// newlineInFile is written instead of the newlines of TEXT, which are
+// in the commands edited over multiple lines.
+const newlineInFile = "\x1E"
+
+// parseHeader returns the version of the format if line is the header.
+func parseHeader(line string) (int, bool) {
+	p := strings.Split(line, "\t")
+	if len(p) != 2 || p[0] != "#nyagos-history" {
+		return 0, false
+	}
+	version, err := strconv.Atoi(p[1])
+	if err != nil {
+		return 0, false
+	}
+	return version, true
+}
+
+// parseLine converts the line in the history file into Line.
+func parseLine(line string, version int) Line {
+	p := strings.Split(line, "\t")
+	row := Line{Text: strings.Replace(p[0], newlineInFile, "\n", -1)}
+	if len(p) >= 3 {
+		row.Dir = p[1]
+		row.Stamp, _ = time.ParseInLocation("2006-01-02 15:04:05", p[2], time.Local)
+		if len(p) >= 4 {
+			row.Pid, _ = strconv.Atoi(p[3])
+		}
+	}
+	if version >= 2 && len(p) >= 7 {
+		row.ErrorLevel, _ = strconv.Atoi(p[4])
+		if ms, err := strconv.ParseInt(p[5], 10, 64); err == nil {
+			row.Duration = time.Duration(ms) * time.Millisecond
+		}
+		row.Session = p[6]
+	}
+	return row
+}
+
+// readLines reads the rows from reader. version is the format assumed
+// until the header is found, and the last version is returned.
+func readLines(reader io.Reader, version int) ([]Line, int, error) {
 	sc := bufio.NewScanner(reader)
-	list := make([][]string, 0, 2000)
-	hash := make(map[string]int)
+	rows := make([]Line, 0, 2000)
 	for sc.Scan() {
 		line := sc.Text()
-		if lnum, ok := hash[line]; ok {
-			// delete duplicated record (marking)
-			list[lnum] = nil
+		if v, ok := parseHeader(line); ok {
+			version = v
+			continue
 		}
-		hash[line] = len(list)
-
-		p := strings.Split(line, "\t")
-		list = append(list, p)
-	}
-	for _, p := range list {
-		// push only not duplicated record.
-		if p != nil {
-			var stamp time.Time
-			var dir string
-			pid := 0
-			if len(p) >= 3 {
-				dir = p[1]
-				stamp, _ = time.Parse("2006-01-02 15:04:05", p[2])
-				if len(p) >= 4 {
-					pid, _ = strconv.Atoi(p[3])
-				}
-			}
-			hisObj.PushLine(Line{
-				Text:  p[0],
-				Dir:   dir,
-				Stamp: stamp,
-				Pid:   pid})
+		if line != "" {
+			rows = append(rows, parseLine(line, version))
 		}
 	}
-	sort.Slice(hisObj.rows, func(i, j int) bool {
-		p := hisObj.rows[i]
-		q := hisObj.rows[j]
-		if p.Stamp != q.Stamp {
-			return p.Stamp.Before(q.Stamp)
-		}
-		return p.Text < q.Text
+	return rows, version, sc.Err()
+}
+
+func (hisObj *Container) LoadViaReader(reader io.Reader) {
+	rows, _, _ := readLines(reader, 1)
+	hisObj.Merge(rows)
+	sort.SliceStable(hisObj.rows, func(i, j int) bool {
+		return hisObj.rows[i].Stamp.Before(hisObj.rows[j].Stamp)
 	})
 }
 
@@ -1,8 +1,15 @@
 package history
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 )
 
 type history_t struct {
@@ -71,6 +78,37 @@ func TestExpandMacro(t *testing.T) {
 	}
 }
 
+func TestReplaceDesignators(t *testing.T) {
+	hisObj := &Container{}
+	hisObj.Push(`cp "a b.txt" dir/c.tar.gz /tmp/d.c`)
+	for source, expect := range map[string]string{
+		"echo !!:2-3":           `echo dir/c.tar.gz /tmp/d.c`,
+		"echo !!:2*":            `echo dir/c.tar.gz /tmp/d.c`,
+		"echo !!:-2":            `echo cp "a b.txt" dir/c.tar.gz`,
+		"echo !!:1-":            `echo "a b.txt" dir/c.tar.gz`,
+		"echo !$:h !$:t":        `echo /tmp d.c`,
+		"echo !!:2:r !!:2:e":    `echo dir/c.tar .gz`,
+		"ls !!:$:s/d/e/":        `ls /tmp/e.c`,
+		"ls !!:$:gs/t/T/":       `ls /Tmp/d.c`,
+		"ls !!:$:s/d/[&]/ !$:&": `ls /tmp/[d].c /tmp/[d].c`,
+		"^cp^mv^ -f":            `mv "a b.txt" dir/c.tar.gz /tmp/d.c -f`,
+		"echo a !#:1":           `echo a a`,
+	} {
+		result, _, err := hisObj.Replace(source)
+		if err != nil || result != expect {
+			t.Errorf("Replace(%q) = %q,%v, expected %q", source, result, err, expect)
+		}
+	}
+	if result, _, err := hisObj.Replace("!!:p"); err != ErrPrintOnly || result != hisObj.At(0) {
+		t.Errorf("Replace(\"!!:p\") = %q,%v", result, err)
+	}
+	for _, source := range []string{"!!:9", "!!:s/zz/y/", "!!:gz"} {
+		if _, _, err := hisObj.Replace(source); err == nil {
+			t.Errorf("Replace(%q): error expected", source)
+		}
+	}
+}
+
 func TestLoadFromReader(t *testing.T) {
 	source := `aaaa
 aaaa
@@ -86,6 +124,323 @@ cccc`
 	}
 }
 
+func TestLoadDedupByText(t *testing.T) {
+	source := "aaaa\tC:\\old\t2018-01-01 00:00:00\t1\n" +
+		"bbbb\tC:\\\t2018-01-02 00:00:00\t2\n" +
+		"aaaa\tC:\\new\t2018-01-03 00:00:00\t3\n"
+	hisObj := &Container{}
+	hisObj.LoadViaReader(strings.NewReader(source))
+	if hisObj.Len() != 2 || hisObj.At(0) != "bbbb" || hisObj.At(1) != "aaaa" {
+		t.Fatalf("%#v", hisObj.rows)
+	}
+	if row := hisObj.rows[1]; row.Dir != `C:\new` || row.Pid != 3 {
+		t.Fatalf("%#v", row)
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "nyagos.history")
+
+	session1 := &Container{}
+	session2 := &Container{}
+	if err := session1.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := session2.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	session1.Add(NewHistoryLine("aaaa"))
+	session2.Add(NewHistoryLine("bbbb"))
+	session1.Add(NewHistoryLine("cccc"))
+
+	if n, err := session1.Sync(); err != nil || n != 1 {
+		t.Fatalf("Sync()=%d,%v", n, err)
+	}
+	if session1.Len() != 3 || session1.At(2) != "bbbb" {
+		t.Fatalf("%#v", session1.rows)
+	}
+	if n, err := session2.Sync(); err != nil || n != 2 {
+		t.Fatalf("Sync()=%d,%v", n, err)
+	}
+
+	// another session rewrites the file.
+	session3 := &Container{}
+	if err := session3.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if session3.Len() != 3 {
+		t.Fatalf("%#v", session3.rows)
+	}
+	if n, err := session2.Sync(); err != nil || n != 0 || session2.Len() != 3 {
+		t.Fatalf("Sync()=%d,%v %#v", n, err, session2.rows)
+	}
+
+	// the rows removed from the memory are not read again from the
+	// replaced file, but the new rows are.
+	session2.rows = session2.rows[:1]
+	session3.Add(NewHistoryLine("dddd"))
+	session4 := &Container{}
+	if err := session4.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := session2.Sync(); err != nil || n != 1 || session2.Len() != 2 || session2.At(1) != "dddd" {
+		t.Fatalf("Sync()=%d,%v %#v", n, err, session2.rows)
+	}
+}
+
+func TestStoreDelete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "nyagos.history")
+
+	session1 := &Container{}
+	if err := session1.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	session1.Add(NewHistoryLine("secret1"))
+	session1.Add(NewHistoryLine("aaaa"))
+	session1.Add(NewHistoryLine("secret2"))
+
+	// another session replaces the file before deleting.
+	if err := (&Container{}).Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := session1.Delete(0); err != nil {
+		t.Fatal(err)
+	}
+	if session1.Len() != 2 || session1.At(0) != "aaaa" {
+		t.Fatalf("%#v", session1.rows)
+	}
+	loaded := &Container{}
+	if err := loaded.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != 2 || loaded.At(0) != "aaaa" || loaded.At(1) != "secret2" {
+		t.Fatalf("%#v", loaded.rows)
+	}
+
+	if err := session1.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if session1.Len() != 0 {
+		t.Fatalf("%#v", session1.rows)
+	}
+	loaded = &Container{}
+	if err := loaded.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != 0 {
+		t.Fatalf("%#v", loaded.rows)
+	}
+}
+
+func TestStoreBlock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "nyagos.history")
+
+	// the lines read while `if` is running as the console does
+	session1 := &Container{}
+	if err := session1.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	row := NewHistoryLine("if exist x then")
+	session1.Start(row)
+	session1.Add(NewHistoryLine("  echo x"))
+	session1.Add(NewHistoryLine("end"))
+
+	loaded := &Container{}
+	if err := loaded.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != 0 {
+		t.Fatalf("written before Finish: %#v", loaded.rows)
+	}
+
+	row.ErrorLevel = 1
+	if err := session1.Finish(row); err != nil {
+		t.Fatal(err)
+	}
+	session1.Add(NewHistoryLine("ls"))
+
+	loaded = &Container{}
+	if err := loaded.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"if exist x then", "  echo x", "end", "ls"}
+	if loaded.Len() != len(expect) {
+		t.Fatalf("%#v", loaded.rows)
+	}
+	for i, text := range expect {
+		if loaded.At(i) != text {
+			t.Fatalf("%d: %q", i, loaded.At(i))
+		}
+	}
+	if loaded.rows[0].ErrorLevel != 1 {
+		t.Fatalf("ErrorLevel=%d", loaded.rows[0].ErrorLevel)
+	}
+	if n, err := session1.Sync(); err != nil || n != 0 {
+		t.Fatalf("Sync()=%d,%v", n, err)
+	}
+}
+
+func TestFileFormat(t *testing.T) {
+	row := Line{
+		Text:       "make",
+		Dir:        `C:\src`,
+		Stamp:      time.Date(2018, 1, 2, 3, 4, 5, 0, time.Local),
+		Pid:        123,
+		ErrorLevel: 2,
+		Duration:   1500 * time.Millisecond,
+		Session:    "123-abc",
+	}
+	hisObj := &Container{rows: []Line{row}}
+	var buffer strings.Builder
+	hisObj.SaveViaWriter(&buffer)
+	if !strings.HasPrefix(buffer.String(), fileHeader+"\n") {
+		t.Fatalf("no header: %q", buffer.String())
+	}
+	loaded := &Container{}
+	loaded.LoadViaReader(strings.NewReader(buffer.String()))
+	if loaded.Len() != 1 || loaded.rows[0] != row {
+		t.Fatalf("%#v", loaded.rows)
+	}
+
+	// the command edited over multiple lines
+	row.Text = "if exist foo then\n  type foo\nend"
+	hisObj = &Container{rows: []Line{row}}
+	buffer.Reset()
+	hisObj.SaveViaWriter(&buffer)
+	if n := strings.Count(buffer.String(), "\n"); n != 2 {
+		t.Fatalf("%d newlines: %q", n, buffer.String())
+	}
+	loaded = &Container{}
+	loaded.LoadViaReader(strings.NewReader(buffer.String()))
+	if loaded.Len() != 1 || loaded.rows[0] != row {
+		t.Fatalf("%#v", loaded.rows)
+	}
+
+	// the old format without the header
+	loaded = &Container{}
+	loaded.LoadViaReader(strings.NewReader("make\tC:\\src\t2018-01-02 03:04:05\t123\n"))
+	if loaded.Len() != 1 || loaded.rows[0].Pid != 123 || loaded.rows[0].Session != "" {
+		t.Fatalf("%#v", loaded.rows)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2018, 1, 10, 12, 0, 0, 0, time.Local)
+	for source, expect := range map[string]time.Time{
+		"7d":               time.Date(2018, 1, 3, 12, 0, 0, 0, time.Local),
+		"2h":               time.Date(2018, 1, 10, 10, 0, 0, 0, time.Local),
+		"2018-01-02":       time.Date(2018, 1, 2, 0, 0, 0, 0, time.Local),
+		"2018-01-02 15:04": time.Date(2018, 1, 2, 15, 4, 0, 0, time.Local),
+	} {
+		if result, err := parseTime(source, now); err != nil || !result.Equal(expect) {
+			t.Errorf("parseTime(%q) = %v,%v", source, result, err)
+		}
+	}
+	if _, err := parseTime("yesterday", now); err == nil {
+		t.Error("parseTime(\"yesterday\"): error expected")
+	}
+}
+
+func TestExport(t *testing.T) {
+	rows := []Line{
+		{Text: "make", Dir: "/src", Stamp: time.Date(2018, 1, 2, 3, 4, 5, 0, time.Local), Pid: 1, ErrorLevel: 2},
+		{Text: "ls -l", Dir: "/tmp", Stamp: time.Date(2018, 1, 3, 3, 4, 5, 0, time.Local), Pid: 1,
+			Duration: 1500 * time.Millisecond, Session: "1-a"},
+	}
+	hisObj := &Container{rows: rows}
+	f := filter{pattern: regexp.MustCompile(`^ma`)}
+	if !f.match(&rows[0]) || f.match(&rows[1]) {
+		t.Error("filter -g failed")
+	}
+	f = filter{since: rows[1].Stamp}
+	if f.match(&rows[0]) || !f.match(&rows[1]) {
+		t.Error("filter --since failed")
+	}
+	for _, format := range []string{"json", "tsv"} {
+		var buffer strings.Builder
+		if format == "json" {
+			hisObj.exportJSON(&buffer, []int{0, 1})
+		} else {
+			hisObj.exportTSV(&buffer, []int{0, 1})
+		}
+		result, err := parseExported(buffer.String(), "")
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !reflect.DeepEqual(result, rows) {
+			t.Errorf("%s:\n%s\n%#v", format, buffer.String(), result)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	defer func(space, dups bool, ignore string) {
+		IgnoreSpace, IgnoreDups, HistIgnore = space, dups, ignore
+	}(IgnoreSpace, IgnoreDups, HistIgnore)
+	defer SetFilterHook(SetFilterHook(func(ctx context.Context, line string) (string, bool) {
+		if line == "secret" {
+			return "", false
+		}
+		return strings.TrimSuffix(line, " #"), true
+	}))
+	IgnoreSpace, IgnoreDups, HistIgnore = true, true, "ls:cd *:?"
+
+	hisObj := &Container{rows: []Line{{Text: "make"}}}
+	for line, expect := range map[string]string{
+		" make":      "",
+		"make":       "",
+		"make #":     "",
+		"make all #": "make all",
+		"secret":     "",
+		"ls":         "",
+		"ls -l":      "ls -l",
+		"cd /tmp":    "",
+		"x":          "",
+		"xy":         "xy",
+	} {
+		result, ok := hisObj.Filter(context.Background(), line)
+		if ok != (expect != "") || result != expect {
+			t.Errorf("Filter(%q) = %q,%v", line, result, ok)
+		}
+	}
+}
+
+func TestHistSize(t *testing.T) {
+	defer func(size, fileSize int) {
+		HistSize, HistFileSize = size, fileSize
+	}(HistSize, HistFileSize)
+	HistSize, HistFileSize = 3, 2
+
+	hisObj := &Container{}
+	for _, line := range []string{"a", "b", "c", "d"} {
+		hisObj.Push(line)
+	}
+	if hisObj.Len() != 3 || hisObj.At(0) != "b" {
+		t.Errorf("HistSize: %v", hisObj.rows)
+	}
+	var buffer strings.Builder
+	hisObj.SaveViaWriter(&buffer)
+	rows, _, err := readLines(strings.NewReader(buffer.String()), 1)
+	if err != nil || len(rows) != 2 || rows[0].Text != "c" {
+		t.Errorf("HistFileSize: %v %v", rows, err)
+	}
+}
+
 // func TestSaveToWriter(t *testing.T) {
 // 	hisObj := &Container{
 // 		[]Line{
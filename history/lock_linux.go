@@ -0,0 +1,16 @@
+package history
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile locks fd exclusively. It waits until other processes unlock it.
+func lockFile(fd *os.File) error {
+	return unix.Flock(int(fd.Fd()), unix.LOCK_EX)
+}
+
+func unlockFile(fd *os.File) error {
+	return unix.Flock(int(fd.Fd()), unix.LOCK_UN)
+}
@@ -0,0 +1,19 @@
+package history
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile locks fd exclusively. It waits until other processes unlock it.
+func lockFile(fd *os.File) error {
+	var overlapped windows.Overlapped
+	return windows.LockFileEx(windows.Handle(fd.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &overlapped)
+}
+
+func unlockFile(fd *os.File) error {
+	var overlapped windows.Overlapped
+	return windows.UnlockFileEx(windows.Handle(fd.Fd()), 0, 1, 0, &overlapped)
+}
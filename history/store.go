@@ -0,0 +1,279 @@
+package history
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ShareHistory is the switch to merge the history of other sessions
+// before every prompt.
+var ShareHistory = false
+
+// Store is the history file shared by the running sessions.
+// It is locked while it is read or written, and is replaced atomically
+// when rewritten.
+type Store struct {
+	Path    string
+	info    os.FileInfo // the file read last
+	size    int64       // the bytes read from the file
+	version int         // the format of the file read last
+	last    *Line       // the row read or written last
+}
+
+// Lock locks the file `path.lock` and returns the function to unlock it.
+// It waits until other processes unlock it.
+func Lock(path string) (func(), error) {
+	fd, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(fd); err != nil {
+		fd.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(fd)
+		fd.Close()
+	}, nil
+}
+
+func (s *Store) lock() (func(), error) {
+	return Lock(s.Path)
+}
+
+// sameRow returns true when a and b are the same row of the file, where
+// the stamps are written in seconds.
+func sameRow(a, b *Line) bool {
+	return a.Text == b.Text && a.Session == b.Session && a.Stamp.Unix() == b.Stamp.Unix()
+}
+
+// rowsAfter returns the rows following last. When last is not found,
+// the rows which are not older than last are returned.
+func rowsAfter(rows []Line, last *Line) []Line {
+	for i := len(rows) - 1; i >= 0; i-- {
+		if sameRow(&rows[i], last) {
+			return rows[i+1:]
+		}
+	}
+	newRows := rows[:0:0]
+	for _, row := range rows {
+		if row.Stamp.Unix() >= last.Stamp.Unix() {
+			newRows = append(newRows, row)
+		}
+	}
+	return newRows
+}
+
+func containsRow(rows []Line, row *Line) bool {
+	for i := range rows {
+		if sameRow(&rows[i], row) {
+			return true
+		}
+	}
+	return false
+}
+
+// readNew returns the rows appended since the last read.
+// When the file was replaced, the rows after the one read last are
+// returned.
+func (s *Store) readNew() ([]Line, error) {
+	fd, err := os.Open(s.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.info, s.size = nil, 0
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer fd.Close()
+	info, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+	replaced := s.info == nil || !os.SameFile(s.info, info) || info.Size() < s.size
+	if replaced {
+		s.size = 0
+		s.version = 1
+	}
+	if _, err := fd.Seek(s.size, io.SeekStart); err != nil {
+		return nil, err
+	}
+	rows, version, err := readLines(io.LimitReader(fd, info.Size()-s.size), s.version)
+	s.info, s.size, s.version = info, info.Size(), version
+	if len(rows) > 0 {
+		last := rows[len(rows)-1]
+		if replaced && s.last != nil {
+			rows = rowsAfter(rows, s.last)
+		}
+		s.last = &last
+	}
+	return rows, err
+}
+
+// ReadNew returns the rows which other sessions appended since the last
+// read. When the file was rewritten, the rows known already are skipped.
+func (s *Store) ReadNew() ([]Line, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return s.readNew()
+}
+
+// Append writes rows at the end of the file.
+func (s *Store) Append(rows ...Line) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	fd, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := fd.Stat()
+	upToDate := err == nil && s.info != nil && os.SameFile(s.info, info) && info.Size() == s.size
+	if err == nil && info.Size() == 0 {
+		fmt.Fprintln(fd, fileHeader)
+	}
+	for _, row := range rows {
+		fmt.Fprintln(fd, row.String())
+	}
+	if upToDate && len(rows) > 0 {
+		// The own rows need not be read again.
+		if info, err := fd.Stat(); err == nil {
+			s.info, s.size = info, info.Size()
+			s.last = &rows[len(rows)-1]
+		}
+	}
+	return fd.Close()
+}
+
+// Rewrite merges the rows which other sessions appended into c, and
+// replaces the file with the last rows of c. The rows in deleted are not
+// merged even if the file has them.
+func (s *Store) Rewrite(c *Container, deleted []Line) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	rows, err := s.readNew()
+	if err != nil {
+		return err
+	}
+	if len(deleted) > 0 {
+		newRows := rows[:0:0]
+		for _, row := range rows {
+			if !containsRow(deleted, &row) {
+				newRows = append(newRows, row)
+			}
+		}
+		rows = newRows
+	}
+	c.Merge(rows)
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	c.SaveViaWriter(tmp)
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.Path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if info, err := os.Stat(s.Path); err == nil {
+		s.info, s.size = info, info.Size()
+	}
+	s.last = c.lastSaved()
+	return nil
+}
+
+// lastSaved returns the last row written by SaveViaWriter. When no rows
+// are written, the row without text stamped now is returned to tell that
+// the older rows are known.
+func (c *Container) lastSaved() *Line {
+	for i := len(c.rows) - 1; i >= 0; i-- {
+		if !c.isUnsaved(&c.rows[i]) {
+			row := c.rows[i]
+			return &row
+		}
+	}
+	return &Line{Stamp: time.Now()}
+}
+
+// Open loads the history file and rewrites it without duplicated rows.
+// The rows given to Add later are appended to the file.
+func (c *Container) Open(path string) error {
+	c.store = &Store{Path: path}
+	return c.store.Rewrite(c, nil)
+}
+
+// Add appends row to the history and the file opened by Open.
+// While the row given to Start is running, row is written after it by
+// Finish.
+func (c *Container) Add(row Line) error {
+	c.PushLine(row)
+	if c.running != nil {
+		c.waiting = append(c.waiting, row)
+		return nil
+	}
+	if c.store == nil {
+		return nil
+	}
+	return c.store.Append(row)
+}
+
+// Sync merges the rows which other sessions appended to the file opened
+// by Open, and returns the number of them.
+func (c *Container) Sync() (int, error) {
+	if c.store == nil {
+		return 0, nil
+	}
+	rows, err := c.store.ReadNew()
+	if err != nil {
+		return 0, err
+	}
+	return c.Merge(rows), nil
+}
+
+// Rewrite replaces the file opened by Open with the rows in c.
+// The rows which other sessions appended are merged before.
+func (c *Container) Rewrite() error {
+	if c.store == nil {
+		return nil
+	}
+	return c.store.Rewrite(c, nil)
+}
+
+// Clear removes all rows from the history and the file opened by Open.
+func (c *Container) Clear() error {
+	deleted := c.rows
+	c.rows = nil
+	if c.store == nil {
+		return nil
+	}
+	return c.store.Rewrite(c, deleted)
+}
+
+// Delete removes the n-th row from the history and the file opened by
+// Open.
+func (c *Container) Delete(n int) error {
+	deleted := []Line{c.rows[n]}
+	c.rows = append(c.rows[:n], c.rows[n+1:]...)
+	if c.store == nil {
+		return nil
+	}
+	return c.store.Rewrite(c, deleted)
+}
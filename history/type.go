@@ -3,20 +3,30 @@ package history
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
 // Line has one history data
 type Line struct {
-	Text  string
-	Dir   string
-	Stamp time.Time
-	Pid   int
+	Text       string
+	Dir        string
+	Stamp      time.Time
+	Pid        int
+	ErrorLevel int
+	Duration   time.Duration
+	Session    string
 }
 
+// SessionID is the identifier of this process recorded in the history.
+var SessionID = fmt.Sprintf("%d-%x", os.Getpid(), time.Now().Unix())
+
 // Container has all history data.
 type Container struct {
-	rows []Line
+	rows    []Line
+	store   *Store // the file opened by Open or nil
+	running *Line  // the row given to Start and not to Finish yet
+	waiting []Line // the rows given to Add while running
 }
 
 type packageIdT struct{}
@@ -37,23 +47,115 @@ func (c *Container) At(n int) string {
 	return c.rows[n%len(c.rows)].Text
 }
 
+// DirAt returns the directory where n-th history-text was executed
+func (c *Container) DirAt(n int) string {
+	for n < 0 {
+		n += len(c.rows)
+	}
+	return c.rows[n%len(c.rows)].Dir
+}
+
 // Push appends a new history line to self with string
 func (c *Container) Push(line string) {
 	c.rows = append(c.rows, Line{Text: line})
+	c.trim()
 }
 
 // PushLine appends a new history line to self with Line object
 func (c *Container) PushLine(row Line) {
 	c.rows = append(c.rows, row)
+	c.trim()
+}
+
+// Merge appends rows which are read from the file. The rows which have
+// the same text are unified into the most recent one.
+// It returns the number of the rows appended.
+func (c *Container) Merge(rows []Line) int {
+	index := make(map[string]int, len(c.rows))
+	for i, row := range c.rows {
+		index[row.Text] = i
+	}
+	removed := map[int]bool{}
+	count := 0
+	for _, row := range rows {
+		if i, ok := index[row.Text]; ok {
+			if !row.Stamp.After(c.rows[i].Stamp) {
+				continue
+			}
+			removed[i] = true
+		}
+		index[row.Text] = len(c.rows)
+		c.rows = append(c.rows, row)
+		count++
+	}
+	if len(removed) > 0 {
+		rows := c.rows[:0]
+		for i, row := range c.rows {
+			if !removed[i] {
+				rows = append(rows, row)
+			}
+		}
+		c.rows = rows
+	}
+	c.trim()
+	return count
+}
+
+// Start appends the row of the command starting to self.
+// It is written into the file by Finish, and so are the rows given to
+// Add until then, not to write them before it.
+func (c *Container) Start(row Line) {
+	c.PushLine(row)
+	c.running = &row
+	c.waiting = nil
+}
+
+func (c *Container) isRunning(row *Line) bool {
+	return c.running != nil && c.running.Text == row.Text && c.running.Stamp.Equal(row.Stamp)
+}
+
+// isUnsaved returns true when row is written into the file by Finish.
+func (c *Container) isUnsaved(row *Line) bool {
+	if c.isRunning(row) {
+		return true
+	}
+	for i := range c.waiting {
+		if c.waiting[i].Text == row.Text && c.waiting[i].Stamp.Equal(row.Stamp) {
+			return true
+		}
+	}
+	return false
+}
+
+// Finish replaces the row given to Start by row which has the result of
+// the command, and appends it and the rows given to Add after Start to
+// the file opened by Open.
+func (c *Container) Finish(row Line) error {
+	waiting := c.waiting
+	c.running = nil
+	c.waiting = nil
+	for i := len(c.rows) - 1; i >= 0; i-- {
+		if c.rows[i].Text == row.Text && c.rows[i].Stamp.Equal(row.Stamp) {
+			c.rows[i] = row
+			break
+		}
+	}
+	if c.store == nil {
+		return nil
+	}
+	return c.store.Append(append([]Line{row}, waiting...)...)
 }
 
 // String returns self as printable text
 func (row *Line) String() string {
-	return fmt.Sprintf("%s\t%s\t%s\t%d",
-		row.Text,
+	return fmt.Sprintf("%s\t%s\t%s\t%d\t%d\t%d\t%s",
+		strings.Replace(row.Text, "\n", newlineInFile, -1),
 		row.Dir,
 		row.Stamp.Format("2006-01-02 15:04:05"),
-		row.Pid)
+		row.Pid,
+		row.ErrorLevel,
+		row.Duration/time.Millisecond,
+		row.Session)
 }
 
 // NewHistoryLine returns new Line object with history-text
@@ -62,5 +164,11 @@ func NewHistoryLine(text string) Line {
 	if err != nil {
 		wd = ""
 	}
-	return Line{Text: text, Dir: wd, Stamp: time.Now(), Pid: os.Getpid()}
+	return Line{
+		Text:    text,
+		Dir:     wd,
+		Stamp:   time.Now(),
+		Pid:     os.Getpid(),
+		Session: SessionID,
+	}
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/zetamatta/nyagos/defined"
 	"github.com/zetamatta/nyagos/frame"
 	"github.com/zetamatta/nyagos/mains"
+	"github.com/zetamatta/nyagos/shell"
 )
 
 var version string
@@ -26,5 +27,9 @@ func main() {
 	if defined.DBG {
 		os.Stdin.Read(dummy[:])
 	}
-	os.Exit(0)
+	if shell.ErrExit && shell.LastErrorLevel != 0 {
+		// the script was stopped by errexit.
+		os.Exit(shell.LastErrorLevel)
+	}
+	os.Exit(frame.ExitCode)
 }
@@ -8,8 +8,6 @@ import (
 
 	"github.com/yuin/gopher-lua"
 
-	"github.com/zetamatta/go-box"
-
 	"github.com/zetamatta/nyagos/readline"
 	"github.com/zetamatta/nyagos/texts"
 )
@@ -128,7 +126,7 @@ func callBoxListing(L Lua) int {
 	for i := 0; i < size; i++ {
 		list[i] = L.GetTable(table, lua.LNumber(i+1)).String()
 	}
-	box.Print(nil, list, os.Stdout)
+	readline.BoxPrint(nil, list, os.Stdout)
 	this.RepaintAll()
 	return 0
 }
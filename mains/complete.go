@@ -68,6 +68,11 @@ func luaHookForComplete(ctx context.Context, this *readline.Buffer, rv *completi
 	if !ok {
 		listupStrs = insertStrs
 	}
+	// The kinds of the candidates are kept for the menu.
+	original := make(map[string]completion.Element, len(rv.List))
+	for _, e := range rv.List {
+		original[e.String()] = e
+	}
 	newList := make([]completion.Element, 0, len(rv.List)+32)
 	wordUpr := strings.ToUpper(rv.Word)
 	L.ForEach(insertStrs, func(key, val lua.LValue) {
@@ -79,8 +84,13 @@ func luaHookForComplete(ctx context.Context, this *readline.Buffer, rv *completi
 				if !ok {
 					listupStr = str
 				}
-				newList = append(newList, completion.Element2{
-					string(str), string(listupStr)})
+				if c, ok := original[string(str)].(completion.Candidate); ok {
+					c.Shown = string(listupStr)
+					newList = append(newList, c)
+				} else {
+					newList = append(newList, completion.Element2{
+						string(str), string(listupStr)})
+				}
 			}
 		}
 	})
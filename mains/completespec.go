@@ -0,0 +1,146 @@
+package mains
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/yuin/gopher-lua"
+
+	"github.com/zetamatta/nyagos/completion"
+)
+
+// luaToArg converts the value type of nyagos.complete_for: the name such as
+// "file", the table of the candidates or the function(word,args).
+func luaToArg(value lua.LValue) *completion.Arg {
+	switch v := value.(type) {
+	case lua.LString:
+		return completion.ParseArg(string(v))
+	case *lua.LTable:
+		arg := &completion.Arg{Type: completion.ValueList}
+		for i := 1; i <= v.Len(); i++ {
+			arg.Values = append(arg.Values, lua.LVAsString(v.RawGetInt(i)))
+		}
+		return arg
+	case *lua.LFunction:
+		return &completion.Arg{Type: completion.ValueFunc, Func: luaArgFunc(v)}
+	}
+	return nil
+}
+
+// luaArgFunc makes the Go function calling f(word,args) which returns
+// the table of the candidates.
+func luaArgFunc(f *lua.LFunction) func(context.Context, string, []string) []string {
+	return func(ctx context.Context, word string, args []string) []string {
+		L, ok := ctx.Value(luaKey).(Lua)
+		if !ok {
+			return nil
+		}
+		stackPos := L.GetTop()
+		defer L.SetTop(stackPos)
+		defer setContext(L, getContext(L))
+		setContext(L, ctx)
+
+		argsTable := L.NewTable()
+		for _, a := range args {
+			argsTable.Append(lua.LString(a))
+		}
+		L.Push(f)
+		L.Push(lua.LString(word))
+		L.Push(argsTable)
+		if err := L.PCall(2, 1, nil); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return nil
+		}
+		result, ok := L.Get(-1).(*lua.LTable)
+		if !ok {
+			return nil
+		}
+		list := make([]string, 0, result.Len())
+		for i := 1; i <= result.Len(); i++ {
+			list = append(list, lua.LVAsString(result.RawGetInt(i)))
+		}
+		return list
+	}
+}
+
+// luaToFlags converts the flags of nyagos.complete_for. The element is
+// "-v", ["-o"]=VALUE or {"-o","--output",value=VALUE,description=TEXT}.
+func luaToFlags(table *lua.LTable) []*completion.Flag {
+	flags := []*completion.Flag{}
+	table.ForEach(func(key, val lua.LValue) {
+		if name, ok := key.(lua.LString); ok {
+			flags = append(flags, &completion.Flag{
+				Names: []string{string(name)},
+				Value: luaToArg(val),
+			})
+			return
+		}
+		switch v := val.(type) {
+		case lua.LString:
+			flags = append(flags, &completion.Flag{Names: []string{string(v)}})
+		case *lua.LTable:
+			f := &completion.Flag{
+				Value:       luaToArg(v.RawGetString("value")),
+				Description: lua.LVAsString(v.RawGetString("description")),
+			}
+			for i := 1; i <= v.Len(); i++ {
+				f.Names = append(f.Names, lua.LVAsString(v.RawGetInt(i)))
+			}
+			if len(f.Names) > 0 {
+				flags = append(flags, f)
+			}
+		}
+	})
+	return flags
+}
+
+// luaToSpec converts the table of nyagos.complete_for to completion.Spec.
+func luaToSpec(table *lua.LTable) *completion.Spec {
+	spec := &completion.Spec{
+		Description: lua.LVAsString(table.RawGetString("description")),
+		Rest:        luaToArg(table.RawGetString("rest")),
+	}
+	if flags, ok := table.RawGetString("flags").(*lua.LTable); ok {
+		spec.Flags = luaToFlags(flags)
+	}
+	if args, ok := table.RawGetString("args").(*lua.LTable); ok {
+		for i := 1; i <= args.Len(); i++ {
+			if arg := luaToArg(args.RawGetInt(i)); arg != nil {
+				spec.Args = append(spec.Args, arg)
+			}
+		}
+	}
+	if subs, ok := table.RawGetString("subcommands").(*lua.LTable); ok {
+		subs.ForEach(func(key, val lua.LValue) {
+			if name, ok := key.(lua.LString); ok {
+				sub := spec.Subcommand(string(name), true)
+				if t, ok := val.(*lua.LTable); ok {
+					*sub = *luaToSpec(t)
+				}
+			} else {
+				spec.Subcommand(lua.LVAsString(val), true)
+			}
+		})
+	}
+	return spec
+}
+
+// cmdCompleteFor is nyagos.complete_for(NAME,SPEC), which registers SPEC
+// as the completion specification for the command NAME. nil removes it.
+func cmdCompleteFor(L Lua) int {
+	name, ok := L.Get(1).(lua.LString)
+	if !ok {
+		return lerror(L, "nyagos.complete_for: the first argument is not a string")
+	}
+	switch v := L.Get(2).(type) {
+	case *lua.LTable:
+		completion.SetSpec(string(name), luaToSpec(v))
+	case *lua.LNilType:
+		completion.SetSpec(string(name), nil)
+	default:
+		return lerror(L, "nyagos.complete_for: the second argument is not a table")
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
@@ -0,0 +1,52 @@
+package mains
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/yuin/gopher-lua"
+
+	"github.com/zetamatta/nyagos/history"
+)
+
+var orgHistoryFilter history.FilterHookT
+
+// luaHookForHistory calls nyagos.history_filter(line) before the line is
+// recorded into the history. The function returns the string to record
+// instead, false not to record it, or nil to record it as it is.
+func luaHookForHistory(ctx context.Context, line string) (string, bool) {
+	L, ok := ctx.Value(luaKey).(Lua)
+	if !ok {
+		return orgHistoryFilter(ctx, line)
+	}
+	nyagosTbl, ok := L.GetGlobal("nyagos").(*lua.LTable)
+	if !ok {
+		return orgHistoryFilter(ctx, line)
+	}
+	f, ok := L.GetField(nyagosTbl, "history_filter").(*lua.LFunction)
+	if !ok {
+		return orgHistoryFilter(ctx, line)
+	}
+
+	stackPos := L.GetTop()
+	defer L.SetTop(stackPos)
+	defer setContext(L, getContext(L))
+	setContext(L, ctx)
+
+	L.Push(f)
+	L.Push(lua.LString(line))
+	if err := L.PCall(1, 1, nil); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return orgHistoryFilter(ctx, line)
+	}
+	switch result := L.Get(-1).(type) {
+	case lua.LString:
+		line = string(result)
+	case lua.LBool:
+		if !result {
+			return "", false
+		}
+	}
+	return orgHistoryFilter(ctx, line)
+}
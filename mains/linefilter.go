@@ -14,6 +14,13 @@ type luaFilterStream struct {
 	L Lua
 }
 
+// Result passes the errorlevel to the stream filtered.
+func (lfs *luaFilterStream) Result(errorlevel int) {
+	if rs, ok := lfs.Stream.(shell.ResultStream); ok {
+		rs.Result(errorlevel)
+	}
+}
+
 func (lfs *luaFilterStream) ReadLine(ctx context.Context) (context.Context, string, error) {
 	ctx, line, err := lfs.Stream.ReadLine(ctx)
 	if err != nil {
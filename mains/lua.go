@@ -33,16 +33,30 @@ func makeVirtualTable(L Lua, getter, setter func(Lua) int) lua.LValue {
 }
 
 var stringProperty = map[string]*string{
-	"antihistquot": &history.DisableMarks,
-	"histchar":     &history.Mark,
-	"quotation":    &readline.Delimiters,
-	"version":      &frame.Version,
+	"antihistquot":  &history.DisableMarks,
+	"histchar":      &history.Mark,
+	"histignore":    &history.HistIgnore,
+	"quotation":     &readline.Delimiters,
+	"version":       &frame.Version,
+	"xtrace_prefix": &shell.XTracePrefix,
 }
 
 var boolProperty = map[string]*bool{
 	"silentmode":        &frame.SilentMode,
 	"completion_hidden": &completion.IncludeHidden,
 	"completion_slash":  &completion.UseSlash,
+	"ignoredups":        &history.IgnoreDups,
+	"ignorespace":       &history.IgnoreSpace,
+}
+
+var intProperty = map[string]*int{
+	"histfilesize": &history.HistFileSize,
+	"histsize":     &history.HistSize,
+}
+
+// intsProperty are the read-only properties which are arrays of integers.
+var intsProperty = map[string]*[]int{
+	"pipestatus": &shell.LastPipeStatus,
 }
 
 func nyagosGetter(L Lua) int {
@@ -59,6 +73,14 @@ func nyagosGetter(L Lua) int {
 		} else {
 			L.Push(lua.LFalse)
 		}
+	} else if ptr, ok := intProperty[key]; ok {
+		L.Push(lua.LNumber(*ptr))
+	} else if ptr, ok := intsProperty[key]; ok {
+		table := L.NewTable()
+		for _, value := range *ptr {
+			table.Append(lua.LNumber(value))
+		}
+		L.Push(table)
 	} else {
 		L.Push(L.RawGet(L.Get(1).(*lua.LTable), keyTmp))
 	}
@@ -85,6 +107,14 @@ func nyagosSetter(L Lua) int {
 		} else {
 			return lerror(L, fmt.Sprintf("nyagos.%s: must be boolean", key))
 		}
+	} else if ptr, ok := intProperty[key]; ok {
+		val, ok := L.Get(3).(lua.LNumber)
+		if !ok {
+			return lerror(L, fmt.Sprintf("nyagos.%s: must be number", key))
+		}
+		*ptr = int(val)
+	} else if _, ok := intsProperty[key]; ok {
+		return lerror(L, fmt.Sprintf("nyagos.%s: read-only", key))
 	} else {
 		L.RawSet(L.Get(1).(*lua.LTable), L.Get(2), L.Get(3))
 	}
@@ -142,6 +172,9 @@ func NewLua() (Lua, error) {
 	optionTable := makeVirtualTable(L, lua2cmd(functions.GetOption), lua2cmd(functions.SetOption))
 	L.SetField(nyagosTable, "option", optionTable)
 
+	highlightTable := makeVirtualTable(L, lua2cmd(functions.GetHighlight), lua2cmd(functions.SetHighlight))
+	L.SetField(nyagosTable, "highlight", highlightTable)
+
 	L.SetField(nyagosTable, "lines", L.GetField(ioTable, "lines"))
 	L.SetField(nyagosTable, "open", L.GetField(ioTable, "open"))
 	L.SetField(nyagosTable, "loadfile", L.GetGlobal("loadfile"))
@@ -151,6 +184,7 @@ func NewLua() (Lua, error) {
 	L.SetField(nyagosTable, "bindkey", L.NewFunction(cmdBindKey))
 	L.SetField(nyagosTable, "exec", L.NewFunction(cmdExec))
 	L.SetField(nyagosTable, "eval", L.NewFunction(cmdEval))
+	L.SetField(nyagosTable, "complete_for", L.NewFunction(cmdCompleteFor))
 	L.SetField(nyagosTable, "prompt", L.NewFunction(lua2param(functions.Prompt)))
 	L.SetField(nyagosTable, "create_object", L.NewFunction(CreateObject))
 	L.SetField(nyagosTable, "goarch", lua.LString(runtime.GOARCH))
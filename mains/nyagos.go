@@ -15,6 +15,7 @@ import (
 	"github.com/zetamatta/nyagos/frame"
 	"github.com/zetamatta/nyagos/functions"
 	"github.com/zetamatta/nyagos/history"
+	"github.com/zetamatta/nyagos/readline"
 	"github.com/zetamatta/nyagos/shell"
 )
 
@@ -107,6 +108,8 @@ func Main() error {
 	ctx := context.Background()
 
 	completion.HookToList = append(completion.HookToList, luaHookForComplete)
+	orgHistoryFilter = history.SetFilterHook(luaHookForHistory)
+	orgSuggestHook = readline.SetSuggestHook(luaHookForSuggest)
 
 	L, err := NewLua()
 	if err != nil {
@@ -0,0 +1,52 @@
+package mains
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/yuin/gopher-lua"
+
+	"github.com/zetamatta/nyagos/readline"
+)
+
+var orgSuggestHook readline.SuggestHookT
+
+// luaHookForSuggest calls nyagos.suggest_hook(text) to get the line to
+// suggest. The function returns the whole line, false to suggest nothing,
+// or nil to suggest from the history and the completion.
+func luaHookForSuggest(ctx context.Context, this *readline.Buffer, text string) string {
+	L, ok := ctx.Value(luaKey).(Lua)
+	if !ok {
+		return orgSuggestHook(ctx, this, text)
+	}
+	nyagosTbl, ok := L.GetGlobal("nyagos").(*lua.LTable)
+	if !ok {
+		return orgSuggestHook(ctx, this, text)
+	}
+	f, ok := L.GetField(nyagosTbl, "suggest_hook").(*lua.LFunction)
+	if !ok {
+		return orgSuggestHook(ctx, this, text)
+	}
+
+	stackPos := L.GetTop()
+	defer L.SetTop(stackPos)
+	defer setContext(L, getContext(L))
+	setContext(L, ctx)
+
+	L.Push(f)
+	L.Push(lua.LString(text))
+	if err := L.PCall(1, 1, nil); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return orgSuggestHook(ctx, this, text)
+	}
+	switch result := L.Get(-1).(type) {
+	case lua.LString:
+		return string(result)
+	case lua.LBool:
+		if !result {
+			return ""
+		}
+	}
+	return orgSuggestHook(ctx, this, text)
+}
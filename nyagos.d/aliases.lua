@@ -48,6 +48,8 @@ nyagos.alias.kill = function(args)
     for i=1,#args do
         if args[i] == "-f" then
             command="taskkill.exe /F"
+        elseif string.sub(args[i],1,1) == "%" then
+            nyagos.exec("__kill__ " .. args[i])
         else
             nyagos.exec(command .. " /PID " .. args[i])
         end
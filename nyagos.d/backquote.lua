@@ -27,6 +27,5 @@ nyagos.filter = function(cmdline)
         end
     end
     cmdline = cmdline:gsub('`[^`]*`',backquote.replace)
-    cmdline = cmdline:gsub('%$(%b())',backquote.replace)
     return cmdline
 end
@@ -0,0 +1,107 @@
+package readline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// displayWidth returns the width of s excluding ESCAPE SEQUENCEs.
+func displayWidth(s string) int {
+	width := 0
+	escape := false
+	for _, ch := range s {
+		if escape {
+			if ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') {
+				escape = false
+			}
+		} else if ch == '\x1B' {
+			escape = true
+		} else {
+			width += GetCharWidth(ch)
+		}
+	}
+	return width
+}
+
+// BoxPrint prints nodes in columns. It returns false when ctx is canceled.
+func BoxPrint(ctx context.Context, nodes []string, out io.Writer) bool {
+	if len(nodes) <= 0 {
+		return true
+	}
+	screenWidth, _ := GetViewSize()
+	maxWidth := 1
+	for _, node := range nodes {
+		if w := displayWidth(node); w > maxWidth {
+			maxWidth = w
+		}
+	}
+	cols := (screenWidth - 1) / (maxWidth + 1)
+	if cols < 1 {
+		cols = 1
+	}
+	rows := (len(nodes) + cols - 1) / cols
+	var line strings.Builder
+	for row := 0; row < rows; row++ {
+		if ctx != nil {
+			select {
+			case <-ctx.Done():
+				return false
+			default:
+			}
+		}
+		line.Reset()
+		for col := 0; col < cols; col++ {
+			i := col*rows + row
+			if i >= len(nodes) {
+				break
+			}
+			line.WriteString(nodes[i])
+			if (col+1)*rows+row < len(nodes) {
+				line.WriteString(strings.Repeat(" ", maxWidth+1-displayWidth(nodes[i])))
+			}
+		}
+		line.WriteByte('\n')
+		io.WriteString(out, line.String())
+	}
+	return true
+}
+
+// BoxChoice prints sources with numbers and returns the one
+// whose number is typed. It returns "" when canceled.
+func BoxChoice(sources []string, out io.Writer) string {
+	if len(sources) <= 0 {
+		return ""
+	}
+	nodes := make([]string, len(sources))
+	for i, s := range sources {
+		nodes[i] = fmt.Sprintf("%d) %s", i+1, s)
+	}
+	BoxPrint(nil, nodes, out)
+	number := []rune{}
+	for {
+		fmt.Fprintf(out, "\r\x1B[K#? %s", string(number))
+		switch ch := GetRune(); ch {
+		case '\r', '\n':
+			fmt.Fprintln(out)
+			n, err := strconv.Atoi(string(number))
+			if err != nil || n < 1 || n > len(sources) {
+				return ""
+			}
+			return sources[n-1]
+		case '\b':
+			if len(number) > 0 {
+				number = number[:len(number)-1]
+			}
+		case '\x1B', 'G' & 0x1F, 'C' & 0x1F:
+			fmt.Fprintln(out)
+			return ""
+		default:
+			if '0' <= ch && ch <= '9' {
+				number = append(number, ch)
+			}
+		}
+	}
+}
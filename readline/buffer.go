@@ -10,6 +10,14 @@ import (
 var hasCache = map[rune]struct{}{}
 
 func (this *Buffer) PutRune(ch rune) {
+	if this.multiLine {
+		return
+	}
+	this.colorsStale = true
+	this.putRune(ch)
+}
+
+func (this *Buffer) putRune(ch rune) {
 	if ch < ' ' {
 		this.Writer.WriteByte('^')
 		this.Writer.WriteByte(byte('A' + (ch - 1)))
@@ -19,7 +27,7 @@ func (this *Buffer) PutRune(ch rune) {
 }
 
 func (this *Buffer) PutRunes(ch rune, n int) {
-	if n <= 0 {
+	if n <= 0 || this.multiLine {
 		return
 	}
 	this.PutRune(ch)
@@ -29,6 +37,9 @@ func (this *Buffer) PutRunes(ch rune, n int) {
 }
 
 func (this *Buffer) Backspace(n int) {
+	if this.multiLine {
+		return
+	}
 	if n > 1 {
 		fmt.Fprintf(this.Writer, "\x1B[%dD", n)
 	} else if n == 1 {
@@ -37,6 +48,9 @@ func (this *Buffer) Backspace(n int) {
 }
 
 func (this *Buffer) Eraseline() {
+	if this.multiLine {
+		return
+	}
 	io.WriteString(this.Writer, "\x1B[0K")
 }
 
@@ -53,9 +67,23 @@ type Buffer struct {
 	TermWidth      int // == TopColumn + ViewWidth + FORBIDDEN_WIDTH
 	TopColumn      int // == width of Prompt
 	HistoryPointer int
+	dirHistory     []int // the order of KeyFuncDirHistoryUp
+	dirHistoryPos  int
+	editingLine    string   // the line before KeyFuncHistorySearchBackward
+	multiLine      bool     // see MultiLine
+	cursorRow      int      // the row of the cursor from the prompt in multiLine
+	lastRow        int      // the last row drawn in multiLine
+	suggestion     string   // the rest of the line suggested, see AutoSuggest
+	colors         []string // the color of each rune, see SyntaxHighlight
+	colorsText     string   // the text which colors are made for
+	colorsStale    bool     // the runes on the screen are not colored
 }
 
 func (this *Buffer) ViewWidth() int {
+	if this.multiLine {
+		// never scroll horizontally
+		return multiLineViewWidth
+	}
 	return this.TermWidth - this.TopColumn - FORBIDDEN_WIDTH
 }
 
@@ -105,6 +133,9 @@ func (this *Buffer) InsertAndRepaint(str string) {
 
 func (this *Buffer) ResetViewStart() {
 	this.ViewStart = 0
+	if this.multiLine {
+		return
+	}
 	w := 0
 	for i := 0; i <= this.Cursor; i++ {
 		w += GetCharWidth(this.Buffer[i])
@@ -161,6 +192,9 @@ func (this *Buffer) GetWidthBetween(from int, to int) int {
 }
 
 func (this *Buffer) Repaint(pos int, del int) {
+	if this.multiLine {
+		return
+	}
 	bs := 0
 	vp := this.GetWidthBetween(this.ViewStart, pos)
 
@@ -183,6 +217,10 @@ func (this *Buffer) Repaint(pos int, del int) {
 }
 
 func (this *Buffer) RepaintAfterPrompt() {
+	if this.multiLine {
+		this.repaintMultiLine()
+		return
+	}
 	this.ResetViewStart()
 	for i := this.ViewStart; i < this.Cursor; i++ {
 		this.PutRune(this.Buffer[i])
@@ -193,6 +231,7 @@ func (this *Buffer) RepaintAfterPrompt() {
 func (this *Buffer) RepaintAll() {
 	this.Writer.Flush()
 	this.TopColumn, _ = this.Prompt()
+	this.cursorRow = 0
 	this.RepaintAfterPrompt()
 }
 
@@ -0,0 +1,52 @@
+package readline
+
+// Shift states of KeyEvent (same as dwControlKeyState of Win32's KEY_EVENT_RECORD)
+const (
+	RIGHT_ALT_PRESSED  = 1
+	LEFT_ALT_PRESSED   = 2
+	RIGHT_CTRL_PRESSED = 4
+	LEFT_CTRL_PRESSED  = 8
+	SHIFT_PRESSED      = 16
+
+	ALT_PRESSED  = RIGHT_ALT_PRESSED | LEFT_ALT_PRESSED
+	CTRL_PRESSED = RIGHT_CTRL_PRESSED | LEFT_CTRL_PRESSED
+)
+
+// KeyEvent is a key typed on the console.
+// Scan is the virtual keycode of Windows.
+type KeyEvent struct {
+	Rune  rune
+	Scan  uint16
+	Shift uint32
+}
+
+// ResizeEvent is the new size of the console window.
+type ResizeEvent struct {
+	Width  uint
+	Height uint
+}
+
+// ConsoleEvent is the event from the console: key or resize.
+type ConsoleEvent struct {
+	Key    *KeyEvent
+	Resize *ResizeEvent
+}
+
+// GetKey waits a key and returns its character, keycode and shift state.
+func GetKey() (rune, uint16, uint32) {
+	for {
+		e := GetConsoleEvent()
+		if e.Key != nil {
+			return e.Key.Rune, e.Key.Scan, e.Key.Shift
+		}
+	}
+}
+
+// GetRune waits a key which has a character and returns it.
+func GetRune() rune {
+	for {
+		if ch, _, _ := GetKey(); ch != 0 {
+			return ch
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package readline
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unicode/utf8"
+
+	"golang.org/x/sys/unix"
+)
+
+const stdinFd = 0
+
+// escTimeout is the milliseconds to wait the rest of an escape sequence.
+const escTimeout = 50
+
+var (
+	keyBuffer   []byte
+	rawDepth    int
+	resizePipe  [2]int
+	resizeOnce  sync.Once
+	ctrlCOnce   sync.Once
+	ctrlCNumber int32
+)
+
+func watchResize() {
+	if err := unix.Pipe2(resizePipe[:], unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
+		resizePipe[0] = -1
+		return
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGWINCH)
+	go func() {
+		for range ch {
+			unix.Write(resizePipe[1], []byte{0})
+		}
+	}()
+}
+
+// enterRawMode makes the terminal raw and returns the function to restore.
+// OPOST is kept so that "\n" is still printed as CRLF.
+func enterRawMode() func() {
+	if rawDepth > 0 {
+		rawDepth++
+		return func() { rawDepth-- }
+	}
+	orig, err := unix.IoctlGetTermios(stdinFd, unix.TCGETS)
+	if err != nil {
+		return func() {}
+	}
+	raw := *orig
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP |
+		unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(stdinFd, unix.TCSETS, &raw); err != nil {
+		return func() {}
+	}
+	rawDepth = 1
+	return func() {
+		rawDepth = 0
+		unix.IoctlSetTermios(stdinFd, unix.TCSETS, orig)
+	}
+}
+
+// readConsole appends the bytes from STDIN to keyBuffer.
+// timeout < 0 means to wait forever.
+func readConsole(timeout int) (resized bool, err error) {
+	fds := []unix.PollFd{{Fd: stdinFd, Events: unix.POLLIN}}
+	if resizePipe[0] > 0 {
+		fds = append(fds, unix.PollFd{Fd: int32(resizePipe[0]), Events: unix.POLLIN})
+	}
+	for {
+		n, err := unix.Poll(fds, timeout)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil || n == 0 {
+			return false, err
+		}
+		if len(fds) > 1 && (fds[1].Revents&unix.POLLIN) != 0 {
+			var dummy [16]byte
+			unix.Read(resizePipe[0], dummy[:])
+			return true, nil
+		}
+		var buffer [256]byte
+		n, err = unix.Read(stdinFd, buffer[:])
+		if err == unix.EINTR || err == unix.EAGAIN {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if n <= 0 {
+			return false, io.EOF
+		}
+		keyBuffer = append(keyBuffer, buffer[:n]...)
+		return false, nil
+	}
+}
+
+var csiTilde = map[int]uint16{
+	1:  0x24, // HOME
+	2:  0x2D, // INSERT
+	3:  0x2E, // DELETE
+	4:  0x23, // END
+	5:  0x21, // PAGEUP
+	6:  0x22, // PAGEDOWN
+	7:  0x24, // HOME
+	8:  0x23, // END
+	11: 0x70, // F1
+	12: 0x71, // F2
+	13: 0x72, // F3
+	14: 0x73, // F4
+	15: 0x74, // F5
+	17: 0x75, // F6
+	18: 0x76, // F7
+	19: 0x77, // F8
+	20: 0x78, // F9
+	21: 0x79, // F10
+	23: 0x7A, // F11
+	24: 0x7B, // F12
+}
+
+var csiFinal = map[byte]uint16{
+	'A': 0x26, // UP
+	'B': 0x28, // DOWN
+	'C': 0x27, // RIGHT
+	'D': 0x25, // LEFT
+	'H': 0x24, // HOME
+	'F': 0x23, // END
+	'P': 0x70, // F1
+	'Q': 0x71, // F2
+	'R': 0x72, // F3
+	'S': 0x73, // F4
+}
+
+// xtermModifier converts the modifier parameter of xterm to the shift state.
+func xtermModifier(m int) uint32 {
+	var shift uint32
+	m--
+	if (m & 1) != 0 {
+		shift |= SHIFT_PRESSED
+	}
+	if (m & 2) != 0 {
+		shift |= LEFT_ALT_PRESSED
+	}
+	if (m & 4) != 0 {
+		shift |= LEFT_CTRL_PRESSED
+	}
+	return shift
+}
+
+// decodeCSI decodes `ESC [ ...` or `ESC O ...`.
+// n == 0 means that the sequence is not completed yet.
+func decodeCSI(buffer []byte) (key *KeyEvent, n int) {
+	var params []int
+	current := -1
+	for i := 2; i < len(buffer); i++ {
+		c := buffer[i]
+		switch {
+		case '0' <= c && c <= '9':
+			if current < 0 {
+				current = 0
+			}
+			current = current*10 + int(c-'0')
+		case c == ';':
+			params = append(params, current)
+			current = -1
+		case 0x40 <= c && c <= 0x7E:
+			if current >= 0 {
+				params = append(params, current)
+			}
+			if c == 'Z' {
+				// Shift-Tab
+				return &KeyEvent{Rune: '\t', Scan: '\t', Shift: SHIFT_PRESSED}, i + 1
+			}
+			var scan uint16
+			var ok bool
+			if c == '~' && len(params) > 0 {
+				scan, ok = csiTilde[params[0]]
+			} else {
+				scan, ok = csiFinal[c]
+			}
+			if !ok {
+				return nil, i + 1
+			}
+			var shift uint32
+			if len(params) >= 2 {
+				shift = xtermModifier(params[1])
+			}
+			return &KeyEvent{Scan: scan, Shift: shift}, i + 1
+		default:
+			// intermediate bytes
+		}
+	}
+	return nil, 0
+}
+
+// altScan returns the virtual keycode for Alt+ch.
+func altScan(ch rune) uint16 {
+	switch {
+	case 'a' <= ch && ch <= 'z':
+		return uint16(ch - 'a' + 'A')
+	case 'A' <= ch && ch <= 'Z', '0' <= ch && ch <= '9':
+		return uint16(ch)
+	case ch == '\x7F' || ch == '\b':
+		return 0x08
+	case ch == '/':
+		return 0xBF
+	}
+	return 0
+}
+
+// decodeKey decodes the first key in buffer.
+// n == 0 means that the key is not completed yet.
+// key == nil and n > 0 means that the bytes should be skipped.
+func decodeKey(buffer []byte) (key *KeyEvent, n int) {
+	if len(buffer) <= 0 {
+		return nil, 0
+	}
+	if buffer[0] == '\x1B' {
+		if len(buffer) < 2 {
+			return nil, 0
+		}
+		if buffer[1] == '[' || buffer[1] == 'O' {
+			return decodeCSI(buffer)
+		}
+		if !utf8.FullRune(buffer[1:]) {
+			return nil, 0
+		}
+		ch, size := utf8.DecodeRune(buffer[1:])
+		return &KeyEvent{Rune: ch, Scan: altScan(ch), Shift: LEFT_ALT_PRESSED}, size + 1
+	}
+	if !utf8.FullRune(buffer) {
+		return nil, 0
+	}
+	ch, size := utf8.DecodeRune(buffer)
+	switch {
+	case ch == '\x7F':
+		return &KeyEvent{Rune: '\b', Scan: 0x08}, size
+	case ch == '\r':
+		return &KeyEvent{Rune: ch, Scan: 0x0D}, size
+	case ch == '\t' || ch == '\b' || ch == '\x1B':
+		return &KeyEvent{Rune: ch, Scan: uint16(ch)}, size
+	case 0 < ch && ch < 0x20:
+		return &KeyEvent{Rune: ch, Scan: uint16(ch + 'A' - 1), Shift: LEFT_CTRL_PRESSED}, size
+	}
+	return &KeyEvent{Rune: ch}, size
+}
+
+// GetConsoleEvent waits an event from the terminal.
+func GetConsoleEvent() ConsoleEvent {
+	resizeOnce.Do(watchResize)
+	defer enterRawMode()()
+	for {
+		if len(keyBuffer) > 0 {
+			key, n := decodeKey(keyBuffer)
+			if n > 0 {
+				keyBuffer = keyBuffer[n:]
+				if key != nil {
+					return ConsoleEvent{Key: key}
+				}
+				continue
+			}
+			// the rest of the sequence has not arrived yet.
+			size := len(keyBuffer)
+			resized, err := readConsole(escTimeout)
+			if resized {
+				return resizeEvent()
+			}
+			if err == nil && len(keyBuffer) > size {
+				continue
+			}
+			// timeout: the lone ESC or the broken sequence
+			ch := rune(keyBuffer[0])
+			keyBuffer = keyBuffer[1:]
+			return ConsoleEvent{Key: &KeyEvent{Rune: ch, Scan: uint16(ch)}}
+		}
+		resized, err := readConsole(-1)
+		if resized {
+			return resizeEvent()
+		}
+		if err != nil {
+			// STDIN is closed: behave as Ctrl-D
+			return ConsoleEvent{Key: &KeyEvent{Rune: 'D' & 0x1F, Scan: 'D', Shift: LEFT_CTRL_PRESSED}}
+		}
+	}
+}
+
+func resizeEvent() ConsoleEvent {
+	width, height := GetViewSize()
+	return ConsoleEvent{Resize: &ResizeEvent{Width: uint(width), Height: uint(height)}}
+}
+
+// FlushConsoleInput discards keys typed ahead.
+func FlushConsoleInput() {
+	keyBuffer = nil
+	unix.IoctlSetInt(stdinFd, unix.TCFLSH, unix.TCIFLUSH)
+}
+
+// DisableCtrlC prevents the process from being terminated by SIGINT.
+func DisableCtrlC() {
+	ctrlCOnce.Do(func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, os.Interrupt)
+		go func() {
+			for range ch {
+				atomic.AddInt32(&ctrlCNumber, 1)
+			}
+		}()
+	})
+}
+
+// IsCtrlCPressed returns true if SIGINT was received since the last call.
+func IsCtrlCPressed() bool {
+	return atomic.SwapInt32(&ctrlCNumber, 0) > 0
+}
+
+// GetViewSize returns the width and height of the terminal.
+func GetViewSize() (int, int) {
+	for _, fd := range []int{1, 2, stdinFd} {
+		if ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ); err == nil && ws.Col > 0 {
+			return int(ws.Col), int(ws.Row)
+		}
+	}
+	return 80, 25
+}
@@ -0,0 +1,63 @@
+package readline
+
+import (
+	"context"
+	"io"
+
+	"github.com/zetamatta/go-box"
+	"github.com/zetamatta/go-getch"
+)
+
+// GetConsoleEvent waits an event from the console.
+func GetConsoleEvent() ConsoleEvent {
+	e := getch.All()
+	var result ConsoleEvent
+	if e.Key != nil {
+		result.Key = &KeyEvent{
+			Rune:  e.Key.Rune,
+			Scan:  e.Key.Scan,
+			Shift: e.Key.Shift,
+		}
+	}
+	if e.Resize != nil {
+		result.Resize = &ResizeEvent{
+			Width:  e.Resize.Width,
+			Height: e.Resize.Height,
+		}
+	}
+	return result
+}
+
+// FlushConsoleInput discards keys typed ahead.
+func FlushConsoleInput() {
+	getch.Flush()
+}
+
+// DisableCtrlC prevents the process from being terminated by Ctrl-C.
+func DisableCtrlC() {
+	getch.DisableCtrlC()
+}
+
+// IsCtrlCPressed returns true if Ctrl-C was typed.
+func IsCtrlCPressed() bool {
+	return getch.IsCtrlCPressed()
+}
+
+// GetViewSize returns the width and height of the console window.
+func GetViewSize() (int, int) {
+	return box.GetScreenBufferInfo().ViewSize()
+}
+
+// BoxPrint prints nodes in columns. It returns false when ctx is canceled.
+func BoxPrint(ctx context.Context, nodes []string, out io.Writer) bool {
+	return box.Print(ctx, nodes, out)
+}
+
+// BoxChoice lets the user select one of sources and returns it.
+func BoxChoice(sources []string, out io.Writer) string {
+	return box.Choice(sources, out)
+}
+
+func enterRawMode() func() {
+	return func() {}
+}
@@ -112,12 +112,19 @@ const (
 	F_DELETE_OR_ABORT      = "DELETE_OR_ABORT"
 	F_END_OF_LINE          = "END_OF_LINE"
 	F_FORWARD_CHAR         = "FORWARD_CHAR"
+	F_FORWARD_WORD         = "FORWARD_WORD"
+	F_FUZZY_HISTORY        = "FUZZY_HISTORY"
 	F_HISTORY_DOWN         = "HISTORY_DOWN" // for compatible
 	F_HISTORY_UP           = "HISTORY_UP"   // for compatible
+	F_HISTORY_SEARCH_BACK  = "HISTORY_SEARCH_BACKWARD"
+	F_HISTORY_SEARCH_FWD   = "HISTORY_SEARCH_FORWARD"
+	F_NEXT_DIR_HISTORY     = "NEXT_DIR_HISTORY"
 	F_NEXT_HISTORY         = "NEXT_HISTORY"
+	F_PREVIOUS_DIR_HISTORY = "PREVIOUS_DIR_HISTORY"
 	F_PREVIOUS_HISTORY     = "PREVIOUS_HISTORY"
 	F_INTR                 = "INTR"
 	F_ISEARCH_BACKWARD     = "ISEARCH_BACKWARD"
+	F_ISEARCH_FORWARD      = "ISEARCH_FORWARD"
 	F_KILL_LINE            = "KILL_LINE"
 	F_KILL_WHOLE_LINE      = "KILL_WHOLE_LINE"
 	F_PASS                 = "PASS"
@@ -247,14 +254,18 @@ var NAME2FUNC = map[string]func(context.Context, *Buffer) Result{
 	F_CLEAR_SCREEN:         KeyFuncCLS,
 	F_DELETE_CHAR:          KeyFuncDelete,
 	F_DELETE_OR_ABORT:      KeyFuncDeleteOrAbort,
-	F_END_OF_LINE:          KeyFuncTail,
-	F_FORWARD_CHAR:         KeyFuncForward,
+	F_END_OF_LINE:          KeyFuncTailOrAccept,
+	F_FORWARD_CHAR:         KeyFuncForwardOrAccept,
+	F_FUZZY_HISTORY:        KeyFuncFuzzyHistory,
 	F_HISTORY_DOWN:         KeyFuncHistoryDown, // for compatible
 	F_HISTORY_UP:           KeyFuncHistoryUp,   // for compatible
+	F_HISTORY_SEARCH_BACK:  KeyFuncHistorySearchBackward,
+	F_HISTORY_SEARCH_FWD:   KeyFuncHistorySearchForward,
+	F_NEXT_DIR_HISTORY:     KeyFuncDirHistoryDown,
 	F_NEXT_HISTORY:         KeyFuncHistoryDown,
+	F_PREVIOUS_DIR_HISTORY: KeyFuncDirHistoryUp,
 	F_PREVIOUS_HISTORY:     KeyFuncHistoryUp,
 	F_INTR:                 KeyFuncIntr,
-	F_ISEARCH_BACKWARD:     KeyFuncIncSearch,
 	F_KILL_LINE:            KeyFuncClearAfter,
 	F_KILL_WHOLE_LINE:      KeyFuncClear,
 	F_PASS:                 nil,
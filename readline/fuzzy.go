@@ -0,0 +1,397 @@
+package readline
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// the scores of FuzzyMatch
+const (
+	fuzzyScoreMatch       = 16
+	fuzzyBonusBoundary    = 8 // the top of the text or the word
+	fuzzyBonusCamel       = 6 // the upper case following the lower case
+	fuzzyBonusConsecutive = 8 // the character following the one matched
+	fuzzyPenaltyGap       = 1 // each character skipped between matches
+	fuzzyMaxLength        = 1024
+)
+
+const fuzzyMinScore = -1 << 30
+
+func isFuzzySeparator(ch rune) bool {
+	return unicode.IsSpace(ch) || strings.ContainsRune(`/\-_.:;,=|&`, ch)
+}
+
+// fuzzyBonus returns the bonus to match text[j].
+func fuzzyBonus(text []rune, j int) int {
+	if j == 0 || isFuzzySeparator(text[j-1]) {
+		return fuzzyBonusBoundary
+	}
+	if unicode.IsUpper(text[j]) && unicode.IsLower(text[j-1]) {
+		return fuzzyBonusCamel
+	}
+	return 0
+}
+
+// FuzzyMatch returns the score how well text matches pattern and the
+// positions (rune index) of the characters matched. All characters of
+// pattern must appear in text in the order. The case is ignored unless
+// pattern has upper case letters. ok is false when text does not match.
+func FuzzyMatch(pattern, text string) (score int, positions []int, ok bool) {
+	if pattern == "" {
+		return 0, nil, true
+	}
+	p := []rune(pattern)
+	t := []rune(text)
+	if len(t) > fuzzyMaxLength {
+		t = t[:fuzzyMaxLength]
+	}
+	n, m := len(p), len(t)
+	if n > m {
+		return 0, nil, false
+	}
+	equal := func(a, b rune) bool { return a == b }
+	if strings.ToLower(pattern) == pattern {
+		equal = func(a, b rune) bool { return a == unicode.ToLower(b) }
+	}
+	// quick check of the order
+	i := 0
+	for j := 0; j < m && i < n; j++ {
+		if equal(p[i], t[j]) {
+			i++
+		}
+	}
+	if i < n {
+		return 0, nil, false
+	}
+
+	// M[i][j]: the best score when p[i] matches t[j]
+	// D[i][j]: the best score when p[:i+1] matches t[:j+1]
+	M := make([][]int, n)
+	D := make([][]int, n)
+	for i := 0; i < n; i++ {
+		M[i] = make([]int, m)
+		D[i] = make([]int, m)
+		for j := 0; j < m; j++ {
+			M[i][j] = fuzzyMinScore
+			if equal(p[i], t[j]) {
+				if i == 0 {
+					M[i][j] = fuzzyScoreMatch + fuzzyBonus(t, j)
+				} else if j > 0 {
+					best := fuzzyMinScore
+					if D[i-1][j-1] > fuzzyMinScore {
+						best = D[i-1][j-1] + fuzzyScoreMatch + fuzzyBonus(t, j)
+					}
+					if M[i-1][j-1] > fuzzyMinScore {
+						bonus := fuzzyBonus(t, j)
+						if bonus < fuzzyBonusConsecutive {
+							bonus = fuzzyBonusConsecutive
+						}
+						if s := M[i-1][j-1] + fuzzyScoreMatch + bonus; s > best {
+							best = s
+						}
+					}
+					M[i][j] = best
+				}
+			}
+			D[i][j] = M[i][j]
+			if j > 0 && D[i][j-1] > fuzzyMinScore {
+				gap := fuzzyPenaltyGap
+				if i == n-1 {
+					gap = 0 // the characters after the last match
+				}
+				if s := D[i][j-1] - gap; s > D[i][j] {
+					D[i][j] = s
+				}
+			}
+		}
+	}
+	score = D[n-1][m-1]
+	if score <= fuzzyMinScore {
+		return 0, nil, false
+	}
+
+	positions = make([]int, n)
+	j := m - 1
+	consecutive := false
+	for i := n - 1; i >= 0; i-- {
+		for ; j >= 0; j-- {
+			if M[i][j] > fuzzyMinScore && (consecutive || M[i][j] == D[i][j]) {
+				consecutive = i > 0 && j > 0 && M[i-1][j-1] > fuzzyMinScore &&
+					M[i][j] > D[i-1][j-1]+fuzzyScoreMatch+fuzzyBonus(t, j)
+				positions[i] = j
+				j--
+				break
+			}
+		}
+	}
+	return score, positions, true
+}
+
+// fuzzyResult is a candidate matched.
+type fuzzyResult struct {
+	index     int
+	score     int
+	positions []int
+}
+
+// fuzzyFilter returns the candidates matching pattern in the order of
+// the score. The ties are kept in the original order.
+func fuzzyFilter(candidates []string, pattern string) []fuzzyResult {
+	results := make([]fuzzyResult, 0, len(candidates))
+	for i, c := range candidates {
+		if score, positions, ok := FuzzyMatch(pattern, c); ok {
+			results = append(results, fuzzyResult{index: i, score: score, positions: positions})
+		}
+	}
+	if pattern != "" {
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].score > results[j].score
+		})
+	}
+	return results
+}
+
+// FuzzyFinder is the full-screen widget to select items from Candidates
+// by typing a part of them.
+type FuzzyFinder struct {
+	Candidates []string
+	Prompt     string // "> " when empty
+	Query      string // the initial query
+	Multi      bool   // Tab marks the items to select more than one
+
+	results  []fuzzyResult
+	cursor   int
+	offset   int // the index of results at the bottom of the list
+	selected map[int]bool
+	width    int
+	height   int
+}
+
+// the escape sequences for FuzzyFinder
+const (
+	fuzzyEnterScreen = "\x1B[?1049h"
+	fuzzyLeaveScreen = "\x1B[?1049l"
+	fuzzyHighlight   = "\x1B[32;1m"
+	fuzzyCurrentLine = "\x1B[7m"
+	fuzzyResetColor  = "\x1B[0m"
+)
+
+// draw prints the list from the bottom to the top above the query line
+// at the bottom of the screen, as the best match is nearest to the query.
+func (f *FuzzyFinder) draw(w *bufio.Writer, query []rune) {
+	io.WriteString(w, CURSOR_OFF)
+	io.WriteString(w, "\x1B[H")
+	rows := f.height - 1
+	if f.cursor < f.offset {
+		f.offset = f.cursor
+	} else if f.cursor >= f.offset+rows {
+		f.offset = f.cursor - rows + 1
+	}
+	for row := rows - 1; row >= 0; row-- {
+		io.WriteString(w, "\x1B[2K")
+		if i := f.offset + row; i < len(f.results) {
+			f.drawItem(w, i)
+		}
+		io.WriteString(w, "\r\n")
+	}
+	prompt := f.Prompt
+	if prompt == "" {
+		prompt = "> "
+	}
+	fmt.Fprintf(w, "\x1B[2K%s%s  %d/%d", prompt, string(query), len(f.results), len(f.Candidates))
+	if len(f.selected) > 0 {
+		fmt.Fprintf(w, " (%d)", len(f.selected))
+	}
+	column := 0
+	for _, ch := range prompt + string(query) {
+		column += GetCharWidth(ch)
+	}
+	fmt.Fprintf(w, "\x1B[%d;%dH", f.height, column+1)
+	io.WriteString(w, CURSOR_ON)
+	w.Flush()
+}
+
+func (f *FuzzyFinder) drawItem(w *bufio.Writer, i int) {
+	result := f.results[i]
+	base := ""
+	if i == f.cursor {
+		base = fuzzyCurrentLine
+		io.WriteString(w, base+">")
+	} else {
+		io.WriteString(w, " ")
+	}
+	if f.selected[result.index] {
+		io.WriteString(w, "*")
+	} else {
+		io.WriteString(w, " ")
+	}
+	matched := map[int]bool{}
+	for _, pos := range result.positions {
+		matched[pos] = true
+	}
+	width := 2
+	for j, ch := range []rune(f.Candidates[result.index]) {
+		if ch < ' ' {
+			ch = ' '
+		}
+		w1 := GetCharWidth(ch)
+		if width+w1 >= f.width {
+			break
+		}
+		if matched[j] {
+			io.WriteString(w, fuzzyHighlight)
+			w.WriteRune(ch)
+			io.WriteString(w, fuzzyResetColor+base)
+		} else {
+			w.WriteRune(ch)
+		}
+		width += w1
+	}
+	io.WriteString(w, fuzzyResetColor)
+}
+
+// moveCursor moves the cursor n items upward on the screen.
+func (f *FuzzyFinder) moveCursor(n int) {
+	f.cursor += n
+	if f.cursor >= len(f.results) {
+		f.cursor = len(f.results) - 1
+	}
+	if f.cursor < 0 {
+		f.cursor = 0
+	}
+}
+
+// Run shows the widget on out and returns the indexes of Candidates
+// selected. It returns CtrlC when it is canceled.
+func (f *FuzzyFinder) Run(out io.Writer) ([]int, error) {
+	w, ok := out.(*bufio.Writer)
+	if !ok {
+		w = bufio.NewWriter(out)
+	}
+	defer enterRawMode()()
+
+	io.WriteString(w, fuzzyEnterScreen)
+	defer func() {
+		io.WriteString(w, fuzzyLeaveScreen)
+		w.Flush()
+	}()
+
+	query := []rune(f.Query)
+	f.selected = map[int]bool{}
+	f.cursor, f.offset = 0, 0
+	f.results = fuzzyFilter(f.Candidates, string(query))
+	f.width, f.height = GetViewSize()
+	for {
+		f.draw(w, query)
+		e := GetConsoleEvent()
+		if e.Resize != nil {
+			f.width, f.height = int(e.Resize.Width), int(e.Resize.Height)
+			continue
+		}
+		if e.Key == nil || (e.Key.Shift&ALT_PRESSED) != 0 {
+			continue
+		}
+		oldQuery := string(query)
+		switch e.Key.Rune {
+		case '\r':
+			if len(f.selected) > 0 {
+				indexes := make([]int, 0, len(f.selected))
+				for i := range f.selected {
+					indexes = append(indexes, i)
+				}
+				sort.Ints(indexes)
+				return indexes, nil
+			}
+			if f.cursor < len(f.results) {
+				return []int{f.results[f.cursor].index}, nil
+			}
+			return nil, CtrlC
+		case '\x1B', 'C' & 0x1F, 'G' & 0x1F:
+			return nil, CtrlC
+		case '\b':
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+		case 'U' & 0x1F:
+			query = query[:0]
+		case 'W' & 0x1F:
+			i := len(query)
+			for i > 0 && unicode.IsSpace(query[i-1]) {
+				i--
+			}
+			for i > 0 && !unicode.IsSpace(query[i-1]) {
+				i--
+			}
+			query = query[:i]
+		case 'P' & 0x1F, 'K' & 0x1F:
+			f.moveCursor(1)
+		case 'N' & 0x1F, 'J' & 0x1F:
+			f.moveCursor(-1)
+		case '\t':
+			if f.Multi && f.cursor < len(f.results) {
+				index := f.results[f.cursor].index
+				if f.selected[index] {
+					delete(f.selected, index)
+				} else {
+					f.selected[index] = true
+				}
+				f.moveCursor(1)
+			}
+		case 0:
+			rows := f.height - 1
+			switch e.Key.Scan {
+			case name2scan[K_UP]:
+				f.moveCursor(1)
+			case name2scan[K_DOWN]:
+				f.moveCursor(-1)
+			case name2scan[K_PAGEUP]:
+				f.moveCursor(rows)
+			case name2scan[K_PAGEDOWN]:
+				f.moveCursor(-rows)
+			}
+		default:
+			if !unicode.IsControl(e.Key.Rune) {
+				query = append(query, e.Key.Rune)
+			}
+		}
+		if string(query) != oldQuery {
+			f.results = fuzzyFilter(f.Candidates, string(query))
+			f.cursor, f.offset = 0, 0
+		}
+	}
+}
+
+// KeyFuncFuzzyHistory selects commands from the history with FuzzyFinder.
+// The commands selected with Tab are joined with `;`.
+func KeyFuncFuzzyHistory(ctx context.Context, this *Buffer) Result {
+	candidates := []string{}
+	found := map[string]bool{}
+	for i := this.History.Len() - 1; i >= 0; i-- {
+		line := this.History.At(i)
+		if !found[line] {
+			found[line] = true
+			candidates = append(candidates, line)
+		}
+	}
+	finder := &FuzzyFinder{
+		Candidates: candidates,
+		Query:      this.String(),
+		Multi:      true,
+	}
+	indexes, err := finder.Run(this.Writer)
+	if err != nil || len(indexes) <= 0 {
+		return CONTINUE
+	}
+	// candidates are the newest first, but lines are in the order executed.
+	lines := make([]string, len(indexes))
+	for i, index := range indexes {
+		lines[len(indexes)-1-i] = candidates[index]
+	}
+	this.replaceLine(ctx, strings.Join(lines, "; "))
+	return CONTINUE
+}
@@ -0,0 +1,85 @@
+package readline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		text      string
+		ok        bool
+		positions []int
+	}{
+		{"", "abc", true, nil},
+		{"abc", "abc", true, []int{0, 1, 2}},
+		{"ac", "abc", true, []int{0, 2}},
+		{"abc", "ab", false, nil},
+		{"ba", "abc", false, nil},
+		// the consecutive match later is better than the first one.
+		{"ab", "a_xb_ab", true, []int{5, 6}},
+		{"gst", "git status", true, []int{0, 4, 5}},
+		// the top of the word is better than the middle.
+		{"b", "ab_b", true, []int{3}},
+		// the upper case following the lower case
+		{"b", "abB", true, []int{2}},
+		// upper case in the pattern makes it case-sensitive.
+		{"foo", "FOO", true, []int{0, 1, 2}},
+		{"Foo", "foo", false, nil},
+		{"Foo", "fooFoo", true, []int{3, 4, 5}},
+		{"B", "abB", true, []int{2}},
+	}
+	for _, test := range tests {
+		_, positions, ok := FuzzyMatch(test.pattern, test.text)
+		if ok != test.ok || !reflect.DeepEqual(positions, test.positions) {
+			t.Errorf("FuzzyMatch(%q,%q)=%v,%v", test.pattern, test.text, positions, ok)
+		}
+	}
+}
+
+func TestFuzzyMatchScore(t *testing.T) {
+	score := func(pattern, text string) int {
+		s, _, ok := FuzzyMatch(pattern, text)
+		if !ok {
+			t.Fatalf("FuzzyMatch(%q,%q) does not match", pattern, text)
+		}
+		return s
+	}
+	if s := score("abc", "abc"); s != 3*fuzzyScoreMatch+fuzzyBonusBoundary+2*fuzzyBonusConsecutive {
+		t.Errorf("consecutive: %d", s)
+	}
+	if s := score("b", "a_b"); s != fuzzyScoreMatch+fuzzyBonusBoundary {
+		t.Errorf("boundary: %d", s)
+	}
+	if s := score("b", "aB"); s != fuzzyScoreMatch+fuzzyBonusCamel {
+		t.Errorf("camel: %d", s)
+	}
+	if s := score("ac", "abc"); s != 2*fuzzyScoreMatch+fuzzyBonusBoundary-fuzzyPenaltyGap {
+		t.Errorf("gap: %d", s)
+	}
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	tests := []struct {
+		candidates []string
+		pattern    string
+		indexes    []int
+	}{
+		{[]string{"b", "a", "c"}, "", []int{0, 1, 2}},
+		{[]string{"xbx", "x_b", "xaB", "xyz"}, "b", []int{1, 2, 0}},
+		// the ties are kept in the original order.
+		{[]string{"ab", "xab", "ab", "a_b"}, "ab", []int{0, 2, 3, 1}},
+		{[]string{"xab", "yab", "zab"}, "ab", []int{0, 1, 2}},
+	}
+	for _, test := range tests {
+		results := fuzzyFilter(test.candidates, test.pattern)
+		indexes := make([]int, len(results))
+		for i, r := range results {
+			indexes[i] = r.index
+		}
+		if !reflect.DeepEqual(indexes, test.indexes) {
+			t.Errorf("fuzzyFilter(%q,%q)=%v", test.candidates, test.pattern, indexes)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package readline
+
+import (
+	"context"
+	"io"
+)
+
+// SyntaxHighlight colors the command line by the hook set with
+// SetHighlightHook while typing.
+var SyntaxHighlight = false
+
+// Highlight is the color of the runes of the buffer in [Start,End).
+type Highlight struct {
+	Start int
+	End   int
+	Color string // the escape sequence such as "\x1B[32m"
+}
+
+// HighlightHookT returns the colors of text. The later ones take
+// precedence where they overlap.
+type HighlightHookT func(ctx context.Context, text string) []Highlight
+
+var highlightHook HighlightHookT
+
+// SetHighlightHook sets the function to color the command line and
+// returns the previous one.
+func SetHighlightHook(hook HighlightHookT) (rv HighlightHookT) {
+	rv, highlightHook = highlightHook, hook
+	return
+}
+
+const highlightResetColor = "\x1B[0m"
+
+// updateColors makes this.colors, the color of each rune of the buffer,
+// when the text is changed since it was made last.
+// It returns false when the line is not highlighted.
+func (this *Buffer) updateColors(ctx context.Context) bool {
+	if !SyntaxHighlight || highlightHook == nil {
+		this.colors = nil
+		return false
+	}
+	text := this.String()
+	if this.colors != nil && text == this.colorsText {
+		return true
+	}
+	this.colors = make([]string, this.Length)
+	this.colorsText = text
+	this.colorsStale = true
+	for _, h := range highlightHook(ctx, text) {
+		for i := h.Start; i < h.End && i < this.Length; i++ {
+			if i >= 0 {
+				this.colors[i] = h.Color
+			}
+		}
+	}
+	return true
+}
+
+// putColoredRune prints the rune at i with its color. current is the
+// color printed last, which is updated.
+func (this *Buffer) putColoredRune(i int, current *string) {
+	color := ""
+	if i < len(this.colors) {
+		color = this.colors[i]
+	}
+	if color != *current {
+		io.WriteString(this.Writer, highlightResetColor+color)
+		*current = color
+	}
+	this.putRune(this.Buffer[i])
+}
+
+// drawHighlight repaints the line from ViewStart with the colors when
+// the colors are changed or the runes are drawn without them.
+// The runes are measured with GetCharWidth as Repaint does.
+func (this *Buffer) drawHighlight(ctx context.Context) {
+	if !this.updateColors(ctx) || !this.colorsStale {
+		return
+	}
+	this.colorsStale = false
+	if this.multiLine {
+		this.repaintMultiLine()
+		return
+	}
+	this.Backspace(this.GetWidthBetween(this.ViewStart, this.Cursor))
+	current := ""
+	w := 0
+	bs := 0
+	for i := this.ViewStart; i < this.Length; i++ {
+		w1 := GetCharWidth(this.Buffer[i])
+		if i >= this.Cursor {
+			if w+w1 >= this.ViewWidth() {
+				break
+			}
+			bs += w1
+		}
+		this.putColoredRune(i, &current)
+		w += w1
+	}
+	if current != "" {
+		io.WriteString(this.Writer, highlightResetColor)
+	}
+	this.Eraseline()
+	this.Backspace(bs)
+}
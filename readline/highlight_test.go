@@ -0,0 +1,55 @@
+package readline
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDrawHighlight(t *testing.T) {
+	called := 0
+	defer SetHighlightHook(SetHighlightHook(func(ctx context.Context, text string) []Highlight {
+		called++
+		return []Highlight{{Start: 0, End: len(text), Color: "\x1B[32m"}}
+	}))
+	defer func(value bool) { SyntaxHighlight = value }(SyntaxHighlight)
+	SyntaxHighlight = true
+
+	var output bytes.Buffer
+	this := newTestBuffer(testHistory{}, "ls -l")
+	this.Writer = bufio.NewWriter(&output)
+	ctx := context.Background()
+	draw := func() string {
+		t.Helper()
+		this.Writer.Flush()
+		output.Reset()
+		this.drawHighlight(ctx)
+		this.Writer.Flush()
+		return output.String()
+	}
+
+	if s := draw(); called != 1 || !strings.Contains(s, "\x1B[32m") {
+		t.Fatalf("first: %d,%q", called, s)
+	}
+	if s := draw(); called != 1 || s != "" {
+		t.Fatalf("unchanged: %d,%q", called, s)
+	}
+
+	// the cursor moves do not color the line again, but the runes drawn
+	// over are repainted with the colors.
+	KeyFuncBackword(ctx, this)
+	if s := draw(); called != 1 || s != "" {
+		t.Fatalf("backward: %d,%q", called, s)
+	}
+	KeyFuncForward(ctx, this)
+	if s := draw(); called != 1 || !strings.Contains(s, "\x1B[32m") {
+		t.Fatalf("forward: %d,%q", called, s)
+	}
+
+	this.InsertAndRepaint("a")
+	if s := draw(); called != 2 || !strings.Contains(s, "\x1B[32m") {
+		t.Fatalf("inserted: %d,%q", called, s)
+	}
+}
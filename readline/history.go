@@ -3,6 +3,9 @@ package readline
 import (
 	"bufio"
 	"context"
+	"os"
+	"runtime"
+	"strings"
 )
 
 type IHistory interface {
@@ -10,15 +13,35 @@ type IHistory interface {
 	At(int) string
 }
 
+// IDirHistory is the history which knows the directory where each
+// command was executed.
+type IDirHistory interface {
+	IHistory
+	DirAt(int) string
+}
+
+// DirHistoryFirst makes Up/Down recall the commands executed in the
+// current directory before the others.
+var DirHistoryFirst = false
+
 type Editor struct {
 	History IHistory
 	Writer  *bufio.Writer
 	Prompt  func() (int, error)
 	Default string
 	Cursor  int
+	// Incomplete returns true when the text needs more lines.
+	// Enter inserts a newline then in the multi-line mode.
+	Incomplete func(text string) bool
 }
 
 func KeyFuncHistoryUp(ctx context.Context, this *Buffer) Result {
+	if this.moveCursorRow(-1) {
+		return CONTINUE
+	}
+	if DirHistoryFirst {
+		return KeyFuncDirHistoryUp(ctx, this)
+	}
 	if this.History.Len() <= 0 {
 		return CONTINUE
 	}
@@ -37,6 +60,12 @@ func KeyFuncHistoryUp(ctx context.Context, this *Buffer) Result {
 }
 
 func KeyFuncHistoryDown(ctx context.Context, this *Buffer) Result {
+	if this.moveCursorRow(1) {
+		return CONTINUE
+	}
+	if DirHistoryFirst {
+		return KeyFuncDirHistoryDown(ctx, this)
+	}
 	if this.History.Len() <= 0 {
 		return CONTINUE
 	}
@@ -53,3 +82,127 @@ func KeyFuncHistoryDown(ctx context.Context, this *Buffer) Result {
 	}
 	return CONTINUE
 }
+
+// replaceLine replaces the whole line with line and moves the cursor
+// to the tail.
+func (this *Buffer) replaceLine(ctx context.Context, line string) {
+	KeyFuncClear(ctx, this)
+	this.InsertString(0, line)
+	this.ViewStart = 0
+	this.Cursor = 0
+	KeyFuncTail(ctx, this)
+}
+
+func sameDir(a, b string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// dirHistoryList returns the indexes of the history in the order to
+// recall: the commands executed in the current directory from the newest,
+// and then the others. The same commands appear only once.
+func dirHistoryList(history IHistory) []int {
+	wd, _ := os.Getwd()
+	dirHistory, _ := history.(IDirHistory)
+	here := []int{}
+	others := []int{}
+	found := map[string]bool{}
+	for i := history.Len() - 1; i >= 0; i-- {
+		line := history.At(i)
+		if found[line] {
+			continue
+		}
+		found[line] = true
+		if dirHistory != nil && wd != "" && sameDir(dirHistory.DirAt(i), wd) {
+			here = append(here, i)
+		} else {
+			others = append(others, i)
+		}
+	}
+	return append(here, others...)
+}
+
+// KeyFuncDirHistoryUp replaces the line with the previous command
+// executed in the current directory. After them, the commands executed
+// in the other directories follow.
+func KeyFuncDirHistoryUp(ctx context.Context, this *Buffer) Result {
+	if this.dirHistory == nil {
+		this.dirHistory = dirHistoryList(this.History)
+		this.dirHistoryPos = -1
+	}
+	if this.dirHistoryPos+1 >= len(this.dirHistory) {
+		return CONTINUE
+	}
+	this.dirHistoryPos++
+	this.replaceLine(ctx, this.History.At(this.dirHistory[this.dirHistoryPos]))
+	return CONTINUE
+}
+
+// KeyFuncDirHistoryDown is the reverse of KeyFuncDirHistoryUp.
+func KeyFuncDirHistoryDown(ctx context.Context, this *Buffer) Result {
+	if this.dirHistory == nil || this.dirHistoryPos < 0 {
+		return CONTINUE
+	}
+	this.dirHistoryPos--
+	if this.dirHistoryPos < 0 {
+		this.replaceLine(ctx, "")
+	} else {
+		this.replaceLine(ctx, this.History.At(this.dirHistory[this.dirHistoryPos]))
+	}
+	return CONTINUE
+}
+
+// replaceAfterCursor replaces the text after the cursor with str
+// and keeps the cursor position.
+func (this *Buffer) replaceAfterCursor(str string) {
+	this.Length = this.Cursor
+	this.InsertString(this.Cursor, str)
+	this.Repaint(this.Cursor, 1)
+}
+
+// KeyFuncHistorySearchBackward replaces the line with the previous command
+// starting with the text left of the cursor.
+func KeyFuncHistorySearchBackward(ctx context.Context, this *Buffer) Result {
+	prefix := this.SubString(0, this.Cursor)
+	current := this.String()
+	for i := this.HistoryPointer - 1; i >= 0; i-- {
+		line := this.History.At(i)
+		if strings.HasPrefix(line, prefix) && line != current {
+			if this.HistoryPointer >= this.History.Len() {
+				// to restore by KeyFuncHistorySearchForward
+				this.editingLine = current
+			}
+			this.HistoryPointer = i
+			this.replaceAfterCursor(line[len(prefix):])
+			return CONTINUE
+		}
+	}
+	return CONTINUE
+}
+
+// KeyFuncHistorySearchForward replaces the line with the next command
+// starting with the text left of the cursor.
+func KeyFuncHistorySearchForward(ctx context.Context, this *Buffer) Result {
+	prefix := this.SubString(0, this.Cursor)
+	current := this.String()
+	for i := this.HistoryPointer + 1; i < this.History.Len(); i++ {
+		line := this.History.At(i)
+		if strings.HasPrefix(line, prefix) && line != current {
+			this.HistoryPointer = i
+			this.replaceAfterCursor(line[len(prefix):])
+			return CONTINUE
+		}
+	}
+	if this.HistoryPointer < this.History.Len() {
+		// back to the line being edited
+		this.HistoryPointer = this.History.Len()
+		if strings.HasPrefix(this.editingLine, prefix) {
+			this.replaceAfterCursor(this.editingLine[len(prefix):])
+		} else {
+			this.replaceAfterCursor("")
+		}
+	}
+	return CONTINUE
+}
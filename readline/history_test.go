@@ -0,0 +1,64 @@
+package readline
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"testing"
+)
+
+// testHistory is the history for the tests.
+type testHistory []string
+
+func (h testHistory) Len() int        { return len(h) }
+func (h testHistory) At(i int) string { return h[i] }
+
+// newTestBuffer returns the buffer which has text with the cursor at
+// its end. The output is discarded.
+func newTestBuffer(history IHistory, text string) *Buffer {
+	this := &Buffer{
+		Editor: &Editor{
+			History: history,
+			Writer:  bufio.NewWriter(ioutil.Discard),
+		},
+		Buffer:         make([]rune, 20),
+		HistoryPointer: history.Len(),
+		TermWidth:      80,
+	}
+	this.InsertString(0, text)
+	this.Cursor = this.Length
+	return this
+}
+
+func TestHistorySearch(t *testing.T) {
+	history := testHistory{"git status", "ls", "git log", "git log -p"}
+	this := newTestBuffer(history, "git lo")
+	this.Cursor = 3 // after `git`
+
+	expect := func(text string, pointer int) {
+		t.Helper()
+		if s := this.String(); s != text || this.HistoryPointer != pointer || this.Cursor != 3 {
+			t.Fatalf("%q,%d,%d", s, this.HistoryPointer, this.Cursor)
+		}
+	}
+	ctx := context.Background()
+	KeyFuncHistorySearchBackward(ctx, this)
+	expect("git log -p", 3)
+	KeyFuncHistorySearchBackward(ctx, this)
+	expect("git log", 2)
+	KeyFuncHistorySearchBackward(ctx, this)
+	expect("git status", 0)
+	KeyFuncHistorySearchBackward(ctx, this)
+	expect("git status", 0)
+
+	KeyFuncHistorySearchForward(ctx, this)
+	expect("git log", 2)
+	KeyFuncHistorySearchForward(ctx, this)
+	expect("git log -p", 3)
+
+	// the line typed comes back at the end.
+	KeyFuncHistorySearchForward(ctx, this)
+	expect("git lo", 4)
+	KeyFuncHistorySearchForward(ctx, this)
+	expect("git lo", 4)
+}
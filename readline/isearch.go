@@ -2,141 +2,313 @@ package readline
 
 import (
 	"context"
-	"fmt"
 	"io"
+	"regexp"
 	"strings"
 	"unicode"
+)
+
+// IncSearchIgnoreCase is the switch for the incremental search to ignore
+// the case. Alt-C toggles it while searching.
+var IncSearchIgnoreCase = false
+
+// IncSearchRegexp is the switch for the incremental search to treat the
+// search string as a regular expression. Alt-R toggles it while searching.
+var IncSearchRegexp = false
 
-	"github.com/zetamatta/go-getch"
+// lastSearchStr is the search string of the last incremental search.
+// Ctrl-R or Ctrl-S with the empty search string searches it again.
+var lastSearchStr = ""
+
+// the escape sequences for the incremental search
+const (
+	isearchHighlight  = "\x1B[7m"
+	isearchResetColor = "\x1B[0m"
 )
 
-func KeyFuncIncSearch(ctx context.Context, this *Buffer) Result {
-	var searchBuf strings.Builder
-	foundStr := ""
-	searchStr := ""
-	lastDrawWidth := 0
-	lastFoundPos := this.History.Len() - 1
-	this.Backspace(this.Cursor - this.ViewStart)
-
-	update := func() {
-		for i := this.History.Len() - 1; ; i-- {
-			if i < 0 {
-				foundStr = ""
-				break
-			}
-			line := this.History.At(i)
-			if strings.Contains(line, searchStr) {
-				foundStr = line
-				lastFoundPos = i
-				break
-			}
+// The incremental search is registered here because it refers to keyMap
+// to run the key typed, which is initialized with NAME2FUNC.
+func init() {
+	NAME2FUNC[F_ISEARCH_BACKWARD] = KeyFuncIncSearch
+	NAME2FUNC[F_ISEARCH_FORWARD] = KeyFuncIncSearchForward
+	keyMap[name2char[K_CTRL_R]] = name2func(F_ISEARCH_BACKWARD)
+	keyMap[name2char[K_CTRL_S]] = name2func(F_ISEARCH_FORWARD)
+}
+
+// incSearch is the state of the incremental search.
+type incSearch struct {
+	*Buffer
+	query      []rune
+	backward   bool
+	ignoreCase bool
+	regexp     bool
+	origin     int    // the index of History to start searching
+	foundPos   int    // the index of History found, or -1
+	found      string // the line found
+	match      []int  // the range of bytes in found matched
+	failing    bool   // the last search did not find any line
+	badPattern bool   // the search string is not a valid regular expression
+	drawWidth  int
+}
+
+// matcher returns the function to find the search string in a line.
+func (s *incSearch) matcher() func(string) []int {
+	pattern := string(s.query)
+	if !s.regexp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if s.ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	s.badPattern = err != nil
+	if err != nil {
+		return nil
+	}
+	return re.FindStringIndex
+}
+
+// search finds the search string from the history[start] toward the
+// direction. With skipSame, the line same as the current one is skipped.
+// When nothing is found, the line found last is kept.
+func (s *incSearch) search(start int, skipSame bool) {
+	if len(s.query) <= 0 {
+		s.foundPos, s.found, s.match, s.failing = -1, "", nil, false
+		return
+	}
+	find := s.matcher()
+	if find == nil {
+		s.failing = true
+		return
+	}
+	step := 1
+	if s.backward {
+		step = -1
+	}
+	for i := start; i >= 0 && i < s.History.Len(); i += step {
+		line := s.History.At(i)
+		if skipSame && line == s.found {
+			continue
+		}
+		if loc := find(line); loc != nil {
+			s.foundPos, s.found, s.match, s.failing = i, line, loc, false
+			return
 		}
 	}
-	for {
-		drawStr := fmt.Sprintf("(i-search)[%s]:%s", searchStr, foundStr)
-		drawWidth := 0
-		for _, ch := range drawStr {
-			w1 := GetCharWidth(ch)
-			if drawWidth+w1 >= this.ViewWidth() {
-				break
-			}
-			this.PutRune(ch)
-			drawWidth += w1
+	s.failing = true
+}
+
+// searchFromOrigin searches again from the position the search started at.
+func (s *incSearch) searchFromOrigin() {
+	s.foundPos, s.found, s.match = -1, "", nil
+	if s.backward {
+		s.search(s.origin-1, false)
+	} else {
+		s.search(s.origin+1, false)
+	}
+}
+
+// searchNext finds the next match toward the direction. The search string
+// of the last search is used when it is empty.
+func (s *incSearch) searchNext(backward bool) {
+	s.backward = backward
+	if len(s.query) <= 0 {
+		s.query = []rune(lastSearchStr)
+		s.searchFromOrigin()
+		return
+	}
+	if s.foundPos < 0 {
+		s.searchFromOrigin()
+	} else if backward {
+		s.search(s.foundPos-1, true)
+	} else {
+		s.search(s.foundPos+1, true)
+	}
+}
+
+func (s *incSearch) label() string {
+	var label strings.Builder
+	label.WriteByte('(')
+	if s.badPattern {
+		label.WriteString("invalid ")
+	} else if s.failing {
+		label.WriteString("failing ")
+	}
+	if s.backward {
+		label.WriteString("i-search")
+	} else {
+		label.WriteString("fwd-i-search")
+	}
+	if s.regexp {
+		label.WriteString(",regexp")
+	}
+	if s.ignoreCase {
+		label.WriteString(",nocase")
+	}
+	label.WriteByte(')')
+	return label.String()
+}
+
+// draw prints `(i-search)[QUERY]:FOUND` with the match highlighted.
+// The head of FOUND is cut off when the match does not fit in the view.
+func (s *incSearch) draw() {
+	header := s.label() + "[" + string(s.query) + "]:"
+	width := 0
+	for _, ch := range header {
+		w1 := GetCharWidth(ch)
+		if width+w1 >= s.ViewWidth() {
+			break
 		}
-		if lastDrawWidth > drawWidth {
-			n := lastDrawWidth - drawWidth
-			this.PutRunes(' ', n)
-			this.Backspace(n)
+		s.PutRune(ch)
+		width += w1
+	}
+	start := 0
+	if s.match != nil {
+		matchWidth := width
+		for _, ch := range s.found[:s.match[1]] {
+			matchWidth += GetCharWidth(ch)
+		}
+		for matchWidth >= s.ViewWidth() && start < s.match[0] {
+			ch := []rune(s.found[start:])[0]
+			matchWidth -= GetCharWidth(ch)
+			start += len(string(ch))
+		}
+	}
+	for i, ch := range s.found[start:] {
+		i += start
+		w1 := GetCharWidth(ch)
+		if width+w1 >= s.ViewWidth() {
+			break
+		}
+		if s.match != nil && i == s.match[0] && s.match[0] < s.match[1] {
+			io.WriteString(s.Writer, isearchHighlight)
 		}
-		lastDrawWidth = drawWidth
+		s.PutRune(ch)
+		if s.match != nil && i+len(string(ch)) == s.match[1] {
+			io.WriteString(s.Writer, isearchResetColor)
+		}
+		width += w1
+	}
+	io.WriteString(s.Writer, isearchResetColor)
+	s.Eraseline()
+	s.drawWidth = width
+}
+
+// accept puts the line found into the buffer with the cursor on the match.
+// The line being edited is kept when nothing is found.
+func (s *incSearch) accept() {
+	if len(s.query) > 0 {
+		lastSearchStr = string(s.query)
+	}
+	if s.found != "" {
+		s.Length = 0
+		s.InsertString(0, s.found)
+		s.Cursor = len([]rune(s.found[:s.match[0]]))
+	}
+	s.RepaintAfterPrompt()
+}
+
+// incSearchLoop runs the incremental search and returns the function
+// bound to the key which ended the search, or nil.
+func incSearchLoop(this *Buffer, backward bool) KeyFuncT {
+	s := &incSearch{
+		Buffer:     this,
+		backward:   backward,
+		ignoreCase: IncSearchIgnoreCase,
+		regexp:     IncSearchRegexp,
+		origin:     this.HistoryPointer,
+		foundPos:   -1,
+	}
+	this.Backspace(this.GetWidthBetween(this.ViewStart, this.Cursor))
+	for {
+		s.draw()
 		io.WriteString(this.Writer, CURSOR_ON)
 		this.Writer.Flush()
-		charcode := getch.Rune()
+		e := GetConsoleEvent()
 		io.WriteString(this.Writer, CURSOR_OFF)
-		this.Backspace(drawWidth)
-		switch charcode {
-		case '\b':
-			searchBuf.Reset()
-			// chop last char
-			var lastchar rune
-			for i, c := range searchStr {
-				if i > 0 {
-					searchBuf.WriteRune(lastchar)
-				}
-				lastchar = c
-			}
-			searchStr = searchBuf.String()
-			update()
-		case '\r': // ENTER
-			this.ViewStart = 0
-			this.Length = 0
-			this.Cursor = 0
-			this.ReplaceAndRepaint(0, foundStr)
-			return CONTINUE
-		case rune('c' & 0x1F), rune('g' & 0x1F), rune(0x1B):
-			w := 0
-			var i int
-			for i = this.ViewStart; i < this.Cursor; i++ {
-				w += GetCharWidth(this.Buffer[i])
-				this.PutRune(this.Buffer[i])
-			}
-			bs := 0
-			for {
-				if i >= this.Length {
-					if drawWidth > w {
-						this.PutRunes(' ', drawWidth-w)
-						bs += (drawWidth - w)
-					}
-					break
-				}
-				w1 := GetCharWidth(this.Buffer[i])
-				if w+w1 >= this.ViewWidth() {
-					break
-				}
-				this.PutRune(this.Buffer[i])
-				w += w1
-				bs += w1
-				i++
+		this.Backspace(s.drawWidth)
+		if e.Resize != nil {
+			this.TermWidth = int(e.Resize.Width)
+			continue
+		}
+		if e.Key == nil {
+			continue
+		}
+		this.Unicode = e.Key.Rune
+		this.Keycode = e.Key.Scan
+		this.ShiftState = e.Key.Shift
+		if (this.ShiftState&ALT_PRESSED) != 0 && (this.ShiftState&CTRL_PRESSED) == 0 {
+			switch this.Keycode {
+			case name2alt[K_ALT_C]:
+				s.ignoreCase = !s.ignoreCase
+				s.searchFromOrigin()
+				continue
+			case name2alt[K_ALT_R]:
+				s.regexp = !s.regexp
+				s.searchFromOrigin()
+				continue
 			}
-			this.Backspace(bs)
-			return CONTINUE
-		case rune('r' & 0x1F):
-			for i := lastFoundPos - 1; ; i-- {
-				if i < 0 {
-					i = this.History.Len() - 1
-				}
-				if i == lastFoundPos {
-					break
-				}
-				line := this.History.At(i)
-				if strings.Contains(line, searchStr) && foundStr != line {
-					foundStr = line
-					lastFoundPos = i
-					break
+		} else {
+			switch this.Unicode {
+			case '\b':
+				if len(s.query) > 0 {
+					s.query = s.query[:len(s.query)-1]
+					s.searchFromOrigin()
 				}
+				continue
+			case name2char[K_CTRL_R]:
+				s.searchNext(true)
+				continue
+			case name2char[K_CTRL_S]:
+				s.searchNext(false)
+				continue
+			case name2char[K_CTRL_C], name2char[K_CTRL_G]:
+				this.RepaintAfterPrompt()
+				return nil
+			case name2char[K_ESCAPE], name2char[K_CTRL_J]:
+				s.accept()
+				return nil
 			}
-		case rune('s' & 0x1F):
-			for i := lastFoundPos + 1; ; i++ {
-				if i >= this.History.Len() {
-					break
-				}
-				if i == lastFoundPos {
-					break
-				}
-				line := this.History.At(i)
-				if strings.Contains(line, searchStr) && foundStr != line {
-					foundStr = line
-					lastFoundPos = i
-					break
+			if this.Unicode != 0 && !unicode.IsControl(this.Unicode) {
+				s.query = append(s.query, this.Unicode)
+				if s.foundPos < 0 {
+					s.searchFromOrigin()
+				} else {
+					s.search(s.foundPos, false)
 				}
+				continue
 			}
-		default:
-			if unicode.IsControl(charcode) {
-				break
-			}
-			searchBuf.WriteRune(charcode)
-			searchStr = searchBuf.String()
-			update()
 		}
+		// The other keys end the search and do what they are bound to.
+		f := this.lookupKeyFunc()
+		if fg, ok := f.(*KeyGoFuncT); f == nil || (ok && fg.Func == nil) {
+			continue
+		}
+		s.accept()
+		return f
 	}
 }
+
+func runIncSearch(ctx context.Context, this *Buffer, backward bool) Result {
+	resume := this.suspendMultiLine()
+	f := incSearchLoop(this, backward)
+	resume()
+	if f == nil {
+		return CONTINUE
+	}
+	if this.multiLine {
+		this.moveToLastRow()
+	}
+	return f.Call(ctx, this)
+}
+
+// KeyFuncIncSearch searches the history backward incrementally.
+// Ctrl-R and Ctrl-S move to the previous and the next match.
+func KeyFuncIncSearch(ctx context.Context, this *Buffer) Result {
+	return runIncSearch(ctx, this, true)
+}
+
+// KeyFuncIncSearchForward searches the history forward incrementally.
+func KeyFuncIncSearchForward(ctx context.Context, this *Buffer) Result {
+	return runIncSearch(ctx, this, false)
+}
@@ -0,0 +1,123 @@
+package readline
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestSearch(history testHistory) *incSearch {
+	return &incSearch{
+		Buffer:   newTestBuffer(history, ""),
+		backward: true,
+		origin:   len(history),
+		foundPos: -1,
+	}
+}
+
+// typeQuery adds str to the search string as incSearchLoop does.
+func (s *incSearch) typeQuery(str string) {
+	for _, ch := range str {
+		s.query = append(s.query, ch)
+		if s.foundPos < 0 {
+			s.searchFromOrigin()
+		} else {
+			s.search(s.foundPos, false)
+		}
+	}
+}
+
+func TestIncSearch(t *testing.T) {
+	history := testHistory{"Make all", "make all", "make test", "make test", "ls"}
+	s := newTestSearch(history)
+	expect := func(pos int, match []int, failing bool) {
+		t.Helper()
+		if s.foundPos != pos || !reflect.DeepEqual(s.match, match) || s.failing != failing {
+			t.Fatalf("[%s] %d,%v,%v", string(s.query), s.foundPos, s.match, s.failing)
+		}
+		if pos >= 0 && s.found != history[pos] {
+			t.Fatalf("[%s] %q", string(s.query), s.found)
+		}
+	}
+	s.typeQuery("make")
+	expect(3, []int{0, 4}, false)
+
+	// the same line is skipped.
+	s.searchNext(true)
+	expect(1, []int{0, 4}, false)
+
+	// the failing search keeps the last match.
+	s.searchNext(true)
+	expect(1, []int{0, 4}, true)
+	if label := s.label(); label != "(failing i-search)" {
+		t.Fatalf("label()=%s", label)
+	}
+
+	// ignoring the case
+	s.ignoreCase = true
+	s.searchFromOrigin()
+	expect(3, []int{0, 4}, false)
+	s.searchNext(true)
+	s.searchNext(true)
+	expect(0, []int{0, 4}, false)
+	if label := s.label(); label != "(i-search,nocase)" {
+		t.Fatalf("label()=%s", label)
+	}
+	s.ignoreCase = false
+	s.searchFromOrigin()
+	expect(3, []int{0, 4}, false)
+
+	// forward
+	s.searchNext(false)
+	expect(3, []int{0, 4}, true)
+}
+
+func TestIncSearchRegexp(t *testing.T) {
+	history := testHistory{"git log", "go test ./...", "ls"}
+	s := newTestSearch(history)
+	// `g` is found, but `g.t` is not.
+	s.typeQuery("g.t")
+	if !s.failing || s.foundPos != 1 {
+		t.Fatalf("literal: %d,%v", s.foundPos, s.failing)
+	}
+	s.regexp = true
+	s.searchFromOrigin()
+	if s.failing || s.foundPos != 0 || !reflect.DeepEqual(s.match, []int{0, 3}) {
+		t.Fatalf("regexp: %d,%v,%v", s.foundPos, s.match, s.failing)
+	}
+	if label := s.label(); label != "(i-search,regexp)" {
+		t.Fatalf("label()=%s", label)
+	}
+
+	// the invalid pattern keeps the last match.
+	s.typeQuery("(")
+	if !s.badPattern || !s.failing || s.foundPos != 0 || !reflect.DeepEqual(s.match, []int{0, 3}) {
+		t.Fatalf("invalid: %v,%d,%v,%v", s.badPattern, s.foundPos, s.match, s.failing)
+	}
+	if label := s.label(); !strings.HasPrefix(label, "(invalid ") {
+		t.Fatalf("label()=%s", label)
+	}
+	s.query = s.query[:len(s.query)-1]
+	s.searchFromOrigin()
+	if s.badPattern || s.failing || s.foundPos != 0 {
+		t.Fatalf("valid again: %v,%d,%v", s.badPattern, s.foundPos, s.failing)
+	}
+
+	// the escaped dots match only dots.
+	s.query = []rune(`\.\.\.`)
+	s.searchFromOrigin()
+	if s.failing || s.foundPos != 1 || !reflect.DeepEqual(s.match, []int{10, 13}) {
+		t.Fatalf("regexp: %d,%v,%v", s.foundPos, s.match, s.failing)
+	}
+}
+
+func TestIncSearchLastSearchString(t *testing.T) {
+	defer func(value string) { lastSearchStr = value }(lastSearchStr)
+	lastSearchStr = "log"
+
+	s := newTestSearch(testHistory{"git log", "ls"})
+	s.searchNext(true)
+	if string(s.query) != "log" || s.foundPos != 0 {
+		t.Fatalf("searchNext: %q,%d", string(s.query), s.foundPos)
+	}
+}
@@ -8,10 +8,13 @@ import (
 	"unicode"
 
 	"github.com/atotto/clipboard"
-	"github.com/zetamatta/go-getch"
 )
 
 func KeyFuncEnter(ctx context.Context, this *Buffer) Result { // Ctrl-M
+	if this.multiLine && this.Incomplete != nil && this.Incomplete(this.String()) {
+		this.InsertAndRepaint("\n")
+		return CONTINUE
+	}
 	return ENTER
 }
 
@@ -216,7 +219,7 @@ func KeyFuncQuotedInsert(ctx context.Context, this *Buffer) Result {
 	defer io.WriteString(this.Writer, CURSOR_OFF)
 	for {
 		this.Writer.Flush()
-		e := getch.All()
+		e := GetConsoleEvent()
 		if e.Key != nil && e.Key.Rune != 0 {
 			this.Unicode = e.Key.Rune
 			return KeyFuncInsertSelf(ctx, this)
@@ -0,0 +1,319 @@
+package readline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MenuItem is a candidate shown by SelectMenu.
+type MenuItem struct {
+	Text        string // the text put into the buffer
+	Display     string // the text shown in the menu. Text when empty
+	Description string
+	Group       string // the header of the items which have the same Group
+}
+
+func (item *MenuItem) display() string {
+	if item.Display == "" {
+		return item.Text
+	}
+	return item.Display
+}
+
+// the escape sequences for the menu
+const (
+	menuHighlight  = "\x1B[7m"
+	menuHeader     = "\x1B[1m"
+	menuResetColor = "\x1B[0m"
+)
+
+// menuGroup is the items having the same Group, which are arranged in
+// columns as BoxPrint does.
+type menuGroup struct {
+	start, end int // the range of the items
+	rows       int
+	cellWidth  int
+	showWidth  int // the width for Display in the cell
+}
+
+// menuLine is a line of the menu: the header or the items in a row.
+type menuLine struct {
+	header string
+	items  []int
+}
+
+// menu is the state of SelectMenu.
+type menu struct {
+	*Buffer
+	items    []MenuItem
+	pos      int // the position in the buffer where the text is put
+	selected int // the index of the item selected, or -1
+	groups   []*menuGroup
+	groupOf  []int // the index of groups for each item
+	lines    []menuLine
+	lineOf   []int // the index of lines for each item
+	offset   int   // the first line shown
+}
+
+// layout arranges the items for the width of the terminal.
+func (m *menu) layout() {
+	m.groups = m.groups[:0]
+	m.lines = m.lines[:0]
+	m.groupOf = make([]int, len(m.items))
+	m.lineOf = make([]int, len(m.items))
+	limit := m.TermWidth - 1
+	for start := 0; start < len(m.items); {
+		end := start + 1
+		for end < len(m.items) && m.items[end].Group == m.items[start].Group {
+			end++
+		}
+		g := &menuGroup{start: start, end: end}
+		descWidth := 0
+		for i := start; i < end; i++ {
+			if w := GetStringWidth(m.items[i].display()); w > g.showWidth {
+				g.showWidth = w
+			}
+			if w := GetStringWidth(m.items[i].Description); w > descWidth {
+				descWidth = w
+			}
+			m.groupOf[i] = len(m.groups)
+		}
+		g.cellWidth = g.showWidth
+		if descWidth > 0 {
+			g.cellWidth += 2 + descWidth
+		}
+		if g.cellWidth > limit {
+			g.cellWidth = limit
+		}
+		if g.showWidth > g.cellWidth {
+			g.showWidth = g.cellWidth
+		}
+		cols := limit / (g.cellWidth + 1)
+		if cols < 1 {
+			cols = 1
+		}
+		g.rows = (end - start + cols - 1) / cols
+		if m.items[start].Group != "" {
+			m.lines = append(m.lines, menuLine{header: m.items[start].Group})
+		}
+		for row := 0; row < g.rows; row++ {
+			line := menuLine{}
+			for i := start + row; i < end; i += g.rows {
+				line.items = append(line.items, i)
+				m.lineOf[i] = len(m.lines)
+			}
+			m.lines = append(m.lines, line)
+		}
+		m.groups = append(m.groups, g)
+		start = end
+	}
+}
+
+// cutWidth returns the head of s which fits in width, padded with spaces.
+func cutWidth(s string, width int) string {
+	var buffer strings.Builder
+	w := 0
+	for _, ch := range s {
+		w1 := GetCharWidth(ch)
+		if w+w1 > width {
+			break
+		}
+		buffer.WriteRune(ch)
+		w += w1
+	}
+	buffer.WriteString(strings.Repeat(" ", width-w))
+	return buffer.String()
+}
+
+func (m *menu) cell(i int) string {
+	g := m.groups[m.groupOf[i]]
+	item := &m.items[i]
+	text := cutWidth(item.display(), g.showWidth)
+	if g.cellWidth > g.showWidth {
+		text += cutWidth("  "+item.Description, g.cellWidth-g.showWidth)
+	}
+	if i == m.selected {
+		return menuHighlight + text + menuResetColor
+	}
+	return text
+}
+
+// draw prints the menu below the line and returns the cursor to the line.
+func (m *menu) draw() {
+	_, height := GetViewSize()
+	maxLines := height - 2
+	if maxLines < 2 {
+		maxLines = 2
+	}
+	visible := len(m.lines)
+	status := ""
+	if visible > maxLines {
+		visible = maxLines - 1
+		if m.selected >= 0 {
+			if line := m.lineOf[m.selected]; line < m.offset {
+				m.offset = line
+			} else if line >= m.offset+visible {
+				m.offset = line - visible + 1
+			}
+		}
+		status = fmt.Sprintf("-- %d-%d/%d --", m.offset+1, m.offset+visible, len(m.lines))
+	} else {
+		m.offset = 0
+	}
+	io.WriteString(m.Writer, CURSOR_OFF+"\r\n\x1B[J")
+	for i, line := range m.lines[m.offset : m.offset+visible] {
+		if i > 0 {
+			io.WriteString(m.Writer, "\r\n")
+		}
+		if line.items == nil {
+			header := strings.TrimRight(cutWidth(line.header, m.TermWidth-1), " ")
+			io.WriteString(m.Writer, menuHeader+header+menuResetColor)
+			continue
+		}
+		for j, index := range line.items {
+			if j > 0 {
+				io.WriteString(m.Writer, " ")
+			}
+			io.WriteString(m.Writer, m.cell(index))
+		}
+	}
+	if status != "" {
+		io.WriteString(m.Writer, "\r\n"+status)
+		visible++
+	}
+	m.returnToLine(visible)
+}
+
+// returnToLine moves the cursor up from the menu of n lines to the line.
+func (m *menu) returnToLine(n int) {
+	if n > 0 {
+		fmt.Fprintf(m.Writer, "\x1B[%dA", n)
+	}
+	column := m.TopColumn + m.GetWidthBetween(m.ViewStart, m.Cursor)
+	fmt.Fprintf(m.Writer, "\x1B[%dG", column+1)
+	io.WriteString(m.Writer, CURSOR_ON)
+	m.Writer.Flush()
+}
+
+// erase removes the menu.
+func (m *menu) erase() {
+	io.WriteString(m.Writer, "\r\n\x1B[J")
+	m.returnToLine(1)
+}
+
+func (m *menu) selectItem(i int) {
+	m.selected = i
+	m.ReplaceAndRepaint(m.pos, m.items[i].Text)
+}
+
+// move selects the item by the arrow key: up and down move in the
+// column, left and right move to the next column or the next group.
+func (m *menu) move(key uint16) {
+	i := m.selected
+	if i < 0 {
+		m.selectItem(0)
+		return
+	}
+	g := m.groups[m.groupOf[i]]
+	switch key {
+	case name2scan[K_UP]:
+		i--
+	case name2scan[K_DOWN]:
+		i++
+	case name2scan[K_LEFT]:
+		if i-g.rows >= g.start {
+			i -= g.rows
+		} else {
+			i = g.start - 1
+		}
+	case name2scan[K_RIGHT]:
+		if i+g.rows < g.end {
+			i += g.rows
+		} else {
+			i = g.end
+		}
+	}
+	if i >= 0 && i < len(m.items) {
+		m.selectItem(i)
+	}
+}
+
+// menuLoop runs the menu and returns the function bound to the key which
+// ended it, or nil.
+func menuLoop(m *menu) KeyFuncT {
+	original := m.SubString(m.pos, m.Cursor)
+	m.layout()
+	m.selectItem(0)
+	for {
+		m.draw()
+		e := GetConsoleEvent()
+		if e.Resize != nil {
+			m.TermWidth = int(e.Resize.Width)
+			m.layout()
+			continue
+		}
+		if e.Key == nil {
+			continue
+		}
+		m.Unicode = e.Key.Rune
+		m.Keycode = e.Key.Scan
+		m.ShiftState = e.Key.Shift
+		switch m.Unicode {
+		case '\t':
+			if (m.ShiftState & SHIFT_PRESSED) != 0 {
+				m.selectItem((m.selected + len(m.items) - 1) % len(m.items))
+			} else {
+				m.selectItem((m.selected + 1) % len(m.items))
+			}
+			continue
+		case name2char[K_CTRL_P]:
+			m.move(name2scan[K_UP])
+			continue
+		case name2char[K_CTRL_N]:
+			m.move(name2scan[K_DOWN])
+			continue
+		case name2char[K_CTRL_M]:
+			m.erase()
+			return nil
+		case name2char[K_ESCAPE], name2char[K_CTRL_G], name2char[K_CTRL_C]:
+			m.ReplaceAndRepaint(m.pos, original)
+			m.erase()
+			return nil
+		case 0:
+			switch m.Keycode {
+			case name2scan[K_UP], name2scan[K_DOWN], name2scan[K_LEFT], name2scan[K_RIGHT]:
+				m.move(m.Keycode)
+				continue
+			}
+		}
+		// The other keys close the menu and do what they are bound to.
+		f := m.lookupKeyFunc()
+		if fg, ok := f.(*KeyGoFuncT); f == nil || (ok && fg.Func == nil) {
+			continue
+		}
+		m.erase()
+		return f
+	}
+}
+
+// SelectMenu shows items in columns below the line. Tab and Shift-Tab
+// select the next and the previous item, and the arrow keys move the
+// highlight in the columns. The text of the item selected replaces the
+// buffer from pos to the cursor. Enter accepts it and Esc cancels.
+func (this *Buffer) SelectMenu(ctx context.Context, pos int, items []MenuItem) Result {
+	if len(items) <= 0 {
+		return CONTINUE
+	}
+	resume := this.suspendMultiLine()
+	f := menuLoop(&menu{Buffer: this, items: items, pos: pos, selected: -1})
+	resume()
+	if f == nil {
+		return CONTINUE
+	}
+	if this.multiLine {
+		this.moveToLastRow()
+	}
+	return f.Call(ctx, this)
+}
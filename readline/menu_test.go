@@ -0,0 +1,114 @@
+package readline
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestMenu(items []MenuItem) *menu {
+	m := &menu{
+		Buffer:   newTestBuffer(testHistory{}, "ls "),
+		items:    items,
+		pos:      3,
+		selected: -1,
+	}
+	m.TermWidth = 20
+	m.layout()
+	return m
+}
+
+func TestMenuLayout(t *testing.T) {
+	m := newTestMenu([]MenuItem{
+		{Text: "aa1", Group: "a"},
+		{Text: "aa2", Group: "a"},
+		{Text: "aa3", Group: "a"},
+		{Text: "aa4", Group: "a"},
+		{Text: "aa5", Group: "a"},
+		{Text: "b1", Description: "desc", Group: "b"},
+		{Text: "b2", Description: "desc", Group: "b"},
+		{Text: "aa6", Group: "a"},
+		{Text: strings.Repeat("c", 30)},
+		{Text: "c"},
+	})
+	// the columns are filled from the top to the bottom in each group,
+	// and the groups are in the order of the items.
+	expected := []menuLine{
+		{header: "a"},
+		{items: []int{0, 2, 4}},
+		{items: []int{1, 3}},
+		{header: "b"},
+		{items: []int{5, 6}},
+		{header: "a"},
+		{items: []int{7}},
+		{items: []int{8}},
+		{items: []int{9}},
+	}
+	if !reflect.DeepEqual(m.lines, expected) {
+		t.Fatalf("lines=%+v", m.lines)
+	}
+	if !reflect.DeepEqual(m.lineOf, []int{1, 2, 1, 2, 1, 4, 4, 6, 7, 8}) {
+		t.Fatalf("lineOf=%v", m.lineOf)
+	}
+	if !reflect.DeepEqual(m.groupOf, []int{0, 0, 0, 0, 0, 1, 1, 2, 3, 3}) {
+		t.Fatalf("groupOf=%v", m.groupOf)
+	}
+	if g := m.groups[1]; g.showWidth != 2 || g.cellWidth != 8 || g.rows != 1 {
+		t.Fatalf("groups[1]=%+v", *g)
+	}
+	// the item too wide is cut to the width of the terminal.
+	if g := m.groups[3]; g.showWidth != 19 || g.cellWidth != 19 || g.rows != 2 {
+		t.Fatalf("groups[3]=%+v", *g)
+	}
+	if cell := m.cell(5); cell != "b1  desc" {
+		t.Fatalf("cell(5)=%q", cell)
+	}
+
+	// the wider terminal has more columns.
+	m.TermWidth = 80
+	m.layout()
+	if len(m.lines) != 7 ||
+		!reflect.DeepEqual(m.lines[1].items, []int{0, 1, 2, 3, 4}) ||
+		!reflect.DeepEqual(m.lines[6].items, []int{8, 9}) {
+		t.Fatalf("lines=%+v", m.lines)
+	}
+}
+
+func TestMenuMove(t *testing.T) {
+	m := newTestMenu([]MenuItem{
+		{Text: "aa1", Group: "a"},
+		{Text: "aa2", Group: "a"},
+		{Text: "aa3", Group: "a"},
+		{Text: "aa4", Group: "a"},
+		{Text: "aa5", Group: "a"},
+		{Text: "b1", Description: "desc", Group: "b"},
+		{Text: "b2", Description: "desc", Group: "b"},
+	})
+	tests := []struct {
+		key      string
+		selected int
+	}{
+		{K_DOWN, 0}, // the first key selects the first item.
+		{K_LEFT, 0}, // nothing on the left
+		{K_UP, 0},
+		{K_RIGHT, 2},
+		{K_RIGHT, 4},
+		{K_RIGHT, 5}, // to the next group
+		{K_RIGHT, 6},
+		{K_RIGHT, 6}, // nothing on the right
+		{K_LEFT, 5},
+		{K_LEFT, 4}, // to the previous group
+		{K_UP, 3},
+		{K_LEFT, 1},
+		{K_DOWN, 2},
+	}
+	for i, test := range tests {
+		m.move(name2scan[test.key])
+		if m.selected != test.selected {
+			t.Fatalf("%d: %s: selected=%d", i, test.key, m.selected)
+		}
+		if s := m.String(); s != "ls "+m.items[m.selected].Text {
+			t.Fatalf("%d: %s: %q", i, test.key, s)
+		}
+	}
+}
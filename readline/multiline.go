@@ -0,0 +1,125 @@
+package readline
+
+import (
+	"fmt"
+	"io"
+)
+
+// MultiLine makes the editor wrap the text across the rows of the terminal
+// instead of scrolling it horizontally. Enter inserts a newline while
+// Editor.Incomplete reports that the text needs more lines, and Up/Down
+// move the cursor between the rows.
+var MultiLine = false
+
+const multiLineViewWidth = 1 << 30
+
+// layout returns the row and the column where each character of the
+// buffer is drawn. The last elements are the position of the end.
+func (this *Buffer) layout() (rows []int, cols []int) {
+	rows = make([]int, this.Length+1)
+	cols = make([]int, this.Length+1)
+	limit := this.TermWidth - 1
+	row, col := 0, this.TopColumn
+	for i := 0; i < this.Length; i++ {
+		ch := this.Buffer[i]
+		if ch == '\n' {
+			rows[i], cols[i] = row, col
+			row, col = row+1, 0
+			continue
+		}
+		w := GetCharWidth(ch)
+		if col+w > limit {
+			row, col = row+1, 0
+		}
+		rows[i], cols[i] = row, col
+		col += w
+	}
+	rows[this.Length], cols[this.Length] = row, col
+	return
+}
+
+// repaintMultiLine draws the whole text from the end of the prompt and
+// moves the cursor to its position.
+func (this *Buffer) repaintMultiLine() {
+	rows, cols := this.layout()
+	if this.cursorRow > 0 {
+		fmt.Fprintf(this.Writer, "\x1B[%dA", this.cursorRow)
+	}
+	fmt.Fprintf(this.Writer, "\x1B[%dG", this.TopColumn+1)
+	row := 0
+	current := ""
+	for i := 0; i <= this.Length; i++ {
+		for row < rows[i] {
+			io.WriteString(this.Writer, "\x1B[K\r\n")
+			row++
+		}
+		if i < this.Length && this.Buffer[i] != '\n' {
+			this.putColoredRune(i, &current)
+		}
+	}
+	if current != "" {
+		io.WriteString(this.Writer, highlightResetColor)
+	}
+	io.WriteString(this.Writer, "\x1B[J")
+	this.lastRow = row
+	if up := row - rows[this.Cursor]; up > 0 {
+		fmt.Fprintf(this.Writer, "\x1B[%dA", up)
+	}
+	fmt.Fprintf(this.Writer, "\x1B[%dG", cols[this.Cursor]+1)
+	this.cursorRow = rows[this.Cursor]
+}
+
+// moveToLastRow moves the cursor to the last row drawn, below which
+// the key functions can print something.
+func (this *Buffer) moveToLastRow() {
+	if down := this.lastRow - this.cursorRow; down > 0 {
+		fmt.Fprintf(this.Writer, "\x1B[%dB", down)
+	}
+	this.cursorRow = this.lastRow
+	this.Writer.Flush()
+}
+
+// suspendMultiLine erases the text drawn in the multi-line mode, and
+// turns the mode off to draw something on the row of the prompt.
+// The returned function turns the mode on again.
+func (this *Buffer) suspendMultiLine() func() {
+	if !this.multiLine {
+		return func() {}
+	}
+	if this.cursorRow > 0 {
+		fmt.Fprintf(this.Writer, "\x1B[%dA", this.cursorRow)
+	}
+	fmt.Fprintf(this.Writer, "\x1B[%dG\x1B[J", this.TopColumn+1)
+	this.cursorRow = 0
+	this.lastRow = 0
+	this.multiLine = false
+	this.ViewStart = 0
+	return func() {
+		this.multiLine = true
+		this.ViewStart = 0
+		this.repaintMultiLine()
+	}
+}
+
+// moveCursorRow moves the cursor to the row delta rows below (above when
+// negative) in the multi-line mode. It returns false without moving when
+// there is no such row.
+func (this *Buffer) moveCursorRow(delta int) bool {
+	if !this.multiLine {
+		return false
+	}
+	rows, cols := this.layout()
+	target := rows[this.Cursor] + delta
+	if target < 0 || target > rows[this.Length] {
+		return false
+	}
+	col := cols[this.Cursor]
+	pos := -1
+	for i := 0; i <= this.Length; i++ {
+		if rows[i] == target && (pos < 0 || cols[i] <= col) {
+			pos = i
+		}
+	}
+	this.Cursor = pos
+	return true
+}
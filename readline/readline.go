@@ -5,10 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
-
-	"github.com/zetamatta/go-box"
-	"github.com/zetamatta/go-getch"
 )
 
 var FlushBeforeReadline = false
@@ -68,7 +66,6 @@ var keyMap = map[rune]KeyFuncT{
 	name2char[K_CTRL_K]: name2func(F_KILL_LINE),
 	name2char[K_CTRL_L]: name2func(F_CLEAR_SCREEN),
 	name2char[K_CTRL_M]: name2func(F_ACCEPT_LINE),
-	name2char[K_CTRL_R]: name2func(F_ISEARCH_BACKWARD),
 	name2char[K_CTRL_U]: name2func(F_UNIX_LINE_DISCARD),
 	name2char[K_CTRL_Y]: name2func(F_YANK),
 	name2char[K_DELETE]: name2func(F_DELETE_CHAR),
@@ -136,6 +133,22 @@ func GetBindKey(keyName string) KeyFuncT {
 	}
 }
 
+// KeyNames returns the names of the keys which can be bound.
+func KeyNames() []string {
+	names := make([]string, 0, len(name2alt)+len(name2char)+len(name2scan))
+	for name := range name2alt {
+		names = append(names, name)
+	}
+	for name := range name2char {
+		names = append(names, name)
+	}
+	for name := range name2scan {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func GetFunc(funcName string) (KeyFuncT, error) {
 	rc := name2func(normWord(funcName))
 	if rc != nil {
@@ -165,6 +178,24 @@ const (
 
 var CtrlC = errors.New("^C")
 
+// lookupKeyFunc returns the function bound to the key typed last,
+// or nil when the key is ignored.
+func (this *Buffer) lookupKeyFunc() KeyFuncT {
+	if (this.ShiftState&ALT_PRESSED) != 0 &&
+		(this.ShiftState&CTRL_PRESSED) == 0 {
+		return altMap[this.Keycode]
+	} else if this.Unicode != 0 {
+		if f, ok := keyMap[this.Unicode]; ok {
+			return f
+		}
+		//f = KeyFuncInsertReport
+		return &KeyGoFuncT{Func: KeyFuncInsertSelf, Name: fmt.Sprintf("%v", this.Unicode)}
+	} else if f, ok := scanMap[this.Keycode]; ok {
+		return f
+	}
+	return &KeyGoFuncT{Func: nil, Name: ""}
+}
+
 // Call LineEditor
 // - ENTER typed -> returns TEXT and nil
 // - CTRL-C typed -> returns "" and readline.CtrlC
@@ -191,9 +222,10 @@ func (session *Editor) ReadLine(ctx context.Context) (string, error) {
 		Editor:         session,
 		Buffer:         make([]rune, 20),
 		HistoryPointer: session.History.Len(),
+		multiLine:      MultiLine,
 	}
 
-	this.TermWidth, _ = box.GetScreenBufferInfo().ViewSize()
+	this.TermWidth, _ = GetViewSize()
 
 	var err1 error
 	this.TopColumn, err1 = session.Prompt()
@@ -211,59 +243,62 @@ func (session *Editor) ReadLine(ctx context.Context) (string, error) {
 		this.Cursor = this.Length
 	}
 	this.RepaintAfterPrompt()
+	this.drawHighlight(ctx)
+
+	defer enterRawMode()()
 
 	if FlushBeforeReadline {
-		getch.Flush()
+		FlushConsoleInput()
 	}
 
 	cursorOnSwitch := false
 	for {
-		var e getch.Event
+		var e ConsoleEvent
 		if !cursorOnSwitch {
 			io.WriteString(this.Writer, CURSOR_ON)
 			cursorOnSwitch = true
 		}
 		this.Writer.Flush()
 		for e.Key == nil {
-			e = getch.All()
+			e = GetConsoleEvent()
 			if e.Resize != nil {
 				w := int(e.Resize.Width)
 				if this.TermWidth != w {
 					this.TermWidth = w
 					fmt.Fprintf(this.Writer, "\x1B[%dG", this.TopColumn+1)
 					this.RepaintAfterPrompt()
+					this.drawHighlight(ctx)
 				}
 			}
 		}
 		this.Unicode = e.Key.Rune
 		this.Keycode = e.Key.Scan
 		this.ShiftState = e.Key.Shift
-		var f KeyFuncT
-		var ok bool
-		if (this.ShiftState&getch.ALT_PRESSED) != 0 &&
-			(this.ShiftState&getch.CTRL_PRESSED) == 0 {
-			f, ok = altMap[this.Keycode]
-			if !ok {
-				continue
-			}
-		} else if this.Unicode != 0 {
-			f, ok = keyMap[this.Unicode]
-			if !ok {
-				//f = KeyFuncInsertReport
-				f = &KeyGoFuncT{Func: KeyFuncInsertSelf, Name: fmt.Sprintf("%v", this.Unicode)}
-			}
-		} else {
-			f, ok = scanMap[this.Keycode]
-			if !ok {
-				f = &KeyGoFuncT{Func: nil, Name: ""}
-			}
+		f := this.lookupKeyFunc()
+		if f == nil {
+			continue
 		}
 		if fg, ok := f.(*KeyGoFuncT); !ok || fg.Func != nil {
 			io.WriteString(this.Writer, CURSOR_OFF)
 			cursorOnSwitch = false
+			if this.multiLine {
+				this.moveToLastRow()
+			}
+			this.eraseSuggestion()
 		}
 		rc := f.Call(ctx, &this)
+		if this.multiLine && rc != INTR && rc != ABORT {
+			this.repaintMultiLine()
+		}
+		if rc == CONTINUE && !cursorOnSwitch {
+			// the suggestion was erased above
+			this.drawHighlight(ctx)
+			this.drawSuggestion(ctx)
+		}
 		if rc != CONTINUE {
+			if this.multiLine {
+				this.moveToLastRow()
+			}
 			this.Writer.WriteByte('\n')
 			if !cursorOnSwitch {
 				io.WriteString(this.Writer, CURSOR_ON)
@@ -0,0 +1,167 @@
+package readline
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// AutoSuggest shows the rest of the line suggested in grey after the
+// cursor at the end of the line. Right and End accept it, and Alt-F
+// accepts its next word.
+var AutoSuggest = false
+
+// SuggestDirOnly makes the suggestion from the history only by the
+// commands executed in the current directory.
+var SuggestDirOnly = false
+
+// the escape sequences for the suggestion
+const (
+	suggestColor      = "\x1B[90m"
+	suggestResetColor = "\x1B[0m"
+)
+
+// SuggestHookT returns the whole line to suggest for text typed, or "".
+type SuggestHookT func(ctx context.Context, this *Buffer, text string) string
+
+var suggestHook SuggestHookT = suggestFromHistory
+
+func init() {
+	NAME2FUNC[F_FORWARD_WORD] = KeyFuncForwardWord
+	altMap[name2alt[K_ALT_F]] = name2func(F_FORWARD_WORD)
+}
+
+// SetSuggestHook sets the function to make the suggestion and returns
+// the previous one.
+func SetSuggestHook(hook SuggestHookT) (rv SuggestHookT) {
+	rv, suggestHook = suggestHook, hook
+	return
+}
+
+// suggestFromHistory returns the newest command in the history which
+// starts with text.
+func suggestFromHistory(ctx context.Context, this *Buffer, text string) string {
+	if text == "" {
+		return ""
+	}
+	wd := ""
+	dirHistory, _ := this.History.(IDirHistory)
+	if SuggestDirOnly {
+		if dirHistory == nil {
+			return ""
+		}
+		wd, _ = os.Getwd()
+	}
+	for i := this.History.Len() - 1; i >= 0; i-- {
+		line := this.History.At(i)
+		if len(line) <= len(text) || !strings.HasPrefix(line, text) ||
+			strings.ContainsRune(line, '\n') {
+			continue
+		}
+		if SuggestDirOnly && !sameDir(dirHistory.DirAt(i), wd) {
+			continue
+		}
+		return line
+	}
+	return ""
+}
+
+// drawSuggestion shows the suggestion after the cursor when it is at the
+// end of the line. The part which does not fit in the view is not shown.
+func (this *Buffer) drawSuggestion(ctx context.Context) {
+	this.suggestion = ""
+	if !AutoSuggest || this.multiLine || this.Cursor < this.Length {
+		return
+	}
+	text := this.String()
+	line := suggestHook(ctx, this, text)
+	if len(line) <= len(text) || !strings.HasPrefix(line, text) {
+		return
+	}
+	rest := line[len(text):]
+	var buffer strings.Builder
+	w := this.GetWidthBetween(this.ViewStart, this.Cursor)
+	width := 0
+	for _, ch := range rest {
+		if ch < ' ' {
+			break
+		}
+		w1 := GetCharWidth(ch)
+		if w+width+w1 >= this.ViewWidth() {
+			break
+		}
+		buffer.WriteRune(ch)
+		width += w1
+	}
+	if width <= 0 {
+		return
+	}
+	this.suggestion = rest
+	io.WriteString(this.Writer, suggestColor+buffer.String()+suggestResetColor)
+	this.Backspace(width)
+}
+
+// eraseSuggestion erases the suggestion shown. this.suggestion is kept
+// for the key functions to accept it.
+func (this *Buffer) eraseSuggestion() {
+	if this.suggestion != "" {
+		this.Eraseline()
+	}
+}
+
+// acceptSuggestion inserts the suggestion, or its next word when word is
+// true. It returns false when nothing is suggested.
+func (this *Buffer) acceptSuggestion(word bool) bool {
+	if this.suggestion == "" || this.Cursor < this.Length {
+		return false
+	}
+	rest := []rune(this.suggestion)
+	if word {
+		i := 0
+		for i < len(rest) && unicode.IsSpace(rest[i]) {
+			i++
+		}
+		for i < len(rest) && !unicode.IsSpace(rest[i]) {
+			i++
+		}
+		rest = rest[:i]
+	}
+	this.suggestion = ""
+	this.InsertAndRepaint(string(rest))
+	return true
+}
+
+// KeyFuncForwardOrAccept accepts the suggestion at the end of the line,
+// or moves the cursor right.
+func KeyFuncForwardOrAccept(ctx context.Context, this *Buffer) Result { // Right, Ctrl-F
+	if this.acceptSuggestion(false) {
+		return CONTINUE
+	}
+	return KeyFuncForward(ctx, this)
+}
+
+// KeyFuncTailOrAccept accepts the suggestion at the end of the line,
+// or moves the cursor to the end.
+func KeyFuncTailOrAccept(ctx context.Context, this *Buffer) Result { // End, Ctrl-E
+	if this.acceptSuggestion(false) {
+		return CONTINUE
+	}
+	return KeyFuncTail(ctx, this)
+}
+
+// KeyFuncForwardWord accepts the next word of the suggestion at the end
+// of the line, or moves the cursor to the end of the next word.
+func KeyFuncForwardWord(ctx context.Context, this *Buffer) Result { // Alt-F
+	if this.acceptSuggestion(true) {
+		return CONTINUE
+	}
+	for this.Cursor < this.Length && unicode.IsSpace(this.Buffer[this.Cursor]) {
+		KeyFuncForward(ctx, this)
+	}
+	for this.Cursor < this.Length && !unicode.IsSpace(this.Buffer[this.Cursor]) {
+		KeyFuncForward(ctx, this)
+	}
+	return CONTINUE
+}
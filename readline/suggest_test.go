@@ -0,0 +1,90 @@
+package readline
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+// testDirHistory is the history which has the directories.
+type testDirHistory struct {
+	testHistory
+	dirs []string
+}
+
+func (h *testDirHistory) DirAt(i int) string { return h.dirs[i] }
+
+func TestSuggestFromHistory(t *testing.T) {
+	history := testHistory{"git commit", "git log", "git", "if a\nb", "ls"}
+	this := newTestBuffer(history, "")
+	ctx := context.Background()
+	tests := []struct {
+		text string
+		line string
+	}{
+		{"", ""},
+		{"g", "git"}, // the newest one
+		{"git ", "git log"},
+		{"git c", "git commit"},
+		{"git log", ""}, // nothing to add
+		{"i", ""},       // the lines edited over multiple lines
+		{"x", ""},
+	}
+	for _, test := range tests {
+		if line := suggestFromHistory(ctx, this, test.text); line != test.line {
+			t.Errorf("suggestFromHistory(%q)=%q", test.text, line)
+		}
+	}
+
+	// only the commands executed in the current directory
+	defer func(value bool) { SuggestDirOnly = value }(SuggestDirOnly)
+	SuggestDirOnly = true
+	if line := suggestFromHistory(ctx, this, "g"); line != "" {
+		t.Errorf("no directories: %q", line)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	this = newTestBuffer(&testDirHistory{
+		testHistory: testHistory{"git commit", "git log", "git"},
+		dirs:        []string{wd, wd, "/elsewhere"},
+	}, "")
+	if line := suggestFromHistory(ctx, this, "g"); line != "git log" {
+		t.Errorf("SuggestDirOnly: %q", line)
+	}
+}
+
+func TestAcceptSuggestion(t *testing.T) {
+	this := newTestBuffer(testHistory{}, "git")
+	if this.acceptSuggestion(true) {
+		t.Fatal("accepted nothing")
+	}
+
+	// one word at a time with the spaces before it
+	this.suggestion = "  commit -m x"
+	if !this.acceptSuggestion(true) || this.String() != "git  commit" {
+		t.Fatalf("first word: %q", this.String())
+	}
+	this.suggestion = " -m x"
+	if !this.acceptSuggestion(true) || this.String() != "git  commit -m" {
+		t.Fatalf("second word: %q", this.String())
+	}
+	if this.suggestion != "" || this.acceptSuggestion(true) {
+		t.Fatalf("the suggestion is left: %q", this.suggestion)
+	}
+
+	// the whole line
+	this.suggestion = " x"
+	if !this.acceptSuggestion(false) || this.String() != "git  commit -m x" ||
+		this.Cursor != this.Length {
+		t.Fatalf("whole: %q,%d", this.String(), this.Cursor)
+	}
+
+	// not at the end of the line
+	this.suggestion = " y"
+	this.Cursor = 0
+	if this.acceptSuggestion(false) || this.String() != "git  commit -m x" {
+		t.Fatalf("not at the end: %q", this.String())
+	}
+}
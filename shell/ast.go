@@ -0,0 +1,200 @@
+package shell
+
+import (
+	"sort"
+)
+
+// Node is the node of the syntax tree made by Parse.
+// Pos and End are the byte offsets of the node in the source line.
+type Node interface {
+	Pos() int
+	End() int
+}
+
+// Span is the range of the source line [Start,Stop).
+type Span struct {
+	Start int
+	Stop  int
+}
+
+// Pos returns the offset of the first byte of the node.
+func (s Span) Pos() int { return s.Start }
+
+// End returns the offset of the byte following the node.
+func (s Span) End() int { return s.Stop }
+
+// WordNode is a word of the command line.
+type WordNode struct {
+	Span
+	Source string // the text in the source line as it is
+	Raw    string // %VAR% are expanded, but quotations are kept
+	Text   string // %VAR% are expanded and quotations are removed
+
+	// Substitutions are `$( ... )` in the word. They are replaced with
+	// the output of the commands on execution.
+	Substitutions []*SubstitutionNode
+}
+
+// SubstitutionNode is the command substitution `$( Text )` or
+// the process substitution `<( Text )` or `>( Text )`.
+type SubstitutionNode struct {
+	Span
+	Operator string // `$(`, `<(` or `>(`
+	Text     string
+	Quoted   bool // true when it is enclosed with double quotations
+}
+
+// OperatorNode is an operator: `|` `|&` `&&` `||` `&` or `;`
+type OperatorNode struct {
+	Span
+	Text string
+}
+
+// RedirectNode is a redirection such as `>file`, `2>>file` or `2>&1`
+type RedirectNode struct {
+	Span
+	Op     string    // the operator such as `>`, `2>>` or `>&1`
+	Target *WordNode // nil for the duplication `>&N`
+	r      *_Redirecter
+}
+
+// CommandNode is a simple command with its redirections.
+type CommandNode struct {
+	Span
+	Words     []*WordNode
+	Redirects []*RedirectNode
+}
+
+// Args returns the words whose quotations are removed.
+func (c *CommandNode) Args() []string {
+	args := make([]string, len(c.Words))
+	for i, w := range c.Words {
+		args[i] = w.Text
+	}
+	return args
+}
+
+// RawArgs returns the words whose quotations are kept.
+func (c *CommandNode) RawArgs() []string {
+	args := make([]string, len(c.Words))
+	for i, w := range c.Words {
+		args[i] = w.Raw
+	}
+	return args
+}
+
+// GroupNode is `( Body )` which runs in a subshell
+// or `{ Body }` which runs in the current shell.
+type GroupNode struct {
+	Span
+	Subshell  bool
+	Open      *OperatorNode
+	Body      Node
+	Close     *OperatorNode // nil when not closed
+	Redirects []*RedirectNode
+}
+
+// PipelineNode is commands connected with `|` or `|&`.
+// len(Operators) is always len(Commands)-1.
+type PipelineNode struct {
+	Span
+	Commands  []Node
+	Operators []*OperatorNode
+}
+
+// AndOrNode is `Left && Right` or `Left || Right`.
+type AndOrNode struct {
+	Span
+	Left     Node
+	Operator *OperatorNode
+	Right    Node
+}
+
+// BackgroundNode is `Body &`.
+type BackgroundNode struct {
+	Span
+	Body     Node
+	Operator *OperatorNode
+}
+
+// SequenceNode is statements separated with `;` (or `&` for BackgroundNode).
+type SequenceNode struct {
+	Span
+	Elements   []Node
+	Separators []*OperatorNode
+}
+
+// Walk calls f for node and its descendants in the order of the source
+// until f returns false.
+func Walk(node Node, f func(Node) bool) bool {
+	if node == nil {
+		return true
+	}
+	if !f(node) {
+		return false
+	}
+	switch n := node.(type) {
+	case *CommandNode:
+		children := make([]Node, 0, len(n.Words)+len(n.Redirects))
+		for _, w := range n.Words {
+			children = append(children, w)
+		}
+		for _, r := range n.Redirects {
+			children = append(children, r)
+		}
+		return walkSorted(children, f)
+	case *WordNode:
+		for _, s := range n.Substitutions {
+			if !Walk(s, f) {
+				return false
+			}
+		}
+	case *RedirectNode:
+		if n.Target != nil {
+			return Walk(n.Target, f)
+		}
+	case *GroupNode:
+		children := []Node{n.Open}
+		if n.Body != nil {
+			children = append(children, n.Body)
+		}
+		if n.Close != nil {
+			children = append(children, n.Close)
+		}
+		for _, r := range n.Redirects {
+			children = append(children, r)
+		}
+		return walkSorted(children, f)
+	case *PipelineNode:
+		children := make([]Node, 0, len(n.Commands)+len(n.Operators))
+		children = append(children, n.Commands...)
+		for _, o := range n.Operators {
+			children = append(children, o)
+		}
+		return walkSorted(children, f)
+	case *AndOrNode:
+		return Walk(n.Left, f) && Walk(n.Operator, f) && Walk(n.Right, f)
+	case *BackgroundNode:
+		return Walk(n.Body, f) && Walk(n.Operator, f)
+	case *SequenceNode:
+		children := make([]Node, 0, len(n.Elements)+len(n.Separators))
+		children = append(children, n.Elements...)
+		for _, s := range n.Separators {
+			children = append(children, s)
+		}
+		return walkSorted(children, f)
+	}
+	return true
+}
+
+func walkSorted(nodes []Node, f func(Node) bool) bool {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Pos() < nodes[j].Pos()
+	})
+	for _, node := range nodes {
+		if !Walk(node, f) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package shell
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// SubshellCommand returns the command line to start the shell itself
+// which runs text for `( ... )`. The subshell runs in the child process
+// not to change the current directory and the environment variables of
+// the shell.
+var SubshellCommand = func(text string) ([]string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return []string{exe, "-b", base64.StdEncoding.EncodeToString([]byte(text))}, nil
+}
+
+// executeGroup runs the body of `( ... )` or `{ ... }`.
+// The standard I/O of sh are already redirected for the group.
+func (sh *Shell) executeGroup(ctx context.Context, group *GroupNode) (int, error) {
+	if !group.Subshell {
+		return sh.Execute(ctx, group.Body)
+	}
+	if group.Body == nil {
+		return 0, nil
+	}
+	text, ok := ctx.Value(sourceTextID).(string)
+	if !ok || group.Body.End() > len(text) {
+		return 255, errors.New("subshell: the source text is not found")
+	}
+	args, err := SubshellCommand(text[group.Body.Pos():group.Body.End()])
+	if err != nil {
+		return 255, err
+	}
+	cmd := sh.Command()
+	defer cmd.Close()
+	cmd.SetArgs(args)
+	errorlevel, err := cmd.startProcess(ctx, exec.Command(args[0], args[1:]...))
+	if _, ok := err.(*exec.ExitError); ok {
+		// the errorlevel tells it.
+		err = nil
+	}
+	return errorlevel, err
+}
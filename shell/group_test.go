@@ -0,0 +1,124 @@
+package shell
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testSubshellEnv makes the test binary run as the subshell started by
+// SubshellCommand with `-b BASE64`.
+const testSubshellEnv = "NYAGOS_TEST_SUBSHELL"
+
+// testHook runs the commands for the tests without the built-in commands.
+func testHook(ctx context.Context, cmd *Cmd) (int, bool, error) {
+	args := cmd.args
+	if len(args) != 2 {
+		return 0, false, nil
+	}
+	switch args[0] {
+	case "rc":
+		n, err := strconv.Atoi(args[1])
+		return n, true, err
+	case "cd":
+		return 0, true, os.Chdir(args[1])
+	case "setenv":
+		return 0, true, os.Setenv(testSubshellEnv+"_VAR", args[1])
+	case "touch":
+		return 0, true, ioutil.WriteFile(args[1], nil, 0666)
+	case "waitfile":
+		for {
+			if _, err := os.Stat(args[1]); err == nil {
+				return 0, true, nil
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	return 0, false, nil
+}
+
+func init() {
+	if os.Getenv(testSubshellEnv) == "" || len(os.Args) != 3 || os.Args[1] != "-b" {
+		return
+	}
+	text, err := base64.StdEncoding.DecodeString(os.Args[2])
+	if err != nil {
+		os.Exit(255)
+	}
+	SetHook(testHook)
+	errorlevel, _ := New().Interpret(context.Background(), string(text))
+	os.Exit(errorlevel)
+}
+
+func TestSubshell(t *testing.T) {
+	os.Setenv(testSubshellEnv, "1")
+	defer os.Unsetenv(testSubshellEnv)
+	save := SetHook(testHook)
+	defer SetHook(save)
+
+	dir, err := ioutil.TempDir("", "subshell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// the subshell in the background changes the directory and the
+	// environment, and waits until the shell checks its own.
+	ready := filepath.Join(dir, "ready")
+	done := filepath.Join(dir, "done")
+	_, err = New().Interpret(ctx,
+		`(cd "`+dir+`" ; setenv x ; touch ready ; waitfile done) &`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := FindJob("%%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := os.Stat(ready); err == nil {
+			break
+		}
+		if job.Done() {
+			t.Fatalf("the subshell finished: %s", job.Status())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if wd1, err := os.Getwd(); err != nil || wd1 != wd {
+		t.Errorf("the directory is changed to %s,%v", wd1, err)
+	}
+	if value, ok := os.LookupEnv(testSubshellEnv + "_VAR"); ok {
+		t.Errorf("the environment variable is set to %q", value)
+	}
+	ioutil.WriteFile(done, nil, 0666)
+	if errorlevel, err := job.Wait(ctx); errorlevel != 0 || err != nil {
+		t.Fatalf("Wait()=%d,%v", errorlevel, err)
+	}
+
+	// the errorlevel of the subshell
+	tests := []struct {
+		line       string
+		errorlevel int
+	}{
+		{"(rc 3)", 3},
+		{"(rc 0 ; rc 4) | rc 0", 0},
+		{"(rc 0) && rc 5", 5},
+		{"(rc 1) || (rc 0 ; rc 6)", 6},
+	}
+	for _, test := range tests {
+		errorlevel, err := New().Interpret(ctx, test.line)
+		if err != nil || errorlevel != test.errorlevel {
+			t.Errorf("%s: %d,%v", test.line, errorlevel, err)
+		}
+	}
+}
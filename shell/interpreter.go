@@ -10,9 +10,6 @@ import (
 	"reflect"
 	"strings"
 	"sync"
-	"syscall"
-
-	"github.com/zetamatta/go-findfile"
 
 	"github.com/zetamatta/nyagos/defined"
 	"github.com/zetamatta/nyagos/dos"
@@ -47,6 +44,7 @@ type Shell struct {
 	Stdout       *os.File
 	Stderr       *os.File
 	Stdin        *os.File
+	ExtraFiles   []*os.File // the file descriptors 3 and later
 	Console      io.Writer
 	tag          CloneCloser
 	IsBackGround bool
@@ -59,6 +57,42 @@ func (sh *Shell) Term() io.Writer        { return sh.Console }
 func (sh *Shell) Tag() CloneCloser       { return sh.tag }
 func (sh *Shell) SetTag(tag CloneCloser) { sh.tag = tag }
 
+// File returns the file of the file descriptor no or nil.
+func (sh *Shell) File(no int) *os.File {
+	switch no {
+	case 0:
+		return sh.Stdin
+	case 1:
+		return sh.Stdout
+	case 2:
+		return sh.Stderr
+	}
+	if no >= 3 && no-3 < len(sh.ExtraFiles) {
+		return sh.ExtraFiles[no-3]
+	}
+	return nil
+}
+
+// SetFile replaces the file of the file descriptor no.
+func (sh *Shell) SetFile(no int, fd *os.File) {
+	switch no {
+	case 0:
+		sh.Stdin = fd
+	case 1:
+		sh.Stdout = fd
+	case 2:
+		sh.Stderr = fd
+	default:
+		// ExtraFiles may be shared with the parent shell.
+		files := append([]*os.File{}, sh.ExtraFiles...)
+		for len(files) < no-2 {
+			files = append(files, nil)
+		}
+		files[no-3] = fd
+		sh.ExtraFiles = files
+	}
+}
+
 type Cmd struct {
 	Shell
 	args            []string
@@ -110,11 +144,12 @@ func New() *Shell {
 func (sh *Shell) Command() *Cmd {
 	cmd := &Cmd{
 		Shell: Shell{
-			Stdin:   sh.Stdin,
-			Stdout:  sh.Stdout,
-			Stderr:  sh.Stderr,
-			Console: sh.Console,
-			tag:     sh.tag,
+			Stdin:      sh.Stdin,
+			Stdout:     sh.Stdout,
+			Stderr:     sh.Stderr,
+			ExtraFiles: sh.ExtraFiles,
+			Console:    sh.Console,
+			tag:        sh.tag,
 		},
 	}
 	if sh.session != nil {
@@ -154,6 +189,28 @@ var OnCommandNotFound = func(ctx context.Context, cmd *Cmd, err error) error {
 
 var LastErrorLevel int
 
+// LastPipeStatus is the errorlevels of all the commands of the last pipeline.
+var LastPipeStatus []int
+
+// ErrExit is the switch to stop the script when a command fails
+// out of the left side of `&&` and `||`.
+var ErrExit = false
+
+// XTrace is the switch to print the commands before execution.
+var XTrace = false
+
+// XTracePrefix is printed before the commands by XTrace.
+var XTracePrefix = "+ "
+
+type conditionalIDT struct{}
+
+// conditionalID is the key-object to tell that the errorlevel is tested.
+var conditionalID conditionalIDT
+
+// PipeFail is the switch to make the errorlevel of the pipeline
+// the last non-zero errorlevel of its commands.
+var PipeFail = false
+
 func makeCmdline(args, rawargs []string) string {
 	var buffer strings.Builder
 	for i, s := range args {
@@ -208,7 +265,7 @@ func (cmd *Cmd) spawnvpSilent(ctx context.Context) (int, error) {
 		print("exec.LookPath(", cmd.args[0], ")==", fullpath, "\n")
 	}
 	if WildCardExpansionAlways {
-		cmd.args = findfile.Globs(cmd.args)
+		cmd.args = dos.Globs(cmd.args)
 	}
 	if cmd.UseShellExecute {
 		// GUI Application
@@ -230,20 +287,25 @@ func (cmd *Cmd) spawnvpSilent(ctx context.Context) (int, error) {
 		}
 	}
 	// Do not use exec.CommandContext because it cancels background process.
-	xcmd := exec.Command(cmd.args[0], cmd.args[1:]...)
+	return cmd.startProcess(ctx, exec.Command(cmd.args[0], cmd.args[1:]...))
+}
+
+// startProcess runs xcmd with the standard I/O of cmd as a process of
+// the job in ctx, and returns its errorlevel.
+func (cmd *Cmd) startProcess(ctx context.Context, xcmd *exec.Cmd) (int, error) {
 	xcmd.Stdin = cmd.Stdin
 	xcmd.Stdout = cmd.Stdout
 	xcmd.Stderr = cmd.Stderr
 
-	if xcmd.SysProcAttr == nil {
-		xcmd.SysProcAttr = new(syscall.SysProcAttr)
-	}
-	cmdline := makeCmdline(xcmd.Args, cmd.rawArgs)
-	if defined.DBG {
-		println(cmdline)
+	setCommandLine(xcmd, cmd.rawArgs)
+	setExtraFiles(xcmd, cmd.ExtraFiles)
+	err := xcmd.Start()
+	if err == nil {
+		if job := jobFromContext(ctx); job != nil {
+			job.addProcess(xcmd.Process)
+		}
+		err = xcmd.Wait()
 	}
-	xcmd.SysProcAttr.CmdLine = cmdline
-	err := xcmd.Run()
 	errorlevel, errorlevelOk := dos.GetErrorLevel(xcmd)
 	if errorlevelOk {
 		return errorlevel, err
@@ -293,146 +355,257 @@ func (sh *Shell) Interpret(ctx context.Context, text string) (errorlevel int, fi
 	if sh == nil {
 		return 255, errors.New("Fatal Error: Interpret: instance is nil")
 	}
-	errorlevel = 0
-	finalerr = nil
-
-	statements, statementsErr := Parse(text)
-	if statementsErr != nil {
+	node, err := Parse(text)
+	if err != nil {
 		if defined.DBG {
-			print("Parse Error:", statementsErr.Error(), "\n")
+			print("Parse Error:", err.Error(), "\n")
 		}
-		return 0, statementsErr
+		return 255, err
 	}
-	if argsHook != nil {
-		if defined.DBG {
-			print("call argsHook\n")
-		}
-		for _, pipeline := range statements {
-			for _, state := range pipeline {
-				var err error
-				state.Args, err = argsHook(ctx, sh, state.Args)
-				if err != nil {
-					return 255, err
-				}
+	if node == nil {
+		return 0, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = context.WithValue(ctx, sourceTextID, text)
+	return sh.Execute(ctx, node)
+}
+
+// Execute runs the syntax tree made by Parse.
+func (sh *Shell) Execute(ctx context.Context, node Node) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	switch n := node.(type) {
+	case *SequenceNode:
+		errorlevel := 0
+		var err error
+		for _, element := range n.Elements {
+			if ctx != nil && ctx.Err() != nil {
+				return errorlevel, ctx.Err()
+			}
+			errorlevel, err = sh.Execute(ctx, element)
+			if err == io.EOF {
+				break
 			}
 		}
-		if defined.DBG {
-			print("done argsHook\n")
+		return errorlevel, err
+	case *AndOrNode:
+		errorlevel, err := sh.Execute(context.WithValue(ctx, conditionalID, true), n.Left)
+		if (n.Operator.Text == "&&") != (errorlevel == 0) {
+			return errorlevel, err
 		}
+		return sh.Execute(ctx, n.Right)
+	case *BackgroundNode:
+		return sh.executeBackground(ctx, n.Body)
+	case *PipelineNode:
+		return sh.executePipeline(ctx, n.Commands, n.Operators)
+	default:
+		return sh.executePipeline(ctx, []Node{node}, nil)
 	}
-	for _, pipeline := range statements {
-		for i, state := range pipeline {
-			if state.Term == "|" && (i+1 >= len(pipeline) || len(pipeline[i+1].Args) <= 0) {
-				return 255, errors.New("The syntax of the command is incorrect.")
-			}
+}
+
+// cloneForBackground makes the copy of the shell to run in the other goroutine.
+func (sh *Shell) cloneForBackground(ctx context.Context) (context.Context, *Shell, error) {
+	newctx, newsh, err := sh.clone(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	newsh.IsBackGround = true
+	return newctx, newsh, nil
+}
+
+// clone makes the copy of the shell whose tag is cloned.
+func (sh *Shell) clone(ctx context.Context) (context.Context, *Shell, error) {
+	newsh := *sh
+	if tag := sh.Tag(); tag != nil {
+		newctx, newtag, err := tag.Clone(ctx)
+		if err != nil {
+			return nil, nil, err
 		}
+		newsh.SetTag(newtag)
+		return newctx, &newsh, nil
 	}
+	return ctx, &newsh, nil
+}
 
-	for _, pipeline := range statements {
+func (sh *Shell) closeBackground() {
+	if tag := sh.Tag(); tag != nil {
+		if err := tag.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
+	}
+}
 
-		var pipeIn *os.File = nil
-		isBackGround := sh.IsBackGround
-		for _, state := range pipeline {
-			if state.Term == "&" {
-				isBackGround = true
-				break
+type sourceTextIDT struct{}
+
+// sourceTextID is the key-object to find the text given to Interpret.
+var sourceTextID sourceTextIDT
+
+// executeBackground starts node as a job.
+func (sh *Shell) executeBackground(ctx context.Context, node Node) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	commandLine := ""
+	if text, ok := ctx.Value(sourceTextID).(string); ok && node.End() <= len(text) {
+		commandLine = strings.TrimSpace(text[node.Pos():node.End()])
+	}
+	job := newJob(commandLine)
+	ctx, job.cancel = context.WithCancel(detachedContext{ctx})
+	ctx = context.WithValue(ctx, jobID, job)
+
+	newctx, newsh, err := sh.cloneForBackground(ctx)
+	if err != nil {
+		job.finish(-1)
+		removeJob(job)
+		fmt.Fprintln(os.Stderr, err.Error())
+		return -1, err
+	}
+	go func() {
+		errorlevel, _ := newsh.Execute(newctx, node)
+		newsh.closeBackground()
+		job.cancel()
+		job.finish(errorlevel)
+	}()
+	return 0, nil
+}
+
+func (sh *Shell) executePipeline(ctx context.Context, pipeline []Node, operators []*OperatorNode) (errorlevel int, finalerr error) {
+	var pipeIn *os.File = nil
+	var wg sync.WaitGroup
+	statuses := make([]int, len(pipeline))
+	for i, node := range pipeline {
+		var state *CommandNode
+		var group *GroupNode
+		var redirects []*RedirectNode
+		var args, rawArgs []string
+		var closers []io.Closer
+		var err error
+		switch n := node.(type) {
+		case *CommandNode:
+			state = n
+			redirects = n.Redirects
+			args, rawArgs, closers, err = sh.expandWords(ctx, state.Words)
+			if err != nil {
+				for _, c := range closers {
+					c.Close()
+				}
+				return 255, err
 			}
-		}
-		var wg sync.WaitGroup
-		shutdown_immediately := false
-		for i, state := range pipeline {
-			if defined.DBG {
-				print(i, ": pipeline loop(", state.Args[0], ")\n")
+			args, err = argsHook(ctx, sh, args)
+			if err != nil {
+				return 255, err
 			}
-			cmd := sh.Command()
-			cmd.IsBackGround = isBackGround
-
-			if pipeIn != nil {
-				cmd.Stdin = pipeIn
-				cmd.Closers = append(cmd.Closers, pipeIn)
-				pipeIn = nil
+			if defined.DBG && len(args) > 0 {
+				print(i, ": pipeline loop(", args[0], ")\n")
+			}
+			if XTrace {
+				fmt.Fprintln(sh.Stderr, XTracePrefix+makeCmdline(args, rawArgs))
 			}
+		case *GroupNode:
+			group = n
+			redirects = n.Redirects
+		default:
+			return 255, &SyntaxError{Pos: node.Pos(), Msg: ERR_SYNTAX}
+		}
+		cmd := sh.Command()
+		cmd.IsBackGround = sh.IsBackGround
+		cmd.Closers = append(cmd.Closers, closers...)
+
+		if pipeIn != nil {
+			cmd.Stdin = pipeIn
+			cmd.Closers = append(cmd.Closers, pipeIn)
+			pipeIn = nil
+		}
 
-			var err error
-			if state.Term[0] == '|' {
-				var pipeOut *os.File
-				pipeIn, pipeOut, err = os.Pipe()
-				cmd.Stdout = pipeOut
-				if state.Term == "|&" {
-					cmd.Stderr = pipeOut
-				}
-				cmd.Closers = append(cmd.Closers, pipeOut)
+		if i < len(operators) {
+			var pipeOut *os.File
+			pipeIn, pipeOut, err = os.Pipe()
+			if err != nil {
+				return 255, err
 			}
+			cmd.Stdout = pipeOut
+			if operators[i].Text == "|&" {
+				cmd.Stderr = pipeOut
+			}
+			cmd.Closers = append(cmd.Closers, pipeOut)
+		}
 
-			for _, red := range state.Redirect {
-				var fd *os.File
-				fd, err = red.OpenOn(cmd)
+		for _, red := range redirects {
+			if red.Target != nil && len(red.Target.Substitutions) > 0 {
+				path, closers, err := sh.expandWord(ctx, red.Target)
+				cmd.Closers = append(cmd.Closers, closers...)
 				if err != nil {
-					return 0, err
+					cmd.Close()
+					return 255, err
 				}
+				red.r.SetPath(path)
+			}
+			var fd *os.File
+			fd, err = red.r.OpenOn(cmd)
+			if err != nil {
+				cmd.Close()
+				return 0, err
+			}
+			if fd != nil {
 				defer fd.Close()
 			}
+		}
 
-			cmd.args = state.Args
-			cmd.rawArgs = state.RawArgs
-			if i > 0 {
-				cmd.IsBackGround = true
-			}
+		if i > 0 {
+			cmd.IsBackGround = true
+		}
+		if state != nil {
+			cmd.args = args
+			cmd.rawArgs = rawArgs
 			if len(pipeline) == 1 && dos.IsGui(cmd.FullPath()) {
 				cmd.UseShellExecute = true
 			}
-			if i == len(pipeline)-1 && state.Term != "&" {
-				// foreground execution.
-				errorlevel, finalerr = cmd.Spawnvp(ctx)
-				LastErrorLevel = errorlevel
-				cmd.Close()
-			} else {
-				// background
-				if !isBackGround {
-					wg.Add(1)
-				}
-				newctx := ctx
-				if tag := cmd.Tag(); tag != nil {
-					var newtag CloneCloser
-					if newctx, newtag, err = tag.Clone(ctx); err != nil {
-						fmt.Fprintln(os.Stderr, err.Error())
-						return -1, err
-					} else {
-						cmd.SetTag(newtag)
-					}
-				}
-				go func(ctx1 context.Context, cmd1 *Cmd) {
-					if !isBackGround {
-						defer wg.Done()
-					}
-					cmd1.Spawnvp(ctx1)
-					if tag := cmd1.Tag(); tag != nil {
-						if err := tag.Close(); err != nil {
-							fmt.Fprintln(os.Stderr, err.Error())
-						}
-					}
-					cmd1.Close()
-				}(newctx, cmd)
+		}
+		run := func(ctx1 context.Context, cmd1 *Cmd) (int, error) {
+			if group != nil {
+				return cmd1.Shell.executeGroup(ctx1, group)
 			}
+			return cmd1.Spawnvp(ctx1)
 		}
-		if !isBackGround {
-			wg.Wait()
-			if shutdown_immediately {
-				return errorlevel, nil
+		if i == len(pipeline)-1 {
+			errorlevel, finalerr = run(ctx, cmd)
+			statuses[i] = errorlevel
+			cmd.Close()
+		} else {
+			newctx, newsh, err := cmd.Shell.cloneForBackground(ctx)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				return -1, err
 			}
-			if len(pipeline) > 0 {
-				switch pipeline[len(pipeline)-1].Term {
-				case "&&":
-					if errorlevel != 0 {
-						return errorlevel, nil
-					}
-				case "||":
-					if errorlevel == 0 {
-						return errorlevel, nil
-					}
-				}
+			cmd.SetTag(newsh.Tag())
+			wg.Add(1)
+			go func(ctx1 context.Context, cmd1 *Cmd, i int) {
+				defer wg.Done()
+				statuses[i], _ = run(ctx1, cmd1)
+				cmd1.closeBackground()
+				cmd1.Close()
+			}(newctx, cmd, i)
+		}
+	}
+	wg.Wait()
+	if PipeFail {
+		for _, status := range statuses {
+			if status != 0 {
+				errorlevel = status
 			}
 		}
 	}
+	if !sh.IsBackGround {
+		LastErrorLevel = errorlevel
+		LastPipeStatus = statuses
+		if ErrExit && errorlevel != 0 && ctx.Value(conditionalID) == nil &&
+			(finalerr == nil || IsAlreadyReported(finalerr)) {
+			// io.EOF stops the script as `exit` does.
+			finalerr = io.EOF
+		}
+	}
 	return
 }
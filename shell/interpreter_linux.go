@@ -0,0 +1,14 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setCommandLine does nothing because argv is passed to the process as it is.
+func setCommandLine(xcmd *exec.Cmd, rawArgs []string) {}
+
+// setExtraFiles passes the file descriptors 3 and later to the process.
+func setExtraFiles(xcmd *exec.Cmd, files []*os.File) {
+	xcmd.ExtraFiles = files
+}
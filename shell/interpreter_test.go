@@ -3,12 +3,23 @@ package shell
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"testing"
 )
 
 func TestInterpret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "interpret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
 	ctx := context.Background()
-	_, err := New().Interpret(ctx, "ls.exe | cat.exe -n > hogehoge")
+	output := filepath.Join(dir, "hogehoge")
+	_, err = New().Interpret(ctx, `ls.exe | cat.exe -n > "`+output+`"`)
 	fmt.Println(err)
 }
 
@@ -20,3 +31,43 @@ func TestMain(t *testing.T) {
 		t.Fatalf(`Fail "%s" != "%s"`, out, tst)
 	}
 }
+
+func TestPipeStatus(t *testing.T) {
+	// `rc N` exits with N without starting a process.
+	save := SetHook(func(ctx context.Context, cmd *Cmd) (int, bool, error) {
+		if len(cmd.args) == 2 && cmd.args[0] == "rc" {
+			n, err := strconv.Atoi(cmd.args[1])
+			return n, true, err
+		}
+		return 0, false, nil
+	})
+	defer SetHook(save)
+	defer func(value bool) { PipeFail = value }(PipeFail)
+
+	tests := []struct {
+		line       string
+		pipeFail   bool
+		errorlevel int
+		statuses   []int
+	}{
+		{"rc 0", false, 0, []int{0}},
+		{"rc 2 | rc 3 | rc 0", false, 0, []int{2, 3, 0}},
+		{"rc 2 | rc 3 | rc 0", true, 3, []int{2, 3, 0}},
+		{"rc 2 | rc 0 | rc 0", true, 2, []int{2, 0, 0}},
+		{"rc 0 | rc 0 | rc 4", false, 4, []int{0, 0, 4}},
+		{"rc 0 | rc 0", true, 0, []int{0, 0}},
+	}
+	ctx := context.Background()
+	for _, test := range tests {
+		PipeFail = test.pipeFail
+		errorlevel, err := New().Interpret(ctx, test.line)
+		if err != nil {
+			t.Fatalf("%s: %v", test.line, err)
+		}
+		if errorlevel != test.errorlevel || LastErrorLevel != test.errorlevel ||
+			!reflect.DeepEqual(LastPipeStatus, test.statuses) {
+			t.Errorf("%s (pipefail=%v): %d,%d,%v", test.line, test.pipeFail,
+				errorlevel, LastErrorLevel, LastPipeStatus)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/zetamatta/nyagos/defined"
+)
+
+// setCommandLine gives the raw command-line to CreateProcess
+// because Windows' programs parse it by themselves.
+func setCommandLine(xcmd *exec.Cmd, rawArgs []string) {
+	if xcmd.SysProcAttr == nil {
+		xcmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	cmdline := makeCmdline(xcmd.Args, rawArgs)
+	if defined.DBG {
+		println(cmdline)
+	}
+	xcmd.SysProcAttr.CmdLine = cmdline
+}
+
+// setExtraFiles does nothing because CreateProcess can not pass
+// the file descriptors 3 and later.
+func setExtraFiles(xcmd *exec.Cmd, files []*os.File) {}
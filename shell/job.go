@@ -0,0 +1,232 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job is the command-line started with `&`.
+type Job struct {
+	ID          int
+	CommandLine string
+	StartTime   time.Time
+	EndTime     time.Time
+	ErrorLevel  int
+
+	mutex     sync.Mutex
+	processes []*os.Process
+	killed    bool
+	cancel    func()
+	done      chan struct{}
+}
+
+type jobIDT struct{}
+
+// jobID is the key-object to find the job in the context object.
+var jobID jobIDT
+
+var jobTable struct {
+	sync.Mutex
+	jobs []*Job
+}
+
+func newJob(commandLine string) *Job {
+	jobTable.Lock()
+	defer jobTable.Unlock()
+
+	id := 1
+	for _, j := range jobTable.jobs {
+		if j.ID >= id {
+			id = j.ID + 1
+		}
+	}
+	job := &Job{
+		ID:          id,
+		CommandLine: commandLine,
+		StartTime:   time.Now(),
+		done:        make(chan struct{}),
+	}
+	jobTable.jobs = append(jobTable.jobs, job)
+	return job
+}
+
+func jobFromContext(ctx context.Context) *Job {
+	if ctx == nil {
+		return nil
+	}
+	job, _ := ctx.Value(jobID).(*Job)
+	return job
+}
+
+func (job *Job) addProcess(p *os.Process) {
+	job.mutex.Lock()
+	job.processes = append(job.processes, p)
+	job.mutex.Unlock()
+}
+
+func (job *Job) finish(errorlevel int) {
+	job.mutex.Lock()
+	job.ErrorLevel = errorlevel
+	job.EndTime = time.Now()
+	job.mutex.Unlock()
+	close(job.done)
+}
+
+// Pids returns the process-ids started by the job.
+func (job *Job) Pids() []int {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	pids := make([]int, len(job.processes))
+	for i, p := range job.processes {
+		pids[i] = p.Pid
+	}
+	return pids
+}
+
+// Done returns true when the job has finished.
+func (job *Job) Done() bool {
+	select {
+	case <-job.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Status returns `Running`, `Done`, `Exit N` or `Killed`.
+func (job *Job) Status() string {
+	if !job.Done() {
+		return "Running"
+	}
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	if job.killed {
+		return "Killed"
+	}
+	if job.ErrorLevel != 0 {
+		return fmt.Sprintf("Exit %d", job.ErrorLevel)
+	}
+	return "Done"
+}
+
+func (job *Job) String() string {
+	return fmt.Sprintf("[%d] %-8s %s", job.ID, job.Status(), job.CommandLine)
+}
+
+// Wait waits until the job finishes and returns its errorlevel.
+func (job *Job) Wait(ctx context.Context) (int, error) {
+	var cancel <-chan struct{}
+	if ctx != nil {
+		cancel = ctx.Done()
+	}
+	select {
+	case <-job.done:
+		removeJob(job)
+		return job.ErrorLevel, nil
+	case <-cancel:
+		return -1, ctx.Err()
+	}
+}
+
+// Kill stops the commands of the job.
+func (job *Job) Kill() error {
+	if job.Done() {
+		return fmt.Errorf("%%%d: job has already finished", job.ID)
+	}
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.killed = true
+	job.cancel()
+	var err error
+	for _, p := range job.processes {
+		if err1 := p.Kill(); err1 != nil && err == nil && !errors.Is(err1, os.ErrProcessDone) {
+			err = err1
+		}
+	}
+	return err
+}
+
+// Continue restarts the stopped processes of the job.
+func (job *Job) Continue() error {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	for _, p := range job.processes {
+		if err := continueProcess(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeJob(job *Job) {
+	jobTable.Lock()
+	defer jobTable.Unlock()
+	for i, j := range jobTable.jobs {
+		if j == job {
+			jobTable.jobs = append(jobTable.jobs[:i], jobTable.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Jobs returns the jobs sorted by ID.
+func Jobs() []*Job {
+	jobTable.Lock()
+	jobs := make([]*Job, len(jobTable.jobs))
+	copy(jobs, jobTable.jobs)
+	jobTable.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+// FindJob returns the job from the job-spec `%N`, `N`, `%%` or `%+`.
+// `%%` and `%+` are the latest job.
+func FindJob(spec string) (*Job, error) {
+	jobs := Jobs()
+	if spec == "" || spec == "%%" || spec == "%+" {
+		if len(jobs) <= 0 {
+			return nil, errors.New("no current job")
+		}
+		return jobs[len(jobs)-1], nil
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(spec, "%"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid job-spec", spec)
+	}
+	for _, job := range jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: no such job", spec)
+}
+
+// NotifyJobs prints the jobs finished since the last call
+// like `[1] Done  make` and removes them from the job table.
+func NotifyJobs(w io.Writer) {
+	for _, job := range Jobs() {
+		if job.Done() {
+			fmt.Fprintln(w, job.String())
+			removeJob(job)
+		}
+	}
+}
+
+// detachedContext has the values of the parent but is never canceled
+// with the parent, so background jobs survive after the prompt returns.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
@@ -0,0 +1,10 @@
+package shell
+
+import (
+	"os"
+	"syscall"
+)
+
+func continueProcess(p *os.Process) error {
+	return p.Signal(syscall.SIGCONT)
+}
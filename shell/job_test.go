@@ -0,0 +1,43 @@
+package shell
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestJob(t *testing.T) {
+	job1 := newJob("a")
+	job2 := newJob("b")
+	if job2.ID != job1.ID+1 {
+		t.Fatalf("job ids: %d,%d", job1.ID, job2.ID)
+	}
+	if j, err := FindJob("%%"); err != nil || j != job2 {
+		t.Fatalf("FindJob(%%%%) = %v,%v", j, err)
+	}
+	if j, err := FindJob("%" + "1"); err != nil || j != job1 {
+		t.Fatalf("FindJob(%%1) = %v,%v", j, err)
+	}
+	if _, err := FindJob("%9"); err == nil {
+		t.Fatal("FindJob(%9): error expected")
+	}
+	job1.finish(3)
+	if s := job1.Status(); s != "Exit 3" {
+		t.Fatalf("job1.Status() = %s", s)
+	}
+	var buffer strings.Builder
+	NotifyJobs(&buffer)
+	if buffer.String() != "[1] Exit 3   a\n" {
+		t.Fatalf("NotifyJobs: %q", buffer.String())
+	}
+	if len(Jobs()) != 1 {
+		t.Fatalf("Jobs() = %v", Jobs())
+	}
+	job2.finish(0)
+	if rc, err := job2.Wait(context.Background()); rc != 0 || err != nil {
+		t.Fatalf("job2.Wait() = %d,%v", rc, err)
+	}
+	if len(Jobs()) != 0 {
+		t.Fatalf("Jobs() = %v", Jobs())
+	}
+}
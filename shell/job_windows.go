@@ -0,0 +1,11 @@
+package shell
+
+import (
+	"errors"
+	"os"
+)
+
+// continueProcess fails because processes are never stopped on Windows.
+func continueProcess(p *os.Process) error {
+	return errors.New("bg: stopping and continuing processes are not supported")
+}
@@ -0,0 +1,364 @@
+package shell
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenOperator
+	tokenRedirect
+)
+
+type token struct {
+	Span
+	kind          tokenKind
+	text          string
+	redirect      *_Redirecter
+	substitutions []*SubstitutionNode
+}
+
+// tokenize splits text into words, operators and redirections.
+// The tokens found before an error are returned with it.
+func tokenize(text string) ([]*token, error) {
+	tokens := []*token{}
+	quoteNow := NOTQUOTED
+	yenCount := 0
+	lastchar := ' '
+	wordStart, wordEnd := -1, -1
+	// commandStart is true while the next word is the command name.
+	commandStart := true
+	parenDepth := 0
+	braceDepth := 0
+	var substitutions []*SubstitutionNode
+	// hereDocs are the indices of `<<` tokens whose bodies are not read yet.
+	var hereDocs []int
+
+	termWord := func() {
+		if wordStart >= 0 {
+			t := &token{
+				Span:          Span{Start: wordStart, Stop: wordEnd},
+				kind:          tokenWord,
+				text:          text[wordStart:wordEnd],
+				substitutions: substitutions,
+			}
+			substitutions = nil
+			if t.text == "{" && commandStart {
+				t.kind = tokenOperator
+				braceDepth++
+			} else {
+				if t.text == "}" && braceDepth > 0 {
+					t.kind = tokenOperator
+					braceDepth--
+				}
+				commandStart = false
+			}
+			tokens = append(tokens, t)
+			wordStart = -1
+		}
+	}
+	addOperator := func(start, stop int, op string) {
+		termWord()
+		tokens = append(tokens, &token{
+			Span: Span{Start: start, Stop: stop},
+			kind: tokenOperator,
+			text: op,
+		})
+		commandStart = (op != ")")
+	}
+	addRedirect := func(start, stop int, no int) {
+		termWord()
+		commandStart = false
+		tokens = append(tokens, &token{
+			Span:     Span{Start: start, Stop: stop},
+			kind:     tokenRedirect,
+			text:     text[start:stop],
+			redirect: newRedirecter(no),
+		})
+	}
+	// adjacent returns the last token if it ends at pos and has the kind.
+	adjacent := func(pos int, kind tokenKind) *token {
+		if wordStart >= 0 || len(tokens) <= 0 {
+			return nil
+		}
+		last := tokens[len(tokens)-1]
+		if last.Stop != pos || last.kind != kind {
+			return nil
+		}
+		return last
+	}
+	extend := func(t *token, stop int) {
+		t.Stop = stop
+		if t.kind == tokenRedirect {
+			t.text = text[t.Start:stop]
+		}
+	}
+	// fdPrefix removes the file descriptor number just before the
+	// redirection at pos from the current word, and returns it and the
+	// start position of the redirection. When chomp is true, the last
+	// 1 or 2 of the word is also taken as the number.
+	fdPrefix := func(pos int, defaultNo int, chomp bool) (int, int) {
+		if wordStart < 0 || wordEnd != pos {
+			return defaultNo, pos
+		}
+		word := text[wordStart:wordEnd]
+		if no, err := strconv.Atoi(word); err == nil && no >= 0 && word[0] != '+' {
+			wordStart = -1
+			return no, pos - len(word)
+		}
+		if last := word[len(word)-1]; chomp && (last == '1' || last == '2') {
+			// 1> or 2> just after the word
+			wordEnd--
+			return int(last - '0'), pos - 1
+		}
+		return defaultNo, pos
+	}
+	// readHereDocs reads the bodies of here-documents from the lines
+	// following pos, and returns the position after them.
+	readHereDocs := func(pos int) int {
+		for _, index := range hereDocs {
+			r := tokens[index].redirect
+			delimiter := ""
+			if index+1 < len(tokens) && tokens[index+1].kind == tokenWord {
+				delimiter = tokens[index+1].text
+			}
+			quoted := strings.ContainsAny(delimiter, `"'`)
+			delimiter = strings.NewReplacer(`"`, "", `'`, "").Replace(delimiter)
+			var body strings.Builder
+			for pos < len(text) {
+				end := strings.IndexByte(text[pos:], '\n')
+				if end < 0 {
+					end = len(text)
+				} else {
+					end += pos
+				}
+				line := strings.TrimSuffix(text[pos:end], "\r")
+				pos = end
+				if pos < len(text) {
+					pos++
+				}
+				if r.stripTabs {
+					line = strings.TrimLeft(line, "\t")
+				}
+				if line == delimiter {
+					r.hereDocClosed = true
+					break
+				}
+				body.WriteString(line)
+				body.WriteByte('\n')
+			}
+			if quoted {
+				r.hereDoc = body.String()
+			} else {
+				r.hereDoc = expandPercent(body.String())
+			}
+		}
+		hereDocs = nil
+		return pos
+	}
+
+	for i := 0; i < len(text); {
+		ch, size := utf8.DecodeRuneInString(text[i:])
+		next := i + size
+
+		if next < len(text) && text[next] == '(' &&
+			((ch == '$' && quoteNow != '\'') ||
+				((ch == '<' || ch == '>') && quoteNow == NOTQUOTED && wordStart < 0)) {
+			// $( ... ), <( ... ) and >( ... ) are parts of the word
+			// even if they contain spaces.
+			end, err := scanSubstitution(text, next)
+			if err != nil {
+				return tokens, err
+			}
+			if wordStart < 0 {
+				wordStart = i
+			}
+			wordEnd = end
+			substitutions = append(substitutions, &SubstitutionNode{
+				Span:     Span{Start: i, Stop: end},
+				Operator: text[i : next+1],
+				Text:     text[next+1 : end-1],
+				Quoted:   quoteNow == '"',
+			})
+			yenCount = 0
+			lastchar = ')'
+			i = end
+			continue
+		}
+		if quoteNow == NOTQUOTED {
+			if yenCount%2 == 0 && (ch == '"' || ch == '\'') {
+				quoteNow = ch
+			}
+		} else if yenCount%2 == 0 && ch == quoteNow {
+			quoteNow = NOTQUOTED
+		}
+		if quoteNow != NOTQUOTED {
+			if wordStart < 0 {
+				wordStart = i
+			}
+			wordEnd = next
+		} else if ch == '\n' && len(hereDocs) > 0 {
+			termWord()
+			next = readHereDocs(next)
+		} else if unicode.IsSpace(ch) {
+			termWord()
+		} else if unicode.IsSpace(lastchar) && ch == '#' {
+			break
+		} else if ch == ';' && (unicode.IsSpace(lastchar) ||
+			(parenDepth > 0 || braceDepth > 0) && (next >= len(text) || isSpaceAt(text, next))) {
+			// `a; b` is split only in the groups as before.
+			addOperator(i, next, ";")
+		} else if ch == '(' && commandStart && wordStart < 0 {
+			addOperator(i, next, "(")
+			parenDepth++
+		} else if ch == ')' && parenDepth > 0 {
+			addOperator(i, next, ")")
+			parenDepth--
+		} else if r := adjacent(i, tokenRedirect); (ch == '!' || ch == '|') && lastchar == '>' && r != nil {
+			// >! or >|
+			r.redirect.force = true
+			extend(r, next)
+		} else if r := adjacent(i, tokenRedirect); ch == '-' && r != nil && r.text == "<<" {
+			// <<-
+			r.redirect.stripTabs = true
+			extend(r, next)
+		} else if ch == '|' {
+			if t := adjacent(i, tokenOperator); lastchar == '|' && t != nil && t.text == "|" {
+				t.text = "||"
+				extend(t, next)
+			} else {
+				addOperator(i, next, "|")
+			}
+		} else if ch == '&' {
+			t := adjacent(i, tokenOperator)
+			if lastchar == '&' && t != nil && t.text == "&" {
+				t.text = "&&"
+				extend(t, next)
+			} else if lastchar == '|' && t != nil && t.text == "|" {
+				t.text = "|&"
+				extend(t, next)
+			} else if r := adjacent(i, tokenRedirect); r != nil &&
+				(lastchar == '>' || (lastchar == '<' && !r.redirect.isHereDoc)) {
+				// >&N , <&N , >&- or <&-
+				end := next
+				if end < len(text) && text[end] == '-' {
+					r.redirect.isClose = true
+					end++
+				} else {
+					for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+						end++
+					}
+					if end == next {
+						if next >= len(text) {
+							return tokens, &SyntaxError{Pos: i, Msg: "Too Near EOF for >&"}
+						}
+						return tokens, &SyntaxError{Pos: next, Msg: "Syntax error after >&"}
+					}
+					no, _ := strconv.Atoi(text[next:end])
+					r.redirect.DupFrom(no)
+				}
+				next = end
+				extend(r, next)
+			} else {
+				addOperator(i, next, "&")
+			}
+		} else if ch == '>' {
+			if t := adjacent(i, tokenOperator); lastchar == '&' && t != nil && t.text == "&" {
+				// &> redirects both stdout and stderr
+				t.kind = tokenRedirect
+				t.redirect = newRedirecter(1)
+				t.redirect.both = true
+				extend(t, next)
+				commandStart = false
+			} else if r := adjacent(i, tokenRedirect); lastchar == '>' && r != nil {
+				// >>
+				r.redirect.SetAppend()
+				extend(r, next)
+			} else {
+				no, start := fdPrefix(i, 1, true)
+				addRedirect(start, next, no)
+			}
+		} else if ch == '<' {
+			if r := adjacent(i, tokenRedirect); lastchar == '<' && r != nil && r.text == "<" {
+				// <<
+				r.redirect.isHereDoc = true
+				hereDocs = append(hereDocs, len(tokens)-1)
+				extend(r, next)
+			} else if lastchar == '<' && r != nil && r.text == "<<" {
+				// <<<
+				r.redirect.isHereDoc = false
+				r.redirect.isHereString = true
+				hereDocs = hereDocs[:len(hereDocs)-1]
+				extend(r, next)
+			} else {
+				no, start := fdPrefix(i, 0, false)
+				addRedirect(start, next, no)
+			}
+		} else {
+			if wordStart < 0 {
+				wordStart = i
+			}
+			wordEnd = next
+		}
+		if ch == '\\' {
+			yenCount++
+		} else {
+			yenCount = 0
+		}
+		lastchar = ch
+		i = next
+	}
+	termWord()
+	return tokens, nil
+}
+
+// hasOpenHereDoc returns true when text has `<<` whose body is not
+// terminated with the delimiter yet.
+func hasOpenHereDoc(text string) bool {
+	tokens, _ := tokenize(text)
+	for i, t := range tokens {
+		if t.redirect != nil && t.redirect.isHereDoc && !t.redirect.hereDocClosed &&
+			i+1 < len(tokens) && tokens[i+1].kind == tokenWord {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpaceAt(text string, pos int) bool {
+	ch, _ := utf8.DecodeRuneInString(text[pos:])
+	return unicode.IsSpace(ch)
+}
+
+// scanSubstitution returns the position following the `)` which closes
+// the `(` at text[open]. Quoted strings and nested parentheses are skipped.
+func scanSubstitution(text string, open int) (int, error) {
+	depth := 0
+	quoteNow := NOTQUOTED
+	for i := open; i < len(text); i++ {
+		ch := rune(text[i])
+		if quoteNow != NOTQUOTED {
+			if ch == quoteNow {
+				quoteNow = NOTQUOTED
+			}
+			continue
+		}
+		switch ch {
+		case '"', '\'':
+			quoteNow = ch
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth <= 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return len(text), &SyntaxError{Pos: open - 1, Msg: "Missing `)` for `$(`"}
+}
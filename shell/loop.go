@@ -2,6 +2,7 @@ package shell
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -13,6 +14,13 @@ type Stream interface {
 	ReadLine(context.Context) (context.Context, string, error)
 }
 
+// ResultStream is the Stream which receives the errorlevel of each line.
+// Loop calls Result after all statements of the line are executed.
+type ResultStream interface {
+	Stream
+	Result(errorlevel int)
+}
+
 func (ses *session) push(lines []string) {
 	if lines != nil && len(lines) >= 1 {
 		ses.unreadline = append(ses.unreadline, lines...)
@@ -32,6 +40,14 @@ func (ses *session) pop() (string, bool) {
 	return line, true
 }
 
+// NewSession returns the copy of the shell which does not share
+// the statements left on the current line with sh.
+func (sh *Shell) NewSession() *Shell {
+	newsh := *sh
+	newsh.session = &session{}
+	return &newsh
+}
+
 // ReadCommand reads completed one command from `stream`.
 func (sh *Shell) ReadCommand(ctx context.Context, stream Stream) (context.Context, string, error) {
 	var line string
@@ -43,6 +59,18 @@ func (sh *Shell) ReadCommand(ctx context.Context, stream Stream) (context.Contex
 		if err != nil {
 			return ctx, line, err
 		}
+		if hasOpenHereDoc(line) {
+			// Read the bodies of here-documents.
+			for hasOpenHereDoc(line) {
+				var next string
+				ctx, next, err = stream.ReadLine(ctx)
+				if err != nil {
+					break
+				}
+				line = line + "\n" + next
+			}
+			return ctx, line, nil
+		}
 
 		texts := splitToStatement(line)
 		line = texts[0]
@@ -56,20 +84,26 @@ type streamIDT struct{}
 // StreamID is the key-object to find the last stream in the context object.
 var StreamID streamIDT
 
+// ErrInterrupted is the cause of the context canceled by Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// Interrupted returns true when ctx was canceled by Ctrl-C.
+func Interrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrInterrupted)
+}
+
 // Loop executes commands from `stream` until any errors are found.
 func (sh *Shell) Loop(ctx0 context.Context, stream Stream) (int, error) {
 	sigint := make(chan os.Signal, 1)
 	defer close(sigint)
-	quit := make(chan struct{}, 1)
-	defer close(quit)
 
 	for {
-		ctx, cancel := context.WithCancel(ctx0)
+		ctx, cancel := context.WithCancelCause(ctx0)
 		ctx = context.WithValue(ctx, StreamID, stream)
 
 		ctx, line, err := sh.ReadCommand(ctx, stream)
 		if err != nil {
-			cancel()
+			cancel(nil)
 			if err == io.EOF {
 				return 0, err
 			}
@@ -77,15 +111,18 @@ func (sh *Shell) Loop(ctx0 context.Context, stream Stream) (int, error) {
 		}
 		signal.Notify(sigint, os.Interrupt)
 
-		go func(sigint_ chan os.Signal, quit_ chan struct{}, cancel_ func()) {
+		// a new channel for each line not to let the goroutine of the
+		// previous line take the token.
+		quit := make(chan struct{}, 1)
+		go func(sigint_ chan os.Signal, quit_ chan struct{}, cancel_ context.CancelCauseFunc) {
 			for {
 				select {
 				case <-sigint_:
-					cancel_()
-					<-quit
+					cancel_(ErrInterrupted)
+					<-quit_
 					return
-				case <-quit:
-					cancel_()
+				case <-quit_:
+					cancel_(nil)
 					return
 				}
 			}
@@ -94,6 +131,10 @@ func (sh *Shell) Loop(ctx0 context.Context, stream Stream) (int, error) {
 		signal.Stop(sigint)
 		quit <- struct{}{}
 
+		if rs, ok := stream.(ResultStream); ok && (len(sh.unreadline) <= 0 || err == io.EOF) {
+			rs.Result(rc)
+		}
+
 		if err != nil {
 			if err == io.EOF {
 				return rc, err
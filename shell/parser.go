@@ -1,7 +1,6 @@
 package shell
 
 import (
-	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -14,15 +13,6 @@ import (
 	"github.com/zetamatta/nyagos/texts"
 )
 
-type StatementT struct {
-	Args     []string
-	RawArgs  []string
-	Redirect []*_Redirecter
-	Term     string
-}
-
-var prefix []string = []string{" 0<", " 1>", " 2>"}
-
 var PercentFunc = map[string]func() string{
 	"CD": func() string {
 		wd, err := os.Getwd()
@@ -35,6 +25,13 @@ var PercentFunc = map[string]func() string{
 	"ERRORLEVEL": func() string {
 		return fmt.Sprintf("%d", LastErrorLevel)
 	},
+	"PIPESTATUS": func() string {
+		status := make([]string, len(LastPipeStatus))
+		for i, s := range LastPipeStatus {
+			status[i] = strconv.Itoa(s)
+		}
+		return strings.Join(status, " ")
+	},
 }
 
 var rxUnicode = regexp.MustCompile("^[uU]\\+?([0-9a-fA-F]+)$")
@@ -85,6 +82,71 @@ const NOTQUOTED = '\000'
 
 const EMPTY_COMMAND_FOUND = "Empty command found"
 
+// NoUnset is the switch to make the unknown %NAME% an error.
+var NoUnset = false
+
+// UnboundVariableError is the error for the unknown %NAME% with NoUnset.
+type UnboundVariableError struct {
+	Pos  int
+	Name string
+}
+
+func (e *UnboundVariableError) Error() string {
+	return "%" + e.Name + "%: unbound variable"
+}
+
+// findUnboundVariable returns the first %NAME% out of single quotations
+// which can not be expanded.
+func findUnboundVariable(source string) (string, bool) {
+	quoted := false
+	for i := 0; i < len(source); i++ {
+		switch source[i] {
+		case '\'':
+			quoted = !quoted
+		case '%':
+			if quoted {
+				continue
+			}
+			end := strings.IndexByte(source[i+1:], '%')
+			if end < 0 {
+				return "", false
+			}
+			name := source[i+1 : i+1+end]
+			if _, ok := ourGetenvSub(name); !ok && name != "" && !strings.ContainsAny(name, " \t") {
+				return name, true
+			}
+			i += end + 1
+		}
+	}
+	return "", false
+}
+
+// expandPercent replaces %VAR% in text. Quotations are not treated.
+func expandPercent(text string) string {
+	var buffer strings.Builder
+	for {
+		start := strings.IndexByte(text, '%')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start+1:], '%')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+		if value, ok := ourGetenvSub(text[start+1 : end]); ok {
+			buffer.WriteString(text[:start])
+			buffer.WriteString(value)
+			text = text[end+1:]
+		} else {
+			buffer.WriteString(text[:end])
+			text = text[end:]
+		}
+	}
+	buffer.WriteString(text)
+	return buffer.String()
+}
+
 func string2word(source_ string, removeQuote bool) string {
 	var buffer strings.Builder
 	source := strings.NewReader(source_)
@@ -177,195 +239,295 @@ func string2word(source_ string, removeQuote bool) string {
 	return buffer.String()
 }
 
-func parse1(text string) ([]*StatementT, error) {
-	quoteNow := NOTQUOTED
-	yenCount := 0
-	statements := make([]*StatementT, 0)
-	args := make([]string, 0)
-	rawArgs := make([]string, 0)
-	lastchar := ' '
-	var buffer strings.Builder
-	isNextRedirect := false
-	redirect := make([]*_Redirecter, 0, 3)
-
-	term_line := func(term string) {
-		statement1 := new(StatementT)
-		if buffer.Len() > 0 {
-			if isNextRedirect && len(redirect) > 0 {
-				redirect[len(redirect)-1].SetPath(string2word(buffer.String(), true))
-				isNextRedirect = false
-				statement1.RawArgs = rawArgs
-				statement1.Args = args
-			} else {
-				statement1.RawArgs = append(rawArgs, string2word(buffer.String(), false))
-				statement1.Args = append(args, string2word(buffer.String(), true))
-			}
-			buffer.Reset()
-		} else if len(args) <= 0 {
-			return
-		} else {
-			statement1.RawArgs = rawArgs
-			statement1.Args = args
+// SyntaxError is the error found by Parse with its position in the line.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Msg
+}
+
+const ERR_SYNTAX = "The syntax of the command is incorrect."
+
+type parser struct {
+	tokens []*token
+	index  int
+	err    error // the error found in words such as UnboundVariableError
+}
+
+func (p *parser) peek() *token {
+	if p.index >= len(p.tokens) {
+		return nil
+	}
+	return p.tokens[p.index]
+}
+
+// peekOperator returns the next token if it is one of ops.
+func (p *parser) peekOperator(ops ...string) *token {
+	t := p.peek()
+	if t == nil || t.kind != tokenOperator {
+		return nil
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return t
+		}
+	}
+	return nil
+}
+
+func (p *parser) newWordNode(t *token) *WordNode {
+	// Each $( ... ) is replaced with the marker not to expand %VAR% in it.
+	// The markers are replaced with the outputs on execution.
+	source := t.text
+	for i := len(t.substitutions) - 1; i >= 0; i-- {
+		s := t.substitutions[i]
+		source = source[:s.Start-t.Start] +
+			string(substitutionMarker+rune(i)) +
+			source[s.Stop-t.Start:]
+	}
+	if NoUnset && p.err == nil {
+		if name, ok := findUnboundVariable(source); ok {
+			p.err = &UnboundVariableError{Pos: t.Start, Name: name}
 		}
-		statement1.Redirect = redirect
-		redirect = make([]*_Redirecter, 0, 3)
-		rawArgs = make([]string, 0)
-		args = make([]string, 0)
-		statement1.Term = term
-		statements = append(statements, statement1)
 	}
+	return &WordNode{
+		Span:          t.Span,
+		Source:        t.text,
+		Raw:           string2word(source, false),
+		Text:          string2word(source, true),
+		Substitutions: t.substitutions,
+	}
+}
 
-	term_word := func() {
-		if isNextRedirect && len(redirect) > 0 {
-			redirect[len(redirect)-1].SetPath(string2word(buffer.String(), true))
-		} else {
-			if buffer.Len() > 0 {
-				rawArgs = append(rawArgs, string2word(buffer.String(), false))
-				args = append(args, string2word(buffer.String(), true))
-			}
+func newOperatorNode(t *token) *OperatorNode {
+	return &OperatorNode{Span: t.Span, Text: t.text}
+}
+
+// parseRedirect makes RedirectNode from the token t and its target.
+func (p *parser) parseRedirect(t *token) *RedirectNode {
+	redirect := &RedirectNode{Span: t.Span, Op: t.text, r: t.redirect}
+	if t.redirect.dupFrom < 0 {
+		if w := p.peek(); w != nil && w.kind == tokenWord {
+			p.index++
+			redirect.Target = p.newWordNode(w)
+			redirect.Stop = w.Stop
+			redirect.r.SetPath(redirect.Target.Text)
+		}
+	}
+	return redirect
+}
+
+// parseGroup reads `( ... )` or `{ ... }` and the redirections after it.
+func (p *parser) parseGroup(open *token) (Node, error) {
+	p.index++
+	group := &GroupNode{
+		Span:     open.Span,
+		Subshell: open.text == "(",
+		Open:     newOperatorNode(open),
+	}
+	closeText := ")"
+	if !group.Subshell {
+		closeText = "}"
+	}
+	body, err := p.parseSequence()
+	if body != nil {
+		group.Body = body
+		group.Stop = body.End()
+	}
+	if err != nil {
+		return group, err
+	}
+	t := p.peekOperator(closeText)
+	if t == nil {
+		return group, &SyntaxError{Pos: group.Stop, Msg: "Missing `" + closeText + "`"}
+	}
+	p.index++
+	group.Close = newOperatorNode(t)
+	group.Stop = t.Stop
+	if body == nil {
+		return group, &SyntaxError{Pos: t.Start, Msg: EMPTY_COMMAND_FOUND}
+	}
+	for {
+		t := p.peek()
+		if t == nil || t.kind == tokenOperator {
+			return group, nil
 		}
-		buffer.Reset()
+		if t.kind == tokenWord {
+			return group, &SyntaxError{Pos: t.Start, Msg: ERR_SYNTAX}
+		}
+		p.index++
+		redirect := p.parseRedirect(t)
+		group.Redirects = append(group.Redirects, redirect)
+		group.Stop = redirect.Stop
 	}
+}
 
-	reader := strings.NewReader(text)
-	for reader.Len() > 0 {
-		ch, chSize, chErr := reader.ReadRune()
-		if chSize <= 0 {
+// parseCommand reads words and redirections until an operator.
+// It returns nil when no words and redirections exist.
+func (p *parser) parseCommand() (Node, error) {
+	if t := p.peekOperator("(", "{"); t != nil {
+		return p.parseGroup(t)
+	}
+	cmd := &CommandNode{Span: Span{Start: -1}}
+	for {
+		t := p.peek()
+		if t == nil || t.kind == tokenOperator {
 			break
 		}
-		if chErr != nil {
-			return nil, chErr
+		p.index++
+		if cmd.Start < 0 {
+			cmd.Start = t.Start
 		}
-		if quoteNow == NOTQUOTED {
-			if yenCount%2 == 0 && (ch == '"' || ch == '\'') {
-				quoteNow = ch
-			}
-		} else if yenCount%2 == 0 && ch == quoteNow {
-			quoteNow = NOTQUOTED
+		cmd.Stop = t.Stop
+		if t.kind == tokenWord {
+			cmd.Words = append(cmd.Words, p.newWordNode(t))
+			continue
 		}
-		if quoteNow != NOTQUOTED {
-			buffer.WriteRune(ch)
-		} else if unicode.IsSpace(ch) {
-			if buffer.Len() > 0 {
-				term_word()
-				isNextRedirect = false
-			}
-		} else if unicode.IsSpace(lastchar) && ch == '#' {
+		redirect := p.parseRedirect(t)
+		cmd.Redirects = append(cmd.Redirects, redirect)
+		cmd.Stop = redirect.Stop
+	}
+	if cmd.Start < 0 {
+		return nil, nil
+	}
+	return cmd, nil
+}
+
+func (p *parser) parsePipeline() (Node, error) {
+	first, err := p.parseCommand()
+	if first == nil || err != nil {
+		return first, err
+	}
+	pipeline := &PipelineNode{
+		Span:     Span{Start: first.Pos(), Stop: first.End()},
+		Commands: []Node{first},
+	}
+	for {
+		t := p.peekOperator("|", "|&")
+		if t == nil {
 			break
-		} else if unicode.IsSpace(lastchar) && ch == ';' {
-			term_line(";")
-		} else if ch == '!' && lastchar == '>' && isNextRedirect && len(redirect) > 0 {
-			redirect[len(redirect)-1].force = true
-		} else if ch == '|' {
-			if lastchar == '>' && isNextRedirect && len(redirect) > 0 {
-				redirect[len(redirect)-1].force = true
-			} else if lastchar == '|' {
-				if len(statements) <= 0 {
-					return nil, errors.New(EMPTY_COMMAND_FOUND)
-				}
-				statements[len(statements)-1].Term = "||"
-			} else {
-				term_line("|")
-			}
-		} else if ch == '&' {
-			switch lastchar {
-			case '&':
-				if len(statements) <= 0 {
-					return nil, errors.New(EMPTY_COMMAND_FOUND)
-				}
-				statements[len(statements)-1].Term = "&&"
-			case '|':
-				if len(statements) <= 0 {
-					return nil, errors.New(EMPTY_COMMAND_FOUND)
-				}
-				statements[len(statements)-1].Term = "|&"
-			case '>':
-				// >&[n]
-				ch2, ch2siz, ch2err := reader.ReadRune()
-				if ch2err != nil {
-					return nil, ch2err
-				}
-				if ch2siz <= 0 {
-					return nil, errors.New("Too Near EOF for >&")
-				}
-				red := redirect[len(redirect)-1]
-				switch ch2 {
-				case '1':
-					red.DupFrom(1)
-				case '2':
-					red.DupFrom(2)
-				default:
-					return nil, errors.New("Syntax error after >&")
-				}
-				isNextRedirect = false
-			default:
-				term_line("&")
-			}
-		} else if ch == '>' {
-			switch lastchar {
-			case '1':
-				// 1>
-				chomp(&buffer)
-				term_word()
-				redirect = append(redirect, newRedirecter(1))
-			case '2':
-				// 2>
-				chomp(&buffer)
-				term_word()
-				redirect = append(redirect, newRedirecter(2))
-			case '>':
-				// >>
-				term_word()
-				if len(redirect) >= 0 {
-					redirect[len(redirect)-1].SetAppend()
-				}
-			default:
-				// >
-				term_word()
-				redirect = append(redirect, newRedirecter(1))
+		}
+		p.index++
+		pipeline.Operators = append(pipeline.Operators, newOperatorNode(t))
+		pipeline.Stop = t.Stop
+		cmd, err := p.parseCommand()
+		if cmd == nil {
+			if err == nil {
+				err = &SyntaxError{Pos: t.Stop, Msg: ERR_SYNTAX}
 			}
-			isNextRedirect = true
-		} else if ch == '<' {
-			term_word()
-			redirect = append(redirect, newRedirecter(0))
-			isNextRedirect = true
-		} else {
-			buffer.WriteRune(ch)
+			return pipeline, err
 		}
-		if ch == '\\' {
-			yenCount++
-		} else {
-			yenCount = 0
+		pipeline.Commands = append(pipeline.Commands, cmd)
+		pipeline.Stop = cmd.End()
+		if err != nil {
+			return pipeline, err
 		}
-		lastchar = ch
 	}
-	term_line(" ")
-	return statements, nil
+	if len(pipeline.Commands) == 1 {
+		return first, nil
+	}
+	return pipeline, nil
 }
 
-// Make arrays whose elements are pipelines
-func parse2(statements []*StatementT) [][]*StatementT {
-	result := make([][]*StatementT, 1)
-	for _, statement1 := range statements {
-		result[len(result)-1] = append(result[len(result)-1], statement1)
-		switch statement1.Term {
-		case "|", "|&":
+func (p *parser) parseAndOr() (Node, error) {
+	left, err := p.parsePipeline()
+	if left == nil || err != nil {
+		return left, err
+	}
+	for {
+		t := p.peekOperator("&&", "||")
+		if t == nil {
+			return left, nil
+		}
+		p.index++
+		right, err := p.parsePipeline()
+		node := &AndOrNode{
+			Span:     Span{Start: left.Pos(), Stop: t.Stop},
+			Left:     left,
+			Operator: newOperatorNode(t),
+		}
+		if right == nil {
+			if err == nil {
+				err = &SyntaxError{Pos: t.Stop, Msg: EMPTY_COMMAND_FOUND}
+			}
+			return node, err
+		}
+		node.Right = right
+		node.Stop = right.End()
+		left = node
+		if err != nil {
+			return left, err
+		}
+	}
+}
 
-		default:
-			result = append(result, make([]*StatementT, 0))
+func (p *parser) parseSequence() (Node, error) {
+	sequence := &SequenceNode{}
+	var err error
+	for p.peek() != nil && err == nil {
+		if p.peekOperator(")", "}") != nil {
+			break
 		}
+		if t := p.peekOperator(";"); t != nil {
+			p.index++
+			sequence.Separators = append(sequence.Separators, newOperatorNode(t))
+			continue
+		}
+		var node Node
+		node, err = p.parseAndOr()
+		if node == nil {
+			if err == nil {
+				err = &SyntaxError{Pos: p.peek().Start, Msg: EMPTY_COMMAND_FOUND}
+			}
+			break
+		}
+		if t := p.peekOperator("&"); t != nil && err == nil {
+			p.index++
+			node = &BackgroundNode{
+				Span:     Span{Start: node.Pos(), Stop: t.Stop},
+				Body:     node,
+				Operator: newOperatorNode(t),
+			}
+		}
+		sequence.Elements = append(sequence.Elements, node)
+	}
+	if len(sequence.Elements) <= 0 {
+		return nil, err
+	}
+	if len(sequence.Elements) == 1 && len(sequence.Separators) == 0 {
+		return sequence.Elements[0], err
 	}
-	if len(result[len(result)-1]) <= 0 {
-		result = result[0 : len(result)-1]
+	sequence.Start = sequence.Elements[0].Pos()
+	sequence.Stop = sequence.Elements[len(sequence.Elements)-1].End()
+	for _, s := range sequence.Separators {
+		if s.Start < sequence.Start {
+			sequence.Start = s.Start
+		}
+		if s.Stop > sequence.Stop {
+			sequence.Stop = s.Stop
+		}
 	}
-	return result
+	return sequence, err
 }
 
-func Parse(text string) ([][]*StatementT, error) {
-	result1, err := parse1(text)
-	if err != nil {
-		return nil, err
+// Parse makes the syntax tree of text. It returns nil for the empty line.
+// When an error is returned, the tree parsed until the error is returned
+// with it (may be incomplete) for completion and so on.
+func Parse(text string) (Node, error) {
+	tokens, err := tokenize(text)
+	p := &parser{tokens: tokens}
+	node, err1 := p.parseSequence()
+	if err == nil {
+		err = err1
+	}
+	if t := p.peek(); t != nil && err == nil {
+		err = &SyntaxError{Pos: t.Start, Msg: "Unexpected `" + t.text + "`"}
+	}
+	if err == nil {
+		err = p.err
 	}
-	result2 := parse2(result1)
-	return result2, nil
+	return node, err
 }
@@ -1,32 +1,295 @@
 package shell
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
+func dumpNode(node Node) string {
+	var buffer strings.Builder
+	Walk(node, func(n Node) bool {
+		switch v := n.(type) {
+		case *WordNode:
+			fmt.Fprintf(&buffer, "[%s]", v.Text)
+		case *OperatorNode:
+			fmt.Fprintf(&buffer, "{%s}", v.Text)
+		case *RedirectNode:
+			fmt.Fprintf(&buffer, "<%s>", v.Op)
+		}
+		return true
+	})
+	return buffer.String()
+}
+
 func TestParser(t *testing.T) {
 	text := "gawk \"{ print(\"\"ahaha ihihi ufufu\"\") }\" <\"ddd\"\"ddd\"|ahaha \"ihihi |ufufu\" ; ohoho gegee&&hogehogeo >ihihi"
 	fmt.Println(text)
-	result, _ := Parse(text)
-	for i, st := range result {
-		fmt.Printf("pipeline-%d:\n", i)
-		for _, stsub := range st {
-			for _, word := range stsub.Args {
-				fmt.Printf("  [%s]", word)
-			}
-			fmt.Println()
-		}
-	}
-	result, _ = Parse("")
-	fmt.Println("<empty-line>")
-	for i, st := range result {
-		fmt.Printf("pipeline-%d:\n", i)
-		for _, stsub := range st {
-			for _, word := range stsub.Args {
-				fmt.Printf("  [%s]", word)
-			}
-			fmt.Println()
+	result, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `[gawk][{ print("ahaha ihihi ufufu") }]<<>[ddd"ddd]{|}[ahaha][ihihi |ufufu]{;}[ohoho][gegee]{&&}[hogehogeo]<>>[ihihi]`
+	if dump := dumpNode(result); dump != expect {
+		t.Fatalf("Parse(%q)\n\t= %s\n\texpected %s", text, dump, expect)
+	}
+	result, err = Parse("")
+	if result != nil || err != nil {
+		t.Fatalf("Parse(\"\") = %v,%v", result, err)
+	}
+}
+
+func TestParserTree(t *testing.T) {
+	text := "a 2>&1 | b >> c && d || e & f"
+	result, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq, ok := result.(*SequenceNode)
+	if !ok || len(seq.Elements) != 2 {
+		t.Fatalf("%s: not sequence: %#v", text, result)
+	}
+	bg, ok := seq.Elements[0].(*BackgroundNode)
+	if !ok {
+		t.Fatalf("%s: not background: %#v", text, seq.Elements[0])
+	}
+	or, ok := bg.Body.(*AndOrNode)
+	if !ok || or.Operator.Text != "||" {
+		t.Fatalf("%s: not `||`: %#v", text, bg.Body)
+	}
+	and, ok := or.Left.(*AndOrNode)
+	if !ok || and.Operator.Text != "&&" {
+		t.Fatalf("%s: not `&&`: %#v", text, or.Left)
+	}
+	pipeline, ok := and.Left.(*PipelineNode)
+	if !ok || len(pipeline.Commands) != 2 {
+		t.Fatalf("%s: not pipeline: %#v", text, and.Left)
+	}
+	b := pipeline.Commands[1].(*CommandNode)
+	if !reflect.DeepEqual(b.Args(), []string{"b"}) || len(b.Redirects) != 1 {
+		t.Fatalf("%s: b=%#v", text, b)
+	}
+	if r := b.Redirects[0]; r.Op != ">>" || r.Target.Text != "c" || text[r.Pos():r.End()] != ">> c" {
+		t.Fatalf("%s: redirect=%#v", text, r)
+	}
+	a := pipeline.Commands[0].(*CommandNode)
+	if r := a.Redirects[0]; text[r.Pos():r.End()] != "2>&1" {
+		t.Fatalf("%s: redirect=%#v", text, r)
+	}
+	if text[pipeline.Pos():pipeline.End()] != "a 2>&1 | b >> c" {
+		t.Fatalf("%s: pipeline=[%d:%d]", text, pipeline.Pos(), pipeline.End())
+	}
+	if f := seq.Elements[1]; text[f.Pos():f.End()] != "f" {
+		t.Fatalf("%s: f=[%d:%d]", text, f.Pos(), f.End())
+	}
+}
+
+func TestParserError(t *testing.T) {
+	for _, text := range []string{"a |", "&& a", "a ||", "a >&x", "a >&"} {
+		_, err := Parse(text)
+		if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("Parse(%q): error expected, but %v", text, err)
 		}
 	}
+	node, _ := Parse("ls | ")
+	if p, ok := node.(*PipelineNode); !ok || len(p.Operators) != 1 {
+		t.Errorf("Parse(\"ls | \"): incomplete pipeline expected, but %#v", node)
+	}
+}
+
+func TestParserGroup(t *testing.T) {
+	text := "(cd src && make) > log ; { echo a; echo b } | sort"
+	result, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{(}[cd][src]{&&}[make]{)}<>>[log]{;}{{}[echo][a]{;}[echo][b]{}}{|}[sort]`
+	if dump := dumpNode(result); dump != expect {
+		t.Fatalf("Parse(%q)\n\t= %s\n\texpected %s", text, dump, expect)
+	}
+	seq := result.(*SequenceNode)
+	sub, ok := seq.Elements[0].(*GroupNode)
+	if !ok || !sub.Subshell || len(sub.Redirects) != 1 {
+		t.Fatalf("%s: not subshell: %#v", text, seq.Elements[0])
+	}
+	if text[sub.Pos():sub.End()] != "(cd src && make) > log" {
+		t.Fatalf("%s: subshell=[%d:%d]", text, sub.Pos(), sub.End())
+	}
+	pipeline, ok := seq.Elements[1].(*PipelineNode)
+	if !ok {
+		t.Fatalf("%s: not pipeline: %#v", text, seq.Elements[1])
+	}
+	if brace, ok := pipeline.Commands[0].(*GroupNode); !ok || brace.Subshell {
+		t.Fatalf("%s: not brace group: %#v", text, pipeline.Commands[0])
+	}
+
+	for text, expect := range map[string]string{
+		"set PATH=a;b":   `[set][PATH=a;b]`,
+		"echo (a) {b}":   `[echo][(a)][{b}]`,
+		"gawk { print }": `[gawk][{][print][}]`,
+		"a && (b ; c)|d": `[a]{&&}{(}[b]{;}[c]{)}{|}[d]`,
+		"echo a; b":      `[echo][a;][b]`,
+		"(a; b)":         `{(}[a]{;}[b]{)}`,
+		"{ a; b; }":      `{{}[a]{;}[b]{;}{}}`,
+	} {
+		result, err := Parse(text)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", text, err)
+		}
+		if dump := dumpNode(result); dump != expect {
+			t.Errorf("Parse(%q)\n\t= %s\n\texpected %s", text, dump, expect)
+		}
+	}
+	for _, text := range []string{"(a", "{ a", "()", "(a) b"} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("Parse(%q): error expected", text)
+		}
+	}
+}
+
+func TestParserSubstitution(t *testing.T) {
+	text := `echo x$(echo "a )" $(echo b))y "$(echo c d)" | more`
+	result, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := result.(*PipelineNode).Commands[0].(*CommandNode)
+	if len(cmd.Words) != 3 {
+		t.Fatalf("%s: words=%#v", text, cmd.Words)
+	}
+	w := cmd.Words[1]
+	if len(w.Substitutions) != 1 || w.Substitutions[0].Text != `echo "a )" $(echo b)` || w.Substitutions[0].Quoted {
+		t.Fatalf("%s: substitution=%#v", text, w.Substitutions)
+	}
+	if s := w.Substitutions[0]; text[s.Pos():s.End()] != `$(echo "a )" $(echo b))` {
+		t.Fatalf("%s: substitution=[%d:%d]", text, s.Pos(), s.End())
+	}
+	if w := cmd.Words[2]; len(w.Substitutions) != 1 || !w.Substitutions[0].Quoted {
+		t.Fatalf("%s: substitution=%#v", text, w.Substitutions)
+	}
+	if _, err := Parse("echo $(echo a"); err == nil {
+		t.Fatal("Parse(\"echo $(echo a\"): error expected")
+	}
+}
+
+func TestSpliceOutputs(t *testing.T) {
+	quoted := []*SubstitutionNode{{Operator: "$(", Quoted: true}}
+	unquoted := []*SubstitutionNode{{Operator: "$("}}
+	m := string(substitutionMarker)
+	for _, c := range []struct {
+		word          string
+		substitutions []*SubstitutionNode
+		output        string
+		expect        []string
+	}{
+		{"x" + m + "y", unquoted, "a b  c", []string{"xa", "b", "cy"}},
+		{"x" + m + "y", quoted, "a b  c", []string{"xa b  cy"}},
+		{"x" + m + "y", unquoted, " a ", []string{"x", "a", "y"}},
+		{m, unquoted, "", []string{""}},
+	} {
+		result := spliceOutputs(c.word, c.substitutions, []string{c.output})
+		if !reflect.DeepEqual(result, c.expect) {
+			t.Errorf("spliceOutputs(%q,%q) = %q, expected %q", c.word, c.output, result, c.expect)
+		}
+	}
+}
+
+func TestParserHereDoc(t *testing.T) {
+	os.Setenv("HEREDOC_TEST", "value")
+	text := "cat <<EOF | sort <<-'END' ; cat <<<\"a b\"\n1 %HEREDOC_TEST%\nEOF\n\t2 %HEREDOC_TEST%\n\tEND"
+	result, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var redirects []*RedirectNode
+	Walk(result, func(n Node) bool {
+		if r, ok := n.(*RedirectNode); ok {
+			redirects = append(redirects, r)
+		}
+		return true
+	})
+	if len(redirects) != 3 {
+		t.Fatalf("%q: redirects=%#v", text, redirects)
+	}
+	if r := redirects[0]; r.Op != "<<" || r.r.hereDoc != "1 value\n" || !r.r.hereDocClosed {
+		t.Errorf("%q: <<EOF: %#v", text, r.r)
+	}
+	if r := redirects[1]; r.Op != "<<-" || r.r.hereDoc != "2 %HEREDOC_TEST%\n" {
+		t.Errorf("%q: <<-'END': %#v", text, r.r)
+	}
+	if r := redirects[2]; r.Op != "<<<" || r.r.path != "a b" {
+		t.Errorf("%q: <<<: %#v", text, r.r)
+	}
+
+	stream := &BufStream{}
+	for _, line := range []string{"cat <<EOF ; echo x", "a ; b", "EOF", "echo y"} {
+		stream.Add(line)
+	}
+	_, line, err := New().ReadCommand(context.Background(), stream)
+	if err != nil || line != "cat <<EOF ; echo x\na ; b\nEOF" {
+		t.Errorf("ReadCommand() = %q,%v", line, err)
+	}
+}
+
+func TestParserFileDescriptor(t *testing.T) {
+	text := "a 3>x 4>&3 1<&- &>>y 12<z echo2>w <(b c) >(d)"
+	result, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := result.(*CommandNode)
+	expect := []struct {
+		op      string
+		no      int
+		dupFrom int
+		isClose bool
+		both    bool
+	}{
+		{"3>", 3, -1, false, false},
+		{"4>&3", 4, 3, false, false},
+		{"1<&-", 1, -1, true, false},
+		{"&>>", 1, -1, false, true},
+		{"12<", 12, -1, false, false},
+		{"2>", 2, -1, false, false},
+	}
+	if len(cmd.Redirects) != len(expect) {
+		t.Fatalf("%s: redirects=%#v", text, cmd.Redirects)
+	}
+	for i, e := range expect {
+		r := cmd.Redirects[i]
+		if r.Op != e.op || r.r.no != e.no || r.r.dupFrom != e.dupFrom || r.r.isClose != e.isClose || r.r.both != e.both {
+			t.Errorf("%s: %d: %s %#v", text, i, r.Op, r.r)
+		}
+	}
+	if !reflect.DeepEqual(cmd.Args(), []string{"a", "echo",
+		string(substitutionMarker), string(substitutionMarker)}) {
+		t.Errorf("%s: args=%q", text, cmd.Args())
+	}
+	if s := cmd.Words[2].Substitutions[0]; s.Operator != "<(" || s.Text != "b c" {
+		t.Errorf("%s: %#v", text, s)
+	}
+	if s := cmd.Words[3].Substitutions[0]; s.Operator != ">(" || s.Text != "d" {
+		t.Errorf("%s: %#v", text, s)
+	}
+}
+
+func TestParserNoUnset(t *testing.T) {
+	os.Setenv("NOUNSET_TEST", "value")
+	os.Unsetenv("NOUNSET_UNKNOWN")
+	NoUnset = true
+	defer func() { NoUnset = false }()
+
+	for _, text := range []string{"echo %NOUNSET_TEST%", "echo '%NOUNSET_UNKNOWN%'", "echo 100%"} {
+		if _, err := Parse(text); err != nil {
+			t.Errorf("Parse(%q): %v", text, err)
+		}
+	}
+	text := "echo a %NOUNSET_UNKNOWN%"
+	_, err := Parse(text)
+	if e, ok := err.(*UnboundVariableError); !ok || e.Name != "NOUNSET_UNKNOWN" {
+		t.Errorf("Parse(%q): UnboundVariableError expected, but %v", text, err)
+	}
 }
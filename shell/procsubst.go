@@ -0,0 +1,60 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// processSubstitution is `<( ... )` or `>( ... )` running in the background.
+type processSubstitution struct {
+	fifo *fifo
+	done chan struct{}
+}
+
+// startProcess runs the command of s whose stdout (for `<(`) or stdin
+// (for `>(`) is the named pipe, and returns the path of the pipe.
+func (sh *Shell) startProcess(ctx context.Context, s *SubstitutionNode) (string, io.Closer, error) {
+	forWrite := (s.Operator == "<(")
+	f, err := newFifo(forWrite)
+	if err != nil {
+		return "", nil, err
+	}
+	newctx, newsh, err := sh.cloneForBackground(ctx)
+	if err != nil {
+		f.remove()
+		return "", nil, err
+	}
+	p := &processSubstitution{fifo: f, done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		defer newsh.closeBackground()
+		fd, err := f.open()
+		if err != nil {
+			fmt.Fprintln(newsh.Stderr, err.Error())
+			return
+		}
+		defer fd.Close()
+		if forWrite {
+			newsh.Stdout = fd
+		} else {
+			newsh.Stdin = fd
+		}
+		newsh.Interpret(newctx, s.Text)
+	}()
+	return f.path, p, nil
+}
+
+// Close waits the command and removes the named pipe.
+func (p *processSubstitution) Close() error {
+	for {
+		// Connect to the pipe in case the command never opened it.
+		p.fifo.unblock()
+		select {
+		case <-p.done:
+			return p.fifo.remove()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package shell
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// fifo is the named pipe made by mkfifo
+type fifo struct {
+	dir      string
+	path     string
+	forWrite bool
+}
+
+func newFifo(forWrite bool) (*fifo, error) {
+	dir, err := ioutil.TempDir("", "nyagos")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "fifo")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		os.Remove(dir)
+		return nil, err
+	}
+	return &fifo{dir: dir, path: path, forWrite: forWrite}, nil
+}
+
+// open returns our side of the pipe. It blocks until the other side opens.
+func (f *fifo) open() (*os.File, error) {
+	if f.forWrite {
+		return os.OpenFile(f.path, os.O_WRONLY, 0)
+	}
+	return os.Open(f.path)
+}
+
+// unblock opens and closes the other side to release open().
+func (f *fifo) unblock() {
+	flag := os.O_RDONLY
+	if !f.forWrite {
+		flag = os.O_WRONLY
+	}
+	if fd, err := os.OpenFile(f.path, flag|syscall.O_NONBLOCK, 0); err == nil {
+		fd.Close()
+	}
+}
+
+func (f *fifo) remove() error {
+	return os.RemoveAll(f.dir)
+}
@@ -0,0 +1,75 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32")
+	procCreateNamedPipe  = kernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe = kernel32.NewProc("ConnectNamedPipe")
+)
+
+const (
+	_PIPE_ACCESS_INBOUND  = 1
+	_PIPE_ACCESS_OUTBOUND = 2
+	_ERROR_PIPE_CONNECTED = 535
+)
+
+var fifoCount uint32
+
+// fifo is the named pipe `\\.\pipe\nyagos-PID-N`
+type fifo struct {
+	path     string
+	forWrite bool
+	handle   syscall.Handle
+}
+
+func newFifo(forWrite bool) (*fifo, error) {
+	path := fmt.Sprintf(`\\.\pipe\nyagos-%d-%d`,
+		os.Getpid(), atomic.AddUint32(&fifoCount, 1))
+	path16, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	var mode uintptr = _PIPE_ACCESS_INBOUND
+	if forWrite {
+		mode = _PIPE_ACCESS_OUTBOUND
+	}
+	h, _, err := procCreateNamedPipe.Call(uintptr(unsafe.Pointer(path16)),
+		mode, 0, 1, 4096, 4096, 0, 0)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		return nil, err
+	}
+	return &fifo{path: path, forWrite: forWrite, handle: syscall.Handle(h)}, nil
+}
+
+// open returns the server side of the pipe. It blocks until the client connects.
+func (f *fifo) open() (*os.File, error) {
+	rc, _, err := procConnectNamedPipe.Call(uintptr(f.handle), 0)
+	if rc == 0 && err != syscall.Errno(_ERROR_PIPE_CONNECTED) {
+		syscall.CloseHandle(f.handle)
+		return nil, err
+	}
+	return os.NewFile(uintptr(f.handle), f.path), nil
+}
+
+// unblock connects to the pipe as the client to release open().
+func (f *fifo) unblock() {
+	flag := os.O_RDONLY
+	if !f.forWrite {
+		flag = os.O_WRONLY
+	}
+	if fd, err := os.OpenFile(f.path, flag, 0); err == nil {
+		fd.Close()
+	}
+}
+
+// remove does nothing because the pipe is closed with the handle.
+func (f *fifo) remove() error {
+	return nil
+}
@@ -2,6 +2,8 @@ package shell
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"os"
 )
 
@@ -14,6 +16,14 @@ type _Redirecter struct {
 	no       int
 	dupFrom  int
 	force    bool
+	isClose  bool // N>&- or N<&-
+	both     bool // &> redirects stdout and stderr
+
+	isHereDoc     bool // <<DELIMITER
+	stripTabs     bool // <<-DELIMITER
+	hereDoc       string
+	hereDocClosed bool
+	isHereString  bool // <<<WORD
 }
 
 func newRedirecter(no int) *_Redirecter {
@@ -40,14 +50,33 @@ func (r *_Redirecter) SetAppend() {
 	r.isAppend = true
 }
 
+// openText returns the pipe to read text from.
+func openText(text string) (*os.File, error) {
+	pipeIn, pipeOut, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		io.WriteString(pipeOut, text)
+		pipeOut.Close()
+	}()
+	return pipeIn, nil
+}
+
 func (r *_Redirecter) open() (*os.File, error) {
+	if r.isHereDoc {
+		return openText(r.hereDoc)
+	}
+	if r.isHereString {
+		return openText(r.path + "\n")
+	}
 	if r.path == "" {
 		return nil, errors.New("_Redirecter.open(): path=\"\"")
 	}
 	if r.no == 0 {
 		return os.Open(r.path)
 	} else if r.isAppend {
-		return os.OpenFile(r.path, os.O_APPEND|os.O_CREATE, 0666)
+		return os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
 	} else {
 		if NoClobber && !r.force {
 			_, err := os.Stat(r.path)
@@ -59,32 +88,37 @@ func (r *_Redirecter) open() (*os.File, error) {
 	}
 }
 
+// OpenOn replaces the file descriptor of cmd with the redirected file.
+// It returns the file which should be closed after cmd finishes,
+// or nil when the file is shared with others.
 func (r *_Redirecter) OpenOn(cmd *Cmd) (*os.File, error) {
-	var fd *os.File
+	var fd, closer *os.File
 	var err error
 
-	switch r.dupFrom {
-	case 0:
-		fd = cmd.Stdin
-	case 1:
-		fd = cmd.Stdout
-	case 2:
-		fd = cmd.Stderr
-	default:
+	if r.isClose {
+		if r.no <= 2 {
+			// Builtin commands can not write to nil, so use the null device.
+			fd, err = os.OpenFile(os.DevNull, os.O_RDWR, 0)
+			if err != nil {
+				return nil, err
+			}
+			closer = fd
+		}
+	} else if r.dupFrom >= 0 {
+		fd = cmd.File(r.dupFrom)
+		if fd == nil {
+			return nil, fmt.Errorf("%d: Bad file descriptor", r.dupFrom)
+		}
+	} else {
 		fd, err = r.open()
 		if err != nil {
 			return nil, err
 		}
+		closer = fd
 	}
-	switch r.FileNo() {
-	case 0:
-		cmd.Stdin = fd
-	case 1:
-		cmd.Stdout = fd
-	case 2:
+	cmd.SetFile(r.no, fd)
+	if r.both {
 		cmd.Stderr = fd
-	default:
-		panic("Assertion failed: _Redirecter.OpenAs: r.no not in (0,1,2)")
 	}
-	return fd, nil
+	return closer, nil
 }
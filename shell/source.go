@@ -2,26 +2,19 @@ package shell
 
 import (
 	"bufio"
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
-
-	"github.com/zetamatta/go-mbcs"
-
-	"github.com/zetamatta/nyagos/dos"
 )
 
 func readEnv(scan *bufio.Scanner, verbose io.Writer) (int, error) {
 	errorlevel := -1
 	for scan.Scan() {
-		line, err := mbcs.ConsoleCpToUtf8(scan.Bytes())
+		line, err := consoleToUtf8(scan.Bytes())
 		if err != nil {
 			continue
 		}
@@ -59,7 +52,7 @@ func readPwd(scan *bufio.Scanner, verbose io.Writer) error {
 	if err := scan.Err(); err != nil {
 		return err
 	}
-	line, err := mbcs.ConsoleCpToUtf8(scan.Bytes())
+	line, err := consoleToUtf8(scan.Bytes())
 	if err != nil {
 		return err
 	}
@@ -86,77 +79,11 @@ func loadTmpFile(fname string, verbose io.Writer) (int, error) {
 	return readEnv(scan, verbose)
 }
 
-func callBatch(batch string,
-	args []string,
-	tmpfile string,
-	verbose io.Writer,
-	stdin io.Reader,
-	stdout io.Writer,
-	stderr io.Writer) (int, error) {
-	params := []string{
-		os.Getenv("COMSPEC"),
-		"/C",
-		batch,
-	}
-	fd, err := os.Create(batch)
-	if err != nil {
-		return 1, err
-	}
-	var writer *bufio.Writer
-	if verbose != nil && verbose != ioutil.Discard {
-		writer = bufio.NewWriter(io.MultiWriter(fd, verbose))
-	} else {
-		writer = bufio.NewWriter(fd)
-	}
-	io.WriteString(writer, "@call")
-	for _, arg1 := range args {
-		// UTF8 parameter to ANSI
-		ansi, err := mbcs.Utf8ToConsoleCp(arg1)
-		if err != nil {
-			// println("utoa: " + err.Error())
-			fd.Close()
-			return -1, err
-		}
-		ansi = bytes.TrimSuffix(ansi, []byte{0})
-		fmt.Fprintf(writer, " %s", ansi)
-	}
-	fmt.Fprintf(writer, "\r\n@set \"ERRORLEVEL_=%%ERRORLEVEL%%\"\r\n")
-
-	// Sometimes %TEMP% has not ASCII letters.
-	ansi, err := mbcs.Utf8ToConsoleCp(tmpfile)
-	if err != nil {
-		fd.Close()
-		return -1, err
-	}
-	ansi = bytes.TrimSuffix(ansi, []byte{0})
-	fmt.Fprintf(writer, "@(cd & set) > \"%s\"\r\n", ansi)
-	fmt.Fprintf(writer, "@exit /b \"%%ERRORLEVEL_%%\"\r\n")
-	writer.Flush()
-	if err := fd.Close(); err != nil {
-		return 1, err
-	}
-	cmd := exec.Cmd{
-		Path:   params[0],
-		Args:   params,
-		Stdin:  stdin,
-		Stdout: stdout,
-		Stderr: stderr,
-	}
-	if err := cmd.Run(); err != nil {
-		return 1, err
-	}
-	errorlevel, errorlevelOk := dos.GetErrorLevel(&cmd)
-	if !errorlevelOk {
-		errorlevel = 255
-	}
-	return errorlevel, nil
-}
-
 // RawSource calls the batchfiles and load the changed variable the batchfile has done.
 func RawSource(args []string, verbose io.Writer, debug bool, stdin io.Reader, stdout io.Writer, stderr io.Writer) (int, error) {
 	tempDir := os.TempDir()
 	pid := os.Getpid()
-	batch := filepath.Join(tempDir, fmt.Sprintf("nyagos-%d.cmd", pid))
+	batch := filepath.Join(tempDir, fmt.Sprintf("nyagos-%d%s", pid, batchSuffix))
 	tmpfile := filepath.Join(tempDir, fmt.Sprintf("nyagos-%d.tmp", pid))
 
 	errorlevel, err := callBatch(
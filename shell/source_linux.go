@@ -0,0 +1,65 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/zetamatta/nyagos/dos"
+)
+
+const batchSuffix = ".sh"
+
+func consoleToUtf8(line []byte) (string, error) {
+	return string(line), nil
+}
+
+func callBatch(batch string,
+	args []string,
+	tmpfile string,
+	verbose io.Writer,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer) (int, error) {
+	params := []string{
+		"/bin/sh",
+		batch,
+	}
+	fd, err := os.Create(batch)
+	if err != nil {
+		return 1, err
+	}
+	var writer *bufio.Writer
+	if verbose != nil && verbose != ioutil.Discard {
+		writer = bufio.NewWriter(io.MultiWriter(fd, verbose))
+	} else {
+		writer = bufio.NewWriter(fd)
+	}
+	io.WriteString(writer, ".")
+	for _, arg1 := range args {
+		fmt.Fprintf(writer, " %s", arg1)
+	}
+	io.WriteString(writer, "\nERRORLEVEL_=$?\nexport ERRORLEVEL_\n")
+	fmt.Fprintf(writer, "(pwd ; env) > '%s'\n", tmpfile)
+	io.WriteString(writer, "exit $ERRORLEVEL_\n")
+	writer.Flush()
+	if err := fd.Close(); err != nil {
+		return 1, err
+	}
+	cmd := exec.Cmd{
+		Path:   params[0],
+		Args:   params,
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+	err = cmd.Run()
+	errorlevel, errorlevelOk := dos.GetErrorLevel(&cmd)
+	if !errorlevelOk {
+		return 1, err
+	}
+	return errorlevel, nil
+}
@@ -0,0 +1,87 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/zetamatta/go-mbcs"
+
+	"github.com/zetamatta/nyagos/dos"
+)
+
+const batchSuffix = ".cmd"
+
+func consoleToUtf8(line []byte) (string, error) {
+	return mbcs.ConsoleCpToUtf8(line)
+}
+
+func callBatch(batch string,
+	args []string,
+	tmpfile string,
+	verbose io.Writer,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer) (int, error) {
+	params := []string{
+		os.Getenv("COMSPEC"),
+		"/C",
+		batch,
+	}
+	fd, err := os.Create(batch)
+	if err != nil {
+		return 1, err
+	}
+	var writer *bufio.Writer
+	if verbose != nil && verbose != ioutil.Discard {
+		writer = bufio.NewWriter(io.MultiWriter(fd, verbose))
+	} else {
+		writer = bufio.NewWriter(fd)
+	}
+	io.WriteString(writer, "@call")
+	for _, arg1 := range args {
+		// UTF8 parameter to ANSI
+		ansi, err := mbcs.Utf8ToConsoleCp(arg1)
+		if err != nil {
+			// println("utoa: " + err.Error())
+			fd.Close()
+			return -1, err
+		}
+		ansi = bytes.TrimSuffix(ansi, []byte{0})
+		fmt.Fprintf(writer, " %s", ansi)
+	}
+	fmt.Fprintf(writer, "\r\n@set \"ERRORLEVEL_=%%ERRORLEVEL%%\"\r\n")
+
+	// Sometimes %TEMP% has not ASCII letters.
+	ansi, err := mbcs.Utf8ToConsoleCp(tmpfile)
+	if err != nil {
+		fd.Close()
+		return -1, err
+	}
+	ansi = bytes.TrimSuffix(ansi, []byte{0})
+	fmt.Fprintf(writer, "@(cd & set) > \"%s\"\r\n", ansi)
+	fmt.Fprintf(writer, "@exit /b \"%%ERRORLEVEL_%%\"\r\n")
+	writer.Flush()
+	if err := fd.Close(); err != nil {
+		return 1, err
+	}
+	cmd := exec.Cmd{
+		Path:   params[0],
+		Args:   params,
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+	if err := cmd.Run(); err != nil {
+		return 1, err
+	}
+	errorlevel, errorlevelOk := dos.GetErrorLevel(&cmd)
+	if !errorlevelOk {
+		errorlevel = 255
+	}
+	return errorlevel, nil
+}
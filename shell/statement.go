@@ -1,33 +1,30 @@
 package shell
 
-import (
-	"strings"
-	"unicode"
-)
-
+// splitToStatement splits line at `;` which is not enclosed with
+// `( )` or `{ }`.
 func splitToStatement(line string) []string {
-	result := make([]string, 0)
-	quote := false
-	var buffer strings.Builder
-	lastc := ' '
-
-	done := func() {
-		result = append(result, buffer.String())
-		// println("[" + buffer.String() + "]")
-		buffer.Reset()
+	tokens, err := tokenize(line)
+	if err != nil {
+		return []string{line}
 	}
-
-	for _, c := range line {
-		if c == '"' {
-			quote = !quote
-		} else if !quote && c == ';' && unicode.IsSpace(lastc) {
-			done()
-			lastc = c
+	result := make([]string, 0)
+	depth := 0
+	start := 0
+	for _, t := range tokens {
+		if t.kind != tokenOperator {
 			continue
 		}
-		buffer.WriteRune(c)
-		lastc = c
+		switch t.text {
+		case "(", "{":
+			depth++
+		case ")", "}":
+			depth--
+		case ";":
+			if depth <= 0 {
+				result = append(result, line[start:t.Start])
+				start = t.Stop
+			}
+		}
 	}
-	done()
-	return result
+	return append(result, line[start:])
 }
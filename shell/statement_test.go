@@ -0,0 +1,22 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitToStatement(t *testing.T) {
+	for source, expect := range map[string][]string{
+		"a ; b":             {"a ", " b"},
+		"(a ; b) ; c":       {"(a ; b) ", " c"},
+		"{ a ; b } ; c":     {"{ a ; b } ", " c"},
+		"echo $(a ; b) ; c": {"echo $(a ; b) ", " c"},
+		`echo "a ; b" ; c`:  {`echo "a ; b" `, " c"},
+		"set PATH=a;b":      {"set PATH=a;b"},
+	} {
+		result := splitToStatement(source)
+		if !reflect.DeepEqual(result, expect) {
+			t.Errorf("splitToStatement(%q) = %q, expected %q", source, result, expect)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package shell
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// substitutionMarker+N stands for the N'th $( ... ) in WordNode.Raw and
+// WordNode.Text. They are in the private use area of the plane 15.
+const substitutionMarker = '\U000F0000'
+
+// substitute runs text and returns what it prints to the standard output
+// without the trailing newlines.
+func (sh *Shell) substitute(ctx context.Context, text string) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+	result := make(chan []byte)
+	go func() {
+		output, _ := ioutil.ReadAll(r)
+		r.Close()
+		result <- output
+	}()
+	newsh := *sh
+	newsh.Stdout = w
+	_, err = newsh.Interpret(ctx, text)
+	w.Close()
+	output := <-result
+
+	if _, ok := err.(*SyntaxError); ok {
+		return "", err
+	}
+	if ctx != nil && ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	var value string
+	if utf8.Valid(output) {
+		value = string(output)
+	} else if value, err = consoleToUtf8(output); err != nil {
+		value = string(output)
+	}
+	return strings.TrimRight(value, "\r\n"), nil
+}
+
+// substituteAll returns the replacements for the substitutions in w.
+// closers are to be closed after the command finishes.
+func (sh *Shell) substituteAll(ctx context.Context, w *WordNode) (outputs []string, closers []io.Closer, err error) {
+	outputs = make([]string, len(w.Substitutions))
+	for i, s := range w.Substitutions {
+		if s.Operator == "$(" {
+			outputs[i], err = sh.substitute(ctx, s.Text)
+		} else {
+			var closer io.Closer
+			outputs[i], closer, err = sh.startProcess(ctx, s)
+			if closer != nil {
+				closers = append(closers, closer)
+			}
+		}
+		if err != nil {
+			return nil, closers, err
+		}
+	}
+	return outputs, closers, nil
+}
+
+// expandWords replaces the markers of substitutions in words with the outputs.
+// The outputs of $( ... ) out of double quotations are split into some arguments.
+func (sh *Shell) expandWords(ctx context.Context, words []*WordNode) (args, rawArgs []string, closers []io.Closer, err error) {
+	args = make([]string, 0, len(words))
+	rawArgs = make([]string, 0, len(words))
+	for _, w := range words {
+		if len(w.Substitutions) <= 0 {
+			args = append(args, w.Text)
+			rawArgs = append(rawArgs, w.Raw)
+			continue
+		}
+		outputs, closers1, err := sh.substituteAll(ctx, w)
+		closers = append(closers, closers1...)
+		if err != nil {
+			return nil, nil, closers, err
+		}
+		fields := spliceOutputs(w.Text, w.Substitutions, outputs)
+		rawFields := spliceOutputs(w.Raw, w.Substitutions, outputs)
+		if len(rawFields) != len(fields) {
+			rawFields = fields
+		}
+		if len(fields) == 1 && fields[0] == "" && rawFields[0] == "" {
+			// the empty output without quotations does not make an argument.
+			continue
+		}
+		args = append(args, fields...)
+		rawArgs = append(rawArgs, rawFields...)
+	}
+	return args, rawArgs, closers, nil
+}
+
+// expandWord is expandWords without the word splitting.
+func (sh *Shell) expandWord(ctx context.Context, w *WordNode) (string, []io.Closer, error) {
+	if len(w.Substitutions) <= 0 {
+		return w.Text, nil, nil
+	}
+	outputs, closers, err := sh.substituteAll(ctx, w)
+	if err != nil {
+		return "", closers, err
+	}
+	var buffer strings.Builder
+	for _, ch := range w.Text {
+		if i := int(ch - substitutionMarker); i >= 0 && i < len(outputs) {
+			buffer.WriteString(outputs[i])
+		} else {
+			buffer.WriteRune(ch)
+		}
+	}
+	return buffer.String(), closers, nil
+}
+
+func spliceOutputs(word string, substitutions []*SubstitutionNode, outputs []string) []string {
+	fields := []string{}
+	var buffer strings.Builder
+	split := false
+	for _, ch := range word {
+		i := int(ch - substitutionMarker)
+		isMarker := i >= 0 && i < len(outputs)
+		if !isMarker || substitutions[i].Quoted || substitutions[i].Operator != "$(" {
+			if split {
+				fields = append(fields, buffer.String())
+				buffer.Reset()
+				split = false
+			}
+			if isMarker {
+				buffer.WriteString(outputs[i])
+			} else {
+				buffer.WriteRune(ch)
+			}
+			continue
+		}
+		output := outputs[i]
+		for j, f := range strings.Fields(output) {
+			if j > 0 || (buffer.Len() > 0 && startsWithSpace(output)) {
+				fields = append(fields, buffer.String())
+				buffer.Reset()
+			}
+			buffer.WriteString(f)
+			split = false
+		}
+		if output != "" && endsWithSpace(output) {
+			split = true
+		}
+	}
+	return append(fields, buffer.String())
+}
+
+func startsWithSpace(s string) bool {
+	ch, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsSpace(ch)
+}
+
+func endsWithSpace(s string) bool {
+	ch, _ := utf8.DecodeLastRuneInString(s)
+	return unicode.IsSpace(ch)
+}